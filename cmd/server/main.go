@@ -1,14 +1,17 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/heyanxiao/llm-bridge/internal/config"
 	"github.com/heyanxiao/llm-bridge/internal/handlers"
 	"github.com/heyanxiao/llm-bridge/internal/middleware"
 	"github.com/heyanxiao/llm-bridge/internal/providers"
@@ -16,6 +19,12 @@ import (
 )
 
 func main() {
+	// 加载配置文件
+	cfg, err := config.LoadDefault()
+	if err != nil {
+		log.Fatalf("配置文件加载失败: %v", err)
+	}
+
 	// 初始化Redis统计服务
 	if err := stats.InitRedisMetrics(); err != nil {
 		log.Printf("Redis统计服务初始化失败 (将使用内存统计): %v", err)
@@ -40,15 +49,36 @@ func main() {
 	// 添加中间件
 	setupMiddleware(app, rateLimiter)
 
+	// 合并配置文件中的模型价格
+	if !providers.IsValidRoutingMode(cfg.Pricing.Routing) {
+		log.Fatalf("不支持的路由模式: %s", cfg.Pricing.Routing)
+	}
+	providers.ApplyPricingConfig(&cfg.Pricing)
+
+	// 加载模型别名
+	providers.SetModelAliases(cfg.Models.Aliases)
+
 	// 初始化提供商工厂和负载均衡器
 	providerFactory := providers.NewProviderFactory()
-	loadBalancer := providers.NewRoundRobinBalancer()
+	loadBalancer, err := providers.NewLoadBalancer(cfg.LoadBalancer)
+	if err != nil {
+		log.Fatalf("负载均衡器初始化失败: %v", err)
+	}
+
+	// 解析路由规则
+	ruleEngine, err := providers.NewRuleEngine(cfg.RoutingRules)
+	if err != nil {
+		log.Fatalf("路由规则加载失败: %v", err)
+	}
 
 	// 注册LLM提供商
 	registerProviders(providerFactory)
+	registerCompatibleProviders(providerFactory, cfg.Providers.Compatible)
+	registerOllamaProvider(providerFactory, cfg.Providers.Ollama)
+	registerAzureProvider(providerFactory, cfg.Providers.Azure)
 
 	// 设置路由
-	setupRoutes(app, providerFactory, loadBalancer, rateLimiter)
+	setupRoutes(app, cfg, providerFactory, loadBalancer, ruleEngine, rateLimiter)
 
 	// 获取端口配置
 	port := os.Getenv("PORT")
@@ -81,7 +111,7 @@ func setupMiddleware(app *fiber.App, rateLimiter *middleware.RateLimiter) {
 		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
 		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
 	}))
-	
+
 	// 限流中间件
 	if rateLimiter != nil {
 		app.Use(rateLimiter.Middleware())
@@ -89,12 +119,19 @@ func setupMiddleware(app *fiber.App, rateLimiter *middleware.RateLimiter) {
 }
 
 // setupRoutes 设置路由
-func setupRoutes(app *fiber.App, factory *providers.ProviderFactory, balancer providers.LoadBalancer, rateLimiter *middleware.RateLimiter) {
+func setupRoutes(app *fiber.App, cfg *config.Config, factory *providers.ProviderFactory, balancer providers.LoadBalancer, rules *providers.RuleEngine, rateLimiter *middleware.RateLimiter) {
 	// 创建处理器实例
 	chatHandler := handlers.NewChatHandler(factory, balancer)
+	chatHandler.SetFailover(&cfg.Failover)
+	chatHandler.SetDefaultRouting(cfg.Pricing.Routing)
+	chatHandler.SetContextConfig(&cfg.Context)
+	chatHandler.SetHedging(&cfg.Hedging)
+	chatHandler.SetSticky(&cfg.LoadBalancer.Sticky)
+	chatHandler.SetRules(rules)
+	chatHandler.SetStructuredOutput(&cfg.StructuredOutput)
 	healthHandler := handlers.NewHealthHandler()
 	adminHandler := handlers.NewAdminHandler(factory, balancer)
-	
+
 	// 设置限流器
 	if rateLimiter != nil {
 		adminHandler.SetRateLimiter(rateLimiter)
@@ -102,11 +139,11 @@ func setupRoutes(app *fiber.App, factory *providers.ProviderFactory, balancer pr
 
 	// 静态文件服务 - 监控面板
 	app.Static("/static", "./static")
-	
+
 	// 管理面板路由
 	admin := app.Group("/admin")
 	admin.Get("/", adminHandler.Dashboard)
-	
+
 	// 管理API路由
 	adminAPI := admin.Group("/api")
 	adminAPI.Get("/providers", adminHandler.GetProvidersStatus)
@@ -114,11 +151,12 @@ func setupRoutes(app *fiber.App, factory *providers.ProviderFactory, balancer pr
 	adminAPI.Get("/stats", adminHandler.GetSystemStats)
 	adminAPI.Get("/providers/:provider/models", adminHandler.GetProviderModels)
 	adminAPI.Get("/models-config", adminHandler.GetAllModelsConfig)
-	
+	adminAPI.Get("/pricing", adminHandler.GetPricing)
+
 	// 添加简单的限流测试接口
 	adminAPI.Get("/rate-limit-test", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
-			"message": "限流测试成功",
+			"message":   "限流测试成功",
 			"timestamp": time.Now().Unix(),
 		})
 	})
@@ -129,6 +167,11 @@ func setupRoutes(app *fiber.App, factory *providers.ProviderFactory, balancer pr
 	// 聊天相关路由
 	v1.Post("/chat/completions", chatHandler.ChatCompletion)
 	v1.Get("/models", chatHandler.Models)
+	v1.Post("/messages", chatHandler.AnthropicMessages)
+
+	// Gemini格式路由: /v1beta/models/{model}:generateContent 和 :streamGenerateContent
+	v1beta := app.Group("/v1beta")
+	v1beta.Post("/models/*", chatHandler.GeminiGenerateContent)
 
 	// 健康检查路由
 	health := app.Group("/health")
@@ -150,6 +193,8 @@ func setupRoutes(app *fiber.App, factory *providers.ProviderFactory, balancer pr
 			"endpoints": fiber.Map{
 				"chat":     "/v1/chat/completions",
 				"models":   "/v1/models",
+				"messages": "/v1/messages",
+				"gemini":   "/v1beta/models/{model}:generateContent",
 				"health":   "/health",
 				"admin":    "/admin",
 				"monitor":  "/admin",
@@ -161,7 +206,7 @@ func setupRoutes(app *fiber.App, factory *providers.ProviderFactory, balancer pr
 // registerProviders 注册LLM提供商
 func registerProviders(factory *providers.ProviderFactory) {
 	// TODO: 从配置文件读取提供商配置
-	
+
 	// 示例：注册OpenAI提供商
 	if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
 		openaiConfig := &providers.OpenAIConfig{
@@ -229,8 +274,119 @@ func registerProviders(factory *providers.ProviderFactory) {
 		log.Println("已注册月之暗面提供商")
 	}
 
-	// TODO: 注册其他提供商(Claude, Azure等)
-	// 这里可以根据环境变量或配置文件动态注册
+	// 注册Claude提供商
+	if apiKey := os.Getenv("CLAUDE_API_KEY"); apiKey != "" {
+		claudeConfig := &providers.ClaudeConfig{
+			APIKey:  apiKey,
+			BaseURL: os.Getenv("CLAUDE_BASE_URL"),
+			Version: os.Getenv("CLAUDE_API_VERSION"),
+			Timeout: 60,
+			Retries: 3,
+		}
+		claudeProvider := providers.NewClaudeProvider(claudeConfig)
+		factory.RegisterProvider("claude", claudeProvider)
+		log.Println("已注册Claude提供商")
+	}
+}
+
+// registerAzureProvider 注册Azure OpenAI提供商
+// 使用配置文件中的providers.azure，未配置的项从AZURE_OPENAI_*环境变量读取，缺少密钥或端点时不注册
+func registerAzureProvider(factory *providers.ProviderFactory, azureConfig *providers.AzureConfig) {
+	if azureConfig == nil {
+		azureConfig = &providers.AzureConfig{}
+	}
+	if azureConfig.APIKey == "" {
+		azureConfig.APIKey = os.Getenv("AZURE_OPENAI_API_KEY")
+	}
+	if azureConfig.Endpoint == "" {
+		azureConfig.Endpoint = os.Getenv("AZURE_OPENAI_ENDPOINT")
+	}
+	if azureConfig.Deployment == "" {
+		azureConfig.Deployment = os.Getenv("AZURE_OPENAI_DEPLOYMENT")
+	}
+	if azureConfig.APIVersion == "" {
+		azureConfig.APIVersion = os.Getenv("AZURE_OPENAI_API_VERSION")
+	}
+	// 环境变量中的部署映射补充配置文件中没有的模型
+	for model, deployment := range parseKeyValueList(os.Getenv("AZURE_OPENAI_DEPLOYMENTS")) {
+		if azureConfig.Deployments == nil {
+			azureConfig.Deployments = make(map[string]string)
+		}
+		if azureConfig.Deployments[model] == "" {
+			azureConfig.Deployments[model] = deployment
+		}
+	}
+	if azureConfig.APIKey == "" || azureConfig.Endpoint == "" {
+		return
+	}
+
+	azureProvider := providers.NewAzureOpenAIProvider(azureConfig)
+	factory.RegisterProvider("azure", azureProvider)
+	log.Println("已注册Azure OpenAI提供商")
+}
+
+// registerCompatibleProviders 注册配置文件中的OpenAI兼容提供商
+func registerCompatibleProviders(factory *providers.ProviderFactory, configs []providers.CompatibleProviderConfig) {
+	for i := range configs {
+		provider, err := providers.NewGenericOpenAICompatibleProvider(&configs[i])
+		if err != nil {
+			log.Printf("OpenAI兼容提供商配置无效: %v", err)
+			continue
+		}
+		if _, exists := factory.GetProvider(provider.GetProviderName()); exists {
+			log.Printf("OpenAI兼容提供商 %s 与已注册的提供商重名，已跳过", provider.GetProviderName())
+			continue
+		}
+		factory.RegisterProvider(provider.GetProviderName(), provider)
+		log.Printf("已注册OpenAI兼容提供商: %s", provider.GetProviderName())
+	}
+}
+
+// registerOllamaProvider 注册Ollama本地模型提供商
+// OLLAMA_BASE_URL环境变量优先于配置文件，均未配置时不注册
+func registerOllamaProvider(factory *providers.ProviderFactory, ollamaConfig *providers.OllamaConfig) {
+	if ollamaConfig == nil {
+		ollamaConfig = &providers.OllamaConfig{}
+	}
+	if baseURL := os.Getenv("OLLAMA_BASE_URL"); baseURL != "" {
+		ollamaConfig.BaseURL = baseURL
+	}
+	if model := os.Getenv("OLLAMA_DEFAULT_MODEL"); model != "" {
+		ollamaConfig.DefaultModel = model
+	}
+	if ollamaConfig.BaseURL == "" {
+		return
+	}
+
+	ollamaProvider := providers.NewOllamaProvider(ollamaConfig)
+
+	// 从/api/tags获取已安装的模型
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if models, err := ollamaProvider.RefreshModels(ctx); err != nil {
+		log.Printf("Ollama模型列表获取失败: %v", err)
+	} else {
+		log.Printf("Ollama发现%d个本地模型", len(models))
+	}
+
+	// 定期刷新模型列表，启动时获取失败的情况下也能在Ollama可用后自动恢复
+	ollamaProvider.StartModelRefresh(context.Background(), time.Duration(ollamaConfig.RefreshInterval)*time.Second)
+
+	factory.RegisterProvider("ollama", ollamaProvider)
+	log.Println("已注册Ollama提供商")
+}
+
+// parseKeyValueList 解析 "key1=value1,key2=value2" 格式的环境变量
+func parseKeyValueList(value string) map[string]string {
+	result := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		key, val, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || key == "" || val == "" {
+			continue
+		}
+		result[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return result
 }
 
 // customErrorHandler 自定义错误处理器
@@ -253,4 +409,4 @@ func customErrorHandler(c *fiber.Ctx, err error) error {
 			"type":    "server_error",
 		},
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/heyanxiao/llm-bridge/internal/providers"
+	"gopkg.in/yaml.v3"
+)
+
+// Config 网关配置文件结构
+// 只包含网关实际使用的配置项，其余配置项会被忽略
+type Config struct {
+	Providers        providers.ProviderConfig         `yaml:"providers"`
+	Failover         providers.FailoverConfig         `yaml:"failover"`
+	LoadBalancer     providers.LoadBalancerConfig     `yaml:"load_balancer"`
+	Pricing          providers.PricingConfig          `yaml:"pricing"`
+	Context          providers.ContextConfig          `yaml:"context"`
+	Models           providers.ModelAliasConfig       `yaml:"models"`
+	Hedging          providers.HedgingConfig          `yaml:"hedging"`
+	RoutingRules     []providers.RoutingRuleConfig    `yaml:"routing_rules"`
+	StructuredOutput providers.StructuredOutputConfig `yaml:"structured_output"`
+}
+
+// DefaultConfigPath 默认配置文件路径
+const DefaultConfigPath = "configs/config.yaml"
+
+// envPattern 匹配 ${VAR} 和 ${VAR:-default} 形式的环境变量引用
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// Load 从指定路径加载配置文件
+// 配置文件中的 ${VAR} 会替换为环境变量的值，${VAR:-default} 在变量为空时使用默认值
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	expanded := expandEnv(string(data))
+
+	cfg := &Config{}
+	if err := yaml.Unmarshal([]byte(expanded), cfg); err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// LoadDefault 加载CONFIG_PATH环境变量指定的配置文件，未指定时使用默认路径
+// 配置文件不存在时返回空配置，保证仅使用环境变量也能启动
+func LoadDefault() (*Config, error) {
+	path := os.Getenv("CONFIG_PATH")
+	if path == "" {
+		path = DefaultConfigPath
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+
+	return Load(path)
+}
+
+// expandEnv 替换配置内容中的环境变量引用
+func expandEnv(content string) string {
+	return envPattern.ReplaceAllStringFunc(content, func(match string) string {
+		groups := envPattern.FindStringSubmatch(match)
+		if value := os.Getenv(groups[1]); value != "" {
+			return value
+		}
+		return groups[2]
+	})
+}
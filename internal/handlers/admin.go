@@ -55,9 +55,10 @@ func (h *AdminHandler) GetProvidersStatus(c *fiber.Ctx) error {
 		baseProvider := getBaseProvider(provider)
 		
 		// 从Redis获取提供商统计
-		var requests, avgResponseTime, tokens int64
+		var requests, avgResponseTime, tokens, hedgeAttempts, hedgeWins int64
 		if redisMetrics := stats.GetRedisMetrics(); redisMetrics != nil {
 			requests, avgResponseTime, tokens = redisMetrics.GetProviderStats(name)
+			hedgeAttempts, hedgeWins = redisMetrics.GetProviderHedgeStats(name)
 		}
 		
 		status := map[string]interface{}{
@@ -73,8 +74,15 @@ func (h *AdminHandler) GetProvidersStatus(c *fiber.Ctx) error {
 			"tokens":           tokens,
 		}
 
-		// 检查健康状态（简单检查，可以扩展为实际的健康检查）
-		if baseProvider.APIKey != "" && baseProvider.BaseURL != "" {
+		// 熔断器状态
+		breaker := h.loadBalancer.BreakerSnapshot(name)
+		status["breaker"] = breaker
+		status["modelBreakers"] = h.loadBalancer.ModelBreakerSnapshots(name)
+		status["load"] = h.loadBalancer.LoadSnapshot(name)
+		status["hedge"] = fiber.Map{"attempts": hedgeAttempts, "wins": hedgeWins}
+
+		// 检查健康状态（未配置密钥或熔断中视为不健康）
+		if isProviderConfigured(provider, baseProvider) && breaker.State != providers.BreakerOpen {
 			status["status"] = "healthy"
 		} else {
 			status["status"] = "unhealthy"
@@ -114,6 +122,7 @@ func (h *AdminHandler) TestProvider(c *fiber.Ctx) error {
 	}
 
 	// 构建测试请求
+	temperature := 0.1
 	testReq := &types.UnifiedRequest{
 		Model:    req.Model,
 		Provider: req.Provider,
@@ -124,7 +133,7 @@ func (h *AdminHandler) TestProvider(c *fiber.Ctx) error {
 			},
 		},
 		Parameters: types.Parameters{
-			Temperature: 0.1,
+			Temperature: &temperature,
 			MaxTokens:   50,
 		},
 		Metadata: types.Metadata{
@@ -292,7 +301,7 @@ func (h *AdminHandler) GetProviderModels(c *fiber.Ctx) error {
 func (h *AdminHandler) GetAllModelsConfig(c *fiber.Ctx) error {
 	modelsConfig := make(map[string]interface{})
 	
-	for provider, config := range providers.SupportedModels {
+	for provider, config := range providers.AllModelConfigs() {
 		modelsConfig[provider] = fiber.Map{
 			"models": config.Models,
 			"defaultModel": config.DefaultModel,
@@ -305,6 +314,14 @@ func (h *AdminHandler) GetAllModelsConfig(c *fiber.Ctx) error {
 	})
 }
 
+// GetPricing 获取模型价格表
+func (h *AdminHandler) GetPricing(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"success": true,
+		"models":  providers.ListModelInfo(),
+	})
+}
+
 // 辅助函数
 func (h *AdminHandler) getAllProviders() []providers.ProviderAdapter {
 	providerNames := h.providerFactory.ListProviders()
@@ -325,7 +342,7 @@ func (h *AdminHandler) getHealthyProvidersCount() int {
 	for _, name := range h.providerFactory.ListProviders() {
 		if provider, exists := h.providerFactory.GetProvider(name); exists {
 			baseProvider := getBaseProvider(provider)
-			if baseProvider.APIKey != "" && baseProvider.BaseURL != "" {
+			if isProviderConfigured(provider, baseProvider) && h.loadBalancer.BreakerSnapshot(name).State != providers.BreakerOpen {
 				count++
 			}
 		}
@@ -347,6 +364,14 @@ func getBaseProvider(provider providers.ProviderAdapter) *providers.BaseProvider
 		return &p.BaseProvider
 	case *providers.MoonshotProvider:
 		return &p.BaseProvider
+	case *providers.ClaudeProvider:
+		return &p.BaseProvider
+	case *providers.AzureOpenAIProvider:
+		return &p.BaseProvider
+	case *providers.GenericOpenAICompatibleProvider:
+		return &p.BaseProvider
+	case *providers.OllamaProvider:
+		return &p.BaseProvider
 	default:
 		// 返回默认值
 		return &providers.BaseProvider{
@@ -358,6 +383,21 @@ func getBaseProvider(provider providers.ProviderAdapter) *providers.BaseProvider
 	}
 }
 
+// 检查提供商配置是否完整
+// 本地模型和自建的OpenAI兼容服务可以不配置API密钥
+func isProviderConfigured(provider providers.ProviderAdapter, baseProvider *providers.BaseProvider) bool {
+	if baseProvider.BaseURL == "" {
+		return false
+	}
+
+	switch provider.(type) {
+	case *providers.OllamaProvider, *providers.GenericOpenAICompatibleProvider:
+		return true
+	default:
+		return baseProvider.APIKey != ""
+	}
+}
+
 // 获取提供商支持的模型
 func getProviderModels(providerName string) []string {
 	return providers.GetProviderModels(providerName)
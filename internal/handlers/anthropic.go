@@ -0,0 +1,404 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/heyanxiao/llm-bridge/internal/providers"
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// AnthropicMessages 处理Anthropic Messages API格式的请求
+// 请求转换为统一格式后与聊天补全走同样的路由，任何提供商都可以响应
+func (h *ChatHandler) AnthropicMessages(c *fiber.Ctx) error {
+	responder := anthropicResponder{}
+
+	var anthropicReq types.AnthropicRequest
+	if err := json.Unmarshal(c.Body(), &anthropicReq); err != nil {
+		return responder.sendError(c, fiber.StatusBadRequest, "invalid_request", "请求体格式错误: "+err.Error(), "invalid_request_error")
+	}
+
+	req, err := anthropicReq.ToUnified()
+	if err != nil {
+		return responder.sendError(c, fiber.StatusBadRequest, "invalid_request", "请求体格式错误: "+err.Error(), "invalid_request_error")
+	}
+
+	return h.complete(c, req, responder)
+}
+
+// anthropicErrorBody Anthropic格式的错误响应
+type anthropicErrorBody struct {
+	Type  string         `json:"type"`
+	Error anthropicError `json:"error"`
+}
+
+// anthropicError Anthropic格式的错误信息
+type anthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// anthropicMessage Anthropic格式的消息响应
+type anthropicMessage struct {
+	ID           string                  `json:"id"`
+	Type         string                  `json:"type"`
+	Role         string                  `json:"role"`
+	Model        string                  `json:"model"`
+	Content      []anthropicContentBlock `json:"content"`
+	StopReason   *string                 `json:"stop_reason"`
+	StopSequence *string                 `json:"stop_sequence"`
+	Usage        anthropicUsage          `json:"usage"`
+}
+
+// anthropicContentBlock Anthropic格式的内容块
+type anthropicContentBlock struct {
+	Type     string          `json:"type"`
+	Text     *string         `json:"text,omitempty"`
+	Thinking *string         `json:"thinking,omitempty"`
+	ID       string          `json:"id,omitempty"`    // tool_use内容块
+	Name     string          `json:"name,omitempty"`  // tool_use内容块
+	Input    json.RawMessage `json:"input,omitempty"` // tool_use内容块的参数对象
+}
+
+// anthropicUsage Anthropic格式的token使用统计
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// anthropicDelta content_block_delta和message_delta事件的delta字段
+type anthropicDelta struct {
+	Type         string  `json:"type,omitempty"`
+	Text         *string `json:"text,omitempty"`
+	Thinking     *string `json:"thinking,omitempty"`
+	PartialJSON  *string `json:"partial_json,omitempty"`
+	StopReason   *string `json:"stop_reason,omitempty"`
+	StopSequence *string `json:"stop_sequence,omitempty"`
+}
+
+// anthropicEvent Anthropic格式的流式事件
+type anthropicEvent struct {
+	Type         string                 `json:"type"`
+	Message      *anthropicMessage      `json:"message,omitempty"`
+	Index        *int                   `json:"index,omitempty"`
+	ContentBlock *anthropicContentBlock `json:"content_block,omitempty"`
+	Delta        *anthropicDelta        `json:"delta,omitempty"`
+	Usage        *anthropicUsage        `json:"usage,omitempty"`
+}
+
+// anthropicStopReason 将统一的finish_reason映射为Anthropic的stop_reason
+func anthropicStopReason(reason string) string {
+	switch reason {
+	case "length":
+		return "max_tokens"
+	case "tool_calls":
+		return "tool_use"
+	case "content_filter":
+		return "refusal"
+	default:
+		return "end_turn"
+	}
+}
+
+// anthropicErrorType 按HTTP状态码确定Anthropic的错误类型
+func anthropicErrorType(status int) string {
+	switch status {
+	case fiber.StatusBadRequest:
+		return "invalid_request_error"
+	case fiber.StatusUnauthorized:
+		return "authentication_error"
+	case fiber.StatusForbidden:
+		return "permission_error"
+	case fiber.StatusNotFound:
+		return "not_found_error"
+	case fiber.StatusTooManyRequests:
+		return "rate_limit_error"
+	case fiber.StatusServiceUnavailable:
+		return "overloaded_error"
+	default:
+		return "api_error"
+	}
+}
+
+// writeSSEEvent 写出一个带event类型的SSE事件
+func writeSSEEvent(w *bufio.Writer, event string, data interface{}) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, jsonData)
+	return err
+}
+
+// anthropicResponder Anthropic Messages格式
+type anthropicResponder struct{}
+
+// sendResponse 输出Anthropic格式的响应
+func (r anthropicResponder) sendResponse(c *fiber.Ctx, resp *types.UnifiedResponse) error {
+	if resp.Error != nil {
+		return r.sendError(c, upstreamStatus(resp.Error.Status), resp.Error.Code, resp.Error.Message, resp.Error.Type)
+	}
+
+	message := anthropicMessage{
+		ID:      resp.ID,
+		Type:    "message",
+		Role:    "assistant",
+		Model:   resp.Model,
+		Content: []anthropicContentBlock{},
+		Usage: anthropicUsage{
+			InputTokens:  resp.Usage.PromptTokens,
+			OutputTokens: resp.Usage.CompletionTokens,
+		},
+	}
+
+	// Anthropic格式只有一个候选回复
+	if len(resp.Choices) > 0 {
+		choice := resp.Choices[0]
+		if choice.Message.Content != "" {
+			text := choice.Message.Content
+			message.Content = append(message.Content, anthropicContentBlock{Type: "text", Text: &text})
+		}
+		for _, call := range choice.Message.ToolCalls {
+			message.Content = append(message.Content, anthropicContentBlock{
+				Type:  "tool_use",
+				ID:    call.ID,
+				Name:  call.Function.Name,
+				Input: argumentsObject(call.Function.Arguments),
+			})
+		}
+		stopReason := anthropicStopReason(choice.FinishReason)
+		message.StopReason = &stopReason
+	}
+
+	return c.JSON(message)
+}
+
+// sendError 输出Anthropic格式的错误，错误类型由状态码决定
+func (anthropicResponder) sendError(c *fiber.Ctx, status int, code, message, errType string) error {
+	return c.Status(status).JSON(anthropicErrorBody{
+		Type:  "error",
+		Error: anthropicError{Type: anthropicErrorType(status), Message: message},
+	})
+}
+
+// newStreamEncoder 创建Anthropic格式的事件编码器
+func (anthropicResponder) newStreamEncoder(req *types.UnifiedRequest) streamEncoder {
+	return &anthropicStreamEncoder{
+		inputTokens: providers.EstimateMessagesTokens(req.Messages),
+	}
+}
+
+// anthropicStreamEncoder Anthropic格式的事件编码器
+// 事件顺序为 message_start、ping、每个内容块的 content_block_start/delta/stop、message_delta、message_stop，
+// 推理过程输出为thinking内容块，正文输出为text内容块，每个工具调用输出为一个tool_use内容块
+type anthropicStreamEncoder struct {
+	inputTokens   int    // 输入token数，收到上游统计前使用估算值
+	outputTokens  int    // 输出token数
+	started       bool   // 已发送message_start
+	block         string // 当前打开的内容块，工具调用为 tool_use:序号
+	index         int    // 下一个内容块的序号
+	stopReason    string // 收到的完成原因
+	failed        bool   // 上游返回了错误
+	usageReceived bool   // 已收到token使用统计
+}
+
+// encode 写出一个分片对应的事件
+func (e *anthropicStreamEncoder) encode(w *bufio.Writer, resp *types.StreamResponse) (bool, error) {
+	// 上游返回错误后停止转发
+	if resp.Error != nil {
+		e.failed = true
+		return true, writeSSEEvent(w, "error", anthropicErrorBody{
+			Type:  "error",
+			Error: anthropicError{Type: "api_error", Message: resp.Error.Message},
+		})
+	}
+
+	if err := e.start(w, resp); err != nil {
+		return true, err
+	}
+
+	if len(resp.Choices) > 0 {
+		choice := resp.Choices[0]
+		if choice.Delta.Reasoning != "" {
+			if err := e.delta(w, "thinking", choice.Delta.Reasoning); err != nil {
+				return true, err
+			}
+		}
+		if choice.Delta.Content != "" {
+			if err := e.delta(w, "text", choice.Delta.Content); err != nil {
+				return true, err
+			}
+		}
+		for _, call := range choice.Delta.ToolCalls {
+			if err := e.toolDelta(w, call); err != nil {
+				return true, err
+			}
+		}
+		if choice.FinishReason != "" {
+			e.stopReason = anthropicStopReason(choice.FinishReason)
+		}
+	}
+
+	if resp.Usage != nil {
+		e.usageReceived = true
+		e.outputTokens = resp.Usage.CompletionTokens
+		if resp.Usage.PromptTokens > 0 {
+			e.inputTokens = resp.Usage.PromptTokens
+		}
+	}
+
+	// 等到收到token使用统计或上游结束，由finish输出结束事件
+	return e.stopReason != "" && e.usageReceived, nil
+}
+
+// start 发送message_start和ping事件
+func (e *anthropicStreamEncoder) start(w *bufio.Writer, resp *types.StreamResponse) error {
+	if e.started {
+		return nil
+	}
+	e.started = true
+
+	if resp.Usage != nil && resp.Usage.PromptTokens > 0 {
+		e.inputTokens = resp.Usage.PromptTokens
+	}
+
+	err := writeSSEEvent(w, "message_start", anthropicEvent{
+		Type: "message_start",
+		Message: &anthropicMessage{
+			ID:      resp.ID,
+			Type:    "message",
+			Role:    "assistant",
+			Model:   resp.Model,
+			Content: []anthropicContentBlock{},
+			Usage:   anthropicUsage{InputTokens: e.inputTokens},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return writeSSEEvent(w, "ping", anthropicEvent{Type: "ping"})
+}
+
+// delta 写出内容增量，内容类型变化时关闭当前内容块并打开新的内容块
+func (e *anthropicStreamEncoder) delta(w *bufio.Writer, blockType, text string) error {
+	if e.block != blockType {
+		empty := ""
+		block := &anthropicContentBlock{Type: blockType}
+		if blockType == "thinking" {
+			block.Thinking = &empty
+		} else {
+			block.Text = &empty
+		}
+
+		if err := e.startBlock(w, blockType, block); err != nil {
+			return err
+		}
+	}
+
+	delta := &anthropicDelta{Type: blockType + "_delta"}
+	if blockType == "thinking" {
+		delta.Thinking = &text
+	} else {
+		delta.Text = &text
+	}
+
+	index := e.index
+	return writeSSEEvent(w, "content_block_delta", anthropicEvent{
+		Type:  "content_block_delta",
+		Index: &index,
+		Delta: delta,
+	})
+}
+
+// toolDelta 写出工具调用增量，新的工具调用打开tool_use内容块，参数片段以input_json_delta输出
+func (e *anthropicStreamEncoder) toolDelta(w *bufio.Writer, call types.ToolCallDelta) error {
+	key := fmt.Sprintf("tool_use:%d", call.Index)
+	if e.block != key {
+		block := &anthropicContentBlock{
+			Type:  "tool_use",
+			ID:    call.ID,
+			Name:  call.Function.Name,
+			Input: json.RawMessage("{}"),
+		}
+		if err := e.startBlock(w, key, block); err != nil {
+			return err
+		}
+	}
+
+	if call.Function.Arguments == "" {
+		return nil
+	}
+
+	index := e.index
+	return writeSSEEvent(w, "content_block_delta", anthropicEvent{
+		Type:  "content_block_delta",
+		Index: &index,
+		Delta: &anthropicDelta{Type: "input_json_delta", PartialJSON: &call.Function.Arguments},
+	})
+}
+
+// startBlock 关闭当前内容块并打开新的内容块
+func (e *anthropicStreamEncoder) startBlock(w *bufio.Writer, key string, block *anthropicContentBlock) error {
+	if err := e.stopBlock(w); err != nil {
+		return err
+	}
+
+	index := e.index
+	if err := writeSSEEvent(w, "content_block_start", anthropicEvent{
+		Type:         "content_block_start",
+		Index:        &index,
+		ContentBlock: block,
+	}); err != nil {
+		return err
+	}
+	e.block = key
+	return nil
+}
+
+// stopBlock 关闭当前打开的内容块
+func (e *anthropicStreamEncoder) stopBlock(w *bufio.Writer) error {
+	if e.block == "" {
+		return nil
+	}
+
+	index := e.index
+	e.block = ""
+	e.index++
+	return writeSSEEvent(w, "content_block_stop", anthropicEvent{
+		Type:  "content_block_stop",
+		Index: &index,
+	})
+}
+
+// finish 关闭内容块并发送message_delta和message_stop事件，上游返回错误时不再发送
+func (e *anthropicStreamEncoder) finish(w *bufio.Writer) error {
+	if e.failed {
+		return nil
+	}
+
+	if !e.started {
+		if err := e.start(w, &types.StreamResponse{}); err != nil {
+			return err
+		}
+	}
+
+	if err := e.stopBlock(w); err != nil {
+		return err
+	}
+
+	stopReason := e.stopReason
+	if stopReason == "" {
+		stopReason = "end_turn"
+	}
+	if err := writeSSEEvent(w, "message_delta", anthropicEvent{
+		Type:  "message_delta",
+		Delta: &anthropicDelta{StopReason: &stopReason},
+		Usage: &anthropicUsage{InputTokens: e.inputTokens, OutputTokens: e.outputTokens},
+	}); err != nil {
+		return err
+	}
+
+	return writeSSEEvent(w, "message_stop", anthropicEvent{Type: "message_stop"})
+}
@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"bufio"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -15,8 +20,15 @@ import (
 
 // ChatHandler 聊天处理器
 type ChatHandler struct {
-	providerFactory *providers.ProviderFactory
-	loadBalancer    providers.LoadBalancer
+	providerFactory  *providers.ProviderFactory
+	loadBalancer     providers.LoadBalancer
+	failover         *providers.FailoverConfig
+	defaultRouting   string
+	contextConfig    *providers.ContextConfig
+	hedging          *providers.HedgingConfig
+	sticky           *providers.StickyConfig
+	rules            *providers.RuleEngine
+	structuredOutput *providers.StructuredOutputConfig
 }
 
 // NewChatHandler 创建聊天处理器实例
@@ -27,40 +39,118 @@ func NewChatHandler(factory *providers.ProviderFactory, balancer providers.LoadB
 	}
 }
 
-// ChatCompletion 处理聊天补全请求
+// SetFailover 设置故障转移配置
+func (h *ChatHandler) SetFailover(failover *providers.FailoverConfig) {
+	h.failover = failover
+}
+
+// SetDefaultRouting 设置请求未指定时使用的路由模式
+func (h *ChatHandler) SetDefaultRouting(mode string) {
+	h.defaultRouting = mode
+}
+
+// SetContextConfig 设置上下文长度检查配置
+func (h *ChatHandler) SetContextConfig(config *providers.ContextConfig) {
+	h.contextConfig = config
+}
+
+// SetHedging 设置对冲请求配置
+func (h *ChatHandler) SetHedging(config *providers.HedgingConfig) {
+	h.hedging = config
+}
+
+// SetSticky 设置会话粘性路由配置
+func (h *ChatHandler) SetSticky(config *providers.StickyConfig) {
+	h.sticky = config
+}
+
+// SetRules 设置路由规则引擎
+func (h *ChatHandler) SetRules(rules *providers.RuleEngine) {
+	h.rules = rules
+}
+
+// SetStructuredOutput 设置结构化输出配置
+func (h *ChatHandler) SetStructuredOutput(config *providers.StructuredOutputConfig) {
+	h.structuredOutput = config
+}
+
+// formatHeader 选择 /v1/chat/completions 响应格式的请求头，值为openai(默认)或unified
+const formatHeader = "X-LLM-Bridge-Format"
+
+// ChatCompletion 处理聊天补全请求，同时支持网关原有格式和OpenAI Chat Completions格式
+// 响应格式由请求头决定，请求体中的provider等网关扩展字段不会改变响应格式
 func (h *ChatHandler) ChatCompletion(c *fiber.Ctx) error {
-	// 记录请求开始时间用于统计
-	startTime := time.Now()
+	format := strings.ToLower(c.Get(formatHeader, types.FormatOpenAI))
+	if format != types.FormatOpenAI && format != types.FormatUnified {
+		return openAIResponder{}.sendError(c, fiber.StatusBadRequest, "invalid_format", "不支持的请求格式: "+format, "invalid_request_error")
+	}
 
 	// 解析请求体
 	var req types.UnifiedRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": fiber.Map{
-				"code":    "invalid_request",
-				"message": "请求体格式错误: " + err.Error(),
-				"type":    "invalid_request_error",
-			},
-		})
+		return openAIResponder{}.sendError(c, fiber.StatusBadRequest, "invalid_request", "请求体格式错误: "+err.Error(), "invalid_request_error")
 	}
+	req.Format = format
+
+	return h.complete(c, req, newResponder(&req))
+}
+
+// complete 执行聊天补全请求，响应和错误由responder按接入协议的格式输出
+func (h *ChatHandler) complete(c *fiber.Ctx, req types.UnifiedRequest, responder chatResponder) error {
+	// 记录请求开始时间用于统计
+	startTime := time.Now()
 
 	// 设置请求元数据
 	req.Metadata.ClientIP = c.IP()
 	req.Metadata.UserAgent = c.Get("User-Agent")
 	req.Metadata.Timestamp = time.Now()
+	if req.Metadata.SessionID == "" {
+		req.Metadata.SessionID = c.Get("X-Session-ID")
+	}
+	if req.Metadata.UserID == "" {
+		req.Metadata.UserID = c.Get("X-User-ID")
+	}
+
+	// 路由规则：按请求属性选择目标、覆盖参数或拒绝请求，X-LLM-Bridge-Rule响应头标注匹配的规则
+	if rule := h.rules.Match(providers.RuleInput{
+		Request: &req,
+		Header:  func(name string) string { return c.Get(name) },
+		Now:     req.Metadata.Timestamp,
+	}); rule != nil {
+		c.Set("X-LLM-Bridge-Rule", rule.Name())
+
+		if denied, message := rule.Denied(); denied {
+			return responder.sendError(c, fiber.StatusForbidden, "request_denied", message, "permission_error")
+		}
+
+		if err := rule.Apply(&req, h.isRegistered); err != nil {
+			return responder.sendError(c, fiber.StatusInternalServerError, "invalid_rule_target", err.Error(), "internal_server_error")
+		}
+	}
+
+	// 校验结构化输出格式
+	if err := req.Parameters.ResponseFormat.Validate(); err != nil {
+		return responder.sendError(c, fiber.StatusBadRequest, "invalid_response_format", err.Error(), "invalid_request_error")
+	}
 
 	// 处理提供商和模型的四种情况
 	var provider providers.ProviderAdapter
+	// 客户端和路由规则都没有指定提供商和模型，由网关自动选择
+	autoRouted := req.Provider == "" && req.Model == ""
 	
-	// 情况4：只指定了model但没有provider - 返回错误
+	// 情况4：只指定了model - 通过模型别名、provider/model前缀或唯一的模型名称确定提供商
 	if req.Model != "" && req.Provider == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": fiber.Map{
-				"code":    "missing_provider",
-				"message": "指定模型时必须同时指定提供商(provider)参数",
-				"type":    "invalid_request_error",
-			},
-		})
+		target, err := providers.ResolveModel(req.Model, h.isRegistered)
+		if err != nil {
+			code := "model_not_found"
+			var resolveErr *providers.ModelResolveError
+			if errors.As(err, &resolveErr) && resolveErr.Ambiguous() {
+				code = "ambiguous_model"
+			}
+			return responder.sendError(c, fiber.StatusBadRequest, code, err.Error(), "invalid_request_error")
+		}
+		req.Provider = target.Provider
+		req.Model = target.Model
 	}
 	
 	if req.Provider != "" {
@@ -68,182 +158,480 @@ func (h *ChatHandler) ChatCompletion(c *fiber.Ctx) error {
 		var exists bool
 		provider, exists = h.providerFactory.GetProvider(req.Provider)
 		if !exists {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": fiber.Map{
-					"code":    "invalid_provider",
-					"message": "不支持的LLM提供商: " + req.Provider,
-					"type":    "invalid_request_error",
-				},
-			})
+			return responder.sendError(c, fiber.StatusBadRequest, "invalid_provider", "不支持的LLM提供商: "+req.Provider, "invalid_request_error")
 		}
 		
 		// 情况2：有provider但没有model - 使用默认模型
 		if req.Model == "" {
 			defaultModel := providers.GetDefaultModel(req.Provider)
 			if defaultModel == "" {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-					"error": fiber.Map{
-						"code":    "no_default_model",
-						"message": "提供商 " + req.Provider + " 没有配置默认模型",
-						"type":    "internal_server_error",
-					},
-				})
+				return responder.sendError(c, fiber.StatusInternalServerError, "no_default_model", "提供商 "+req.Provider+" 没有配置默认模型", "internal_server_error")
 			}
 			req.Model = defaultModel
 		}
 		// 情况3：有provider和model - 正常处理（无需额外操作）
 	} else {
-		// 情况1：没有provider和model - 负载均衡选择
+		// 情况1：没有provider和model - 按路由模式选择
+		routing := h.defaultRouting
+		if req.Routing != nil && req.Routing.Mode != "" {
+			routing = req.Routing.Mode
+		}
+		if !providers.IsValidRoutingMode(routing) {
+			return responder.sendError(c, fiber.StatusBadRequest, "invalid_routing", "不支持的路由模式: "+routing, "invalid_request_error")
+		}
+
+		// 最低价格路由，没有满足要求的模型时退回负载均衡
+		if routing == providers.RoutingCheapest {
+			provider, req.Model = h.selectCheapest(&req)
+			if provider != nil {
+				req.Provider = provider.GetProviderName()
+			}
+		}
+	}
+
+	var stickyFallback providers.ProviderAdapter
+	if provider == nil {
 		allProviders := h.getAllProviders()
-		provider = h.loadBalancer.SelectProvider(allProviders)
+		if key := stickyKey(&req); key != "" && h.sticky.IsEnabled() {
+			// 会话粘性路由：同一会话固定到同一个提供商，该提供商熔断时迁移到排序中的下一个
+			ranked := h.loadBalancer.RankSticky(key, allProviders)
+			if len(ranked) > 0 {
+				provider = ranked[0]
+			}
+			if len(ranked) > 1 {
+				stickyFallback = ranked[1]
+			}
+		} else {
+			// 负载均衡选择
+			provider = h.loadBalancer.SelectProvider(allProviders)
+		}
 		if provider == nil {
-			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
-				"error": fiber.Map{
-					"code":    "no_provider_available",
-					"message": "当前没有可用的LLM提供商",
-					"type":    "service_unavailable_error",
-				},
-			})
+			return responder.sendError(c, fiber.StatusServiceUnavailable, "no_provider_available", "当前没有可用的LLM提供商", "service_unavailable_error")
 		}
 		req.Provider = provider.GetProviderName()
 		
 		// 使用负载均衡选中提供商的默认模型
 		defaultModel := providers.GetDefaultModel(req.Provider)
 		if defaultModel == "" {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": fiber.Map{
-					"code":    "no_default_model",
-					"message": "提供商 " + req.Provider + " 没有配置默认模型",
-					"type":    "internal_server_error",
-				},
-			})
+			return responder.sendError(c, fiber.StatusInternalServerError, "no_default_model", "提供商 "+req.Provider+" 没有配置默认模型", "internal_server_error")
 		}
 		req.Model = defaultModel
 	}
 
+	// 检查上下文长度，超出时自动切换到同系列更大上下文的模型
+	fittedModel, err := providers.FitContextWindow(req.Provider, req.Model, &req, h.contextConfig)
+	if err != nil {
+		return responder.sendError(c, fiber.StatusBadRequest, "context_length_exceeded", err.Error(), "invalid_request_error")
+	}
+	req.Model = fittedModel
+
 	// 验证请求参数
 	if err := provider.ValidateRequest(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": fiber.Map{
-				"code":    "invalid_request",
-				"message": "请求参数验证失败: " + err.Error(),
-				"type":    "invalid_request_error",
-			},
-		})
+		return responder.sendError(c, fiber.StatusBadRequest, "invalid_request", "请求参数验证失败: "+err.Error(), "invalid_request_error")
 	}
 
-	// 转换请求格式
-	providerData, err := provider.Transform(&req)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fiber.Map{
-				"code":    "transformation_error",
-				"message": "请求格式转换失败: " + err.Error(),
-				"type":    "internal_server_error",
-			},
-		})
+	// 创建请求上下文，非流式请求的整个故障转移链共用同一个超时时间
+	// 流式请求(如长时间的推理过程)不限制总时长，上游卡住由提供商的等待响应头超时和分片间隔超时中断；
+	// 流式响应在响应体写出结束后才取消，由streamResponse接管cancel
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if req.Parameters.Stream {
+		ctx, cancel = context.WithCancel(context.Background())
+	} else {
+		ctx, cancel = context.WithTimeout(context.Background(), 60*time.Second)
 	}
+	streaming := false
+	defer func() {
+		if !streaming {
+			cancel()
+		}
+	}()
 
-	// 创建请求上下文
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
+	// 按故障转移链依次尝试，首选目标失败时切换到下一个提供商/模型
+	chain := h.failover.BuildChain(providers.FailoverTarget{Provider: req.Provider, Model: req.Model}, h.isRegistered)
+	if stickyFallback != nil && len(chain) == 1 && h.failover.IsEnabled() {
+		// 没有配置备选链时，会话固定的提供商调用失败后切换到该会话排序中的下一个提供商
+		fallback := stickyFallback.GetProviderName()
+		if model := providers.GetDefaultModel(fallback); model != "" {
+			chain = append(chain, providers.FailoverTarget{Provider: fallback, Model: model})
+		}
+	}
+	var failure *chatFailure
+	next := 0
 
-	// 调用LLM API
-	resp, err := provider.CallAPI(ctx, providerData)
-	if err != nil {
-		// 更新提供商健康状态
-		h.loadBalancer.UpdateHealth(provider.GetProviderName(), false)
-		
-		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
-			"error": fiber.Map{
-				"code":    "api_call_failed",
-				"message": "调用LLM API失败: " + err.Error(),
-				"type":    "service_unavailable_error",
-			},
-		})
+	// 对冲请求：首选目标在延迟内没有完成时同时请求备选目标，使用先完成的结果
+	var hedgeOverride *bool
+	if req.Routing != nil {
+		hedgeOverride = req.Routing.Hedge
 	}
+	if h.hedging.IsEnabled(hedgeOverride) {
+		if secondary, secondaryReq, skip, ok := h.hedgeTarget(chain, &req, autoRouted); ok {
+			result, hedgeFailure := h.hedge(ctx, []hedgeAttempt{
+				{provider: provider, req: &req},
+				{provider: secondary, req: &secondaryReq},
+			})
+			if hedgeFailure == nil {
+				streaming = result.stream != nil
+				return h.sendHedgeResult(ctx, c, responder, result, startTime, cancel)
+			}
 
-	// 检查是否为流式请求
-	if req.Parameters.Stream {
-		return h.handleStreamResponse(c, provider, resp)
+			// 对冲的两个目标都失败时，继续尝试故障转移链中剩余的目标
+			failure = hedgeFailure
+			next = skip
+		}
+	}
+
+	for i := next; i < len(chain) && ctx.Err() == nil; i++ {
+		candidate := provider
+		attemptReq := req
+		if i > 0 {
+			var ok bool
+			candidate, attemptReq, ok = h.prepareTarget(chain[i], req)
+			if !ok {
+				continue
+			}
+		}
+
+		isLast := i == len(chain)-1
+		resp, attemptFailure := h.callProvider(ctx, candidate, &attemptReq, isLast)
+		if attemptFailure != nil {
+			failure = attemptFailure
+			continue
+		}
+
+		// 标注实际处理请求的提供商和模型
+		c.Set("X-LLM-Bridge-Provider", candidate.GetProviderName())
+		c.Set("X-LLM-Bridge-Model", attemptReq.Model)
+
+		// 检查是否为流式请求
+		if req.Parameters.Stream {
+			streaming = true
+			return h.handleStreamResponse(c, responder, &attemptReq, candidate, resp, cancel)
+		}
+
+		// 解析响应
+		unifiedResp, err := candidate.ParseResponse(resp)
+		if err != nil {
+			failure = &chatFailure{
+				status:  fiber.StatusInternalServerError,
+				code:    "response_parse_error",
+				message: "响应解析失败: " + err.Error(),
+				errType: "internal_server_error",
+			}
+			continue
+		}
+
+		// 结构化输出：校验输出是否为符合要求的JSON
+		unifiedResp, failure = h.checkStructuredOutput(ctx, candidate, &attemptReq, unifiedResp)
+		if failure != nil {
+			return responder.sendError(c, failure.status, failure.code, failure.message, failure.errType)
+		}
+
+		return h.sendResponse(c, responder, candidate, unifiedResp, startTime)
+	}
+
+	if failure == nil {
+		failure = &chatFailure{
+			status:  fiber.StatusServiceUnavailable,
+			code:    "no_provider_available",
+			message: "故障转移链中没有可用的LLM提供商",
+			errType: "service_unavailable_error",
+		}
 	}
 
-	// 解析响应
-	unifiedResp, err := provider.ParseResponse(resp)
+	return responder.sendError(c, failure.status, failure.code, failure.message, failure.errType)
+}
+
+// prepareTarget 为故障转移链中的备选目标生成请求
+// 提供商未注册、模型放不下当前对话或参数校验失败时返回false
+func (h *ChatHandler) prepareTarget(target providers.FailoverTarget, req types.UnifiedRequest) (providers.ProviderAdapter, types.UnifiedRequest, bool) {
+	candidate, exists := h.providerFactory.GetProvider(target.Provider)
+	if !exists {
+		return nil, req, false
+	}
+
+	req.Provider = target.Provider
+	req.Model = target.Model
+
+	fittedModel, err := providers.FitContextWindow(target.Provider, target.Model, &req, h.contextConfig)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fiber.Map{
-				"code":    "response_parse_error",
-				"message": "响应解析失败: " + err.Error(),
-				"type":    "internal_server_error",
-			},
-		})
+		return nil, req, false
 	}
+	req.Model = fittedModel
 
-	// 更新提供商健康状态为正常
-	h.loadBalancer.UpdateHealth(provider.GetProviderName(), true)
+	if err := candidate.ValidateRequest(&req); err != nil {
+		return nil, req, false
+	}
+
+	return candidate, req, true
+}
+
+// sendResponse 返回非流式响应并记录统计
+func (h *ChatHandler) sendResponse(c *fiber.Ctx, responder chatResponder, provider providers.ProviderAdapter, unifiedResp *types.UnifiedResponse, startTime time.Time) error {
+	unifiedResp.Provider = provider.GetProviderName()
+
+	// 上游返回错误或无法解析的响应
+	if unifiedResp.Error != nil {
+		return responder.sendResponse(c, unifiedResp)
+	}
 
 	// 记录统计数据到Redis
 	responseTime := time.Since(startTime)
 	tokens := unifiedResp.Usage.TotalTokens
-	
+
 	// 记录统计
 	if redisMetrics := stats.GetRedisMetrics(); redisMetrics != nil {
 		redisMetrics.IncrementRequest(provider.GetProviderName(), responseTime, tokens)
 	}
 
-	// 返回统一格式的响应
-	return c.JSON(unifiedResp)
+	// 按接入协议的格式返回响应
+	return responder.sendResponse(c, unifiedResp)
+}
+
+// chatFailure 单次调用失败的原因，所有目标都失败时返回最后一次的错误
+type chatFailure struct {
+	status  int
+	code    string
+	message string
+	errType string
+}
+
+// callProvider 调用单个提供商，返回可以继续解析的响应
+// isLast为false时，限流、鉴权失败和服务端错误等响应会被视为失败，以便切换到下一个目标
+func (h *ChatHandler) callProvider(ctx context.Context, provider providers.ProviderAdapter, req *types.UnifiedRequest, isLast bool) (*http.Response, *chatFailure) {
+	// 转换请求格式
+	providerData, err := provider.Transform(req)
+	if err != nil {
+		return nil, &chatFailure{
+			status:  fiber.StatusInternalServerError,
+			code:    "transformation_error",
+			message: "请求格式转换失败: " + err.Error(),
+			errType: "internal_server_error",
+		}
+	}
+
+	// 熔断器打开时直接跳过，半开状态下只放行探测请求
+	if !h.loadBalancer.Allow(provider.GetProviderName(), req.Model) {
+		return nil, &chatFailure{
+			status:  fiber.StatusServiceUnavailable,
+			code:    "circuit_open",
+			message: "提供商 " + provider.GetProviderName() + " 已熔断，暂时不接收请求",
+			errType: "service_unavailable_error",
+		}
+	}
+
+	// 调用LLM API，在途请求数在响应体关闭时释放
+	providerName := provider.GetProviderName()
+	h.loadBalancer.Acquire(providerName)
+	callStart := time.Now()
+
+	resp, err := provider.CallAPI(ctx, providerData)
+	if err != nil {
+		h.loadBalancer.Release(providerName)
+
+		// 记录失败，用于熔断器统计；请求被主动取消(如对冲请求中落后的一方)不算提供商的失败，只归还探测名额
+		if errors.Is(ctx.Err(), context.Canceled) {
+			h.loadBalancer.ReleaseProbe(providerName, req.Model)
+		} else {
+			h.loadBalancer.RecordResult(providerName, req.Model, false)
+		}
+
+		return nil, &chatFailure{
+			status:  fiber.StatusServiceUnavailable,
+			code:    "api_call_failed",
+			message: "调用LLM API失败: " + err.Error(),
+			errType: "service_unavailable_error",
+		}
+	}
+
+	// 服务端错误计入两级熔断器的失败率；限流和模型不存在只与该模型有关，只计入模型级熔断器
+	// 其余状态码(如请求内容导致的400)说明提供商可以正常响应
+	serverFailure := resp.StatusCode >= http.StatusInternalServerError
+	modelFailure := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusNotFound
+	if modelFailure {
+		h.loadBalancer.RecordResult(providerName, "", true)
+		h.loadBalancer.RecordModelResult(providerName, req.Model, false)
+	} else {
+		h.loadBalancer.RecordResult(providerName, req.Model, !serverFailure)
+	}
+	if !serverFailure && !modelFailure {
+		h.loadBalancer.RecordLatency(providerName, time.Since(callStart))
+	}
+	resp.Body = &releaseOnClose{
+		ReadCloser: resp.Body,
+		release:    func() { h.loadBalancer.Release(providerName) },
+	}
+
+	if isLast || !shouldFailover(resp.StatusCode) {
+		return resp, nil
+	}
+
+	resp.Body.Close()
+
+	failure := &chatFailure{
+		status:  fiber.StatusBadGateway,
+		code:    "upstream_error",
+		message: fmt.Sprintf("%s API返回错误状态码: %d", provider.GetProviderName(), resp.StatusCode),
+		errType: "upstream_error",
+	}
+	// 上游返回的4xx状态码原样透传
+	if status := upstreamStatus(resp.StatusCode); status != fiber.StatusBadGateway {
+		failure.status = status
+		failure.code = openAIErrorCode(status, "")
+		failure.errType = openAIErrorType(status, "")
+	}
+	return nil, failure
+}
+
+// releaseOnClose 响应体关闭时执行一次release，用于释放在途请求数
+type releaseOnClose struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+// Close 关闭响应体
+func (r *releaseOnClose) Close() error {
+	r.once.Do(r.release)
+	return r.ReadCloser.Close()
+}
+
+// shouldFailover 判断上游状态码是否应该切换到下一个目标
+// 请求本身有误(400)时换提供商也无法成功，不做故障转移
+func shouldFailover(statusCode int) bool {
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound,
+		http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return statusCode >= http.StatusInternalServerError
+}
+
+// handleStreamResponse 处理流式响应，done在响应写出结束后调用
+func (h *ChatHandler) handleStreamResponse(c *fiber.Ctx, responder chatResponder, req *types.UnifiedRequest, provider providers.ProviderAdapter, resp *http.Response, done func()) error {
+	// 获取流式响应channel
+	streamChan, err := provider.ParseStreamResponse(resp)
+	if err != nil {
+		done()
+		return responder.sendError(c, fiber.StatusBadGateway, "stream_parse_error", "流式响应解析失败: "+err.Error(), "upstream_error")
+	}
+
+	return h.streamResponse(c, responder, req, provider, nil, streamChan, done)
 }
 
-// handleStreamResponse 处理流式响应
-func (h *ChatHandler) handleStreamResponse(c *fiber.Ctx, provider providers.ProviderAdapter, resp *http.Response) error {
-	// 设置流式响应头
+// setStreamHeaders 设置流式响应头
+func setStreamHeaders(c *fiber.Ctx) {
 	c.Set("Content-Type", "text/event-stream")
 	c.Set("Cache-Control", "no-cache")
 	c.Set("Connection", "keep-alive")
 	c.Set("Access-Control-Allow-Origin", "*")
 	c.Set("Access-Control-Allow-Headers", "Cache-Control")
+}
 
-	// 获取流式响应channel
-	streamChan, err := provider.ParseStreamResponse(resp)
-	if err != nil {
-		// 发送错误事件
-		errorEvent := fmt.Sprintf("data: {\"error\":{\"code\":\"stream_parse_error\",\"message\":\"流式响应解析失败: %s\"}}\n\n", err.Error())
-		return c.SendString(errorEvent)
+// streamResponse 以SSE形式逐个写出流式分片，first不为nil时先写出已经读取的第一个分片
+// 分片在写出响应体时才从上游读取，写出结束或客户端断开后调用done释放请求上下文
+func (h *ChatHandler) streamResponse(c *fiber.Ctx, responder chatResponder, req *types.UnifiedRequest, provider providers.ProviderAdapter, first *types.StreamResponse, streamChan <-chan *types.StreamResponse, done func()) error {
+	setStreamHeaders(c)
+	encoder := responder.newStreamEncoder(req)
+	if format := req.Parameters.ResponseFormat; format.IsJSON() {
+		encoder = &structuredStreamEncoder{streamEncoder: encoder, format: format}
 	}
+	providerName := provider.GetProviderName()
 
-	// 逐个发送流式事件
-	for streamResp := range streamChan {
-		// 将StreamResponse转换为JSON
-		jsonData, err := json.Marshal(streamResp)
-		if err != nil {
-			continue // 跳过无法序列化的响应
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer done()
+
+		// 提前结束时丢弃剩余的分片，避免解析协程阻塞
+		defer func() { go drainStream(streamChan) }()
+
+		send := func(streamResp *types.StreamResponse) bool {
+			streamResp.Provider = providerName
+			stop, err := encoder.encode(w, streamResp)
+			if err == nil {
+				err = w.Flush()
+			}
+			// 写出失败说明客户端已断开连接
+			return stop || err != nil
 		}
-		
-		// 发送SSE事件
-		event := fmt.Sprintf("data: %s\n\n", string(jsonData))
-		if err := c.SendString(event); err != nil {
-			// 客户端断开连接
-			break
+
+		if first == nil || !send(first) {
+			for streamResp := range streamChan {
+				if send(streamResp) {
+					break
+				}
+			}
 		}
-		
-		// 检查是否完成
-		if len(streamResp.Choices) > 0 && streamResp.Choices[0].FinishReason != "" {
-			break
+
+		// 发送完成事件
+		if err := encoder.finish(w); err == nil {
+			w.Flush()
+		}
+	})
+
+	return nil
+}
+
+// drainStream 读取并丢弃剩余的流式分片，直到channel关闭
+func drainStream(streamChan <-chan *types.StreamResponse) {
+	for range streamChan {
+	}
+}
+
+// selectCheapest 选择满足能力要求、熔断器可用且估算费用最低的模型
+func (h *ChatHandler) selectCheapest(req *types.UnifiedRequest) (providers.ProviderAdapter, string) {
+	// 按估算的输入token数和输出预留计算费用，上下文窗口需要放得下整个对话
+	promptTokens := providers.EstimateMessagesTokens(req.Messages)
+	requiredTokens := providers.RequiredContextTokens(req, h.contextConfig)
+
+	requirements := providers.ModelRequirements{
+		MinContextWindow: requiredTokens,
+		Reasoning:        req.Parameters.Reasoning,
+		Streaming:        req.Parameters.Stream,
+	}
+	if req.Routing != nil && req.Routing.MinContextWindow > requirements.MinContextWindow {
+		requirements.MinContextWindow = req.Routing.MinContextWindow
+	}
+
+	candidates := providers.CheapestModels(h.providerFactory.ListProviders(), requirements, promptTokens, requiredTokens-promptTokens)
+	for _, info := range candidates {
+		if !h.loadBalancer.IsAvailable(info.Provider, info.Model) {
+			continue
 		}
+		if provider, exists := h.providerFactory.GetProvider(info.Provider); exists {
+			return provider, info.Model
+		}
+	}
+
+	return nil, ""
+}
+
+// stickyKey 会话粘性路由使用的key，优先使用会话ID，其次是用户ID
+func stickyKey(req *types.UnifiedRequest) string {
+	if req.Metadata.SessionID != "" {
+		return "session:" + req.Metadata.SessionID
+	}
+	if req.Metadata.UserID != "" {
+		return "user:" + req.Metadata.UserID
 	}
+	return ""
+}
 
-	// 发送完成事件
-	return c.SendString("data: [DONE]\n\n")
+// isRegistered 判断提供商是否已注册
+func (h *ChatHandler) isRegistered(providerName string) bool {
+	_, exists := h.providerFactory.GetProvider(providerName)
+	return exists
 }
 
 // getAllProviders 获取所有可用的提供商
+// 没有可用模型的提供商(如Ollama尚未获取到模型列表)不参与自动选择
 func (h *ChatHandler) getAllProviders() []providers.ProviderAdapter {
 	providerNames := h.providerFactory.ListProviders()
 	allProviders := make([]providers.ProviderAdapter, 0, len(providerNames))
 	
 	for _, name := range providerNames {
+		if providers.GetDefaultModel(name) == "" {
+			continue
+		}
 		if provider, exists := h.providerFactory.GetProvider(name); exists {
 			allProviders = append(allProviders, provider)
 		}
@@ -253,35 +641,48 @@ func (h *ChatHandler) getAllProviders() []providers.ProviderAdapter {
 }
 
 // Models 获取支持的模型列表
+// 已注册提供商的模型以 provider/model 形式返回，配置的模型别名同样可以直接作为model使用
 func (h *ChatHandler) Models(c *fiber.Ctx) error {
-	// 返回支持的模型列表
-	// 这里可以根据已注册的提供商动态生成
-	models := []map[string]interface{}{
-		{
-			"id":      "gpt-3.5-turbo",
-			"object":  "model",
-			"created": time.Now().Unix(),
-			"owned_by": "openai",
-			"provider": "openai",
-		},
-		{
-			"id":      "gpt-4",
-			"object":  "model", 
-			"created": time.Now().Unix(),
-			"owned_by": "openai",
-			"provider": "openai",
-		},
-		{
-			"id":      "claude-3-sonnet",
-			"object":  "model",
-			"created": time.Now().Unix(),
-			"owned_by": "anthropic",
-			"provider": "claude",
-		},
+	created := time.Now().Unix()
+	models := make([]map[string]interface{}, 0)
+
+	providerNames := h.providerFactory.ListProviders()
+	sort.Strings(providerNames)
+	for _, providerName := range providerNames {
+		for _, model := range providers.GetProviderModels(providerName) {
+			models = append(models, map[string]interface{}{
+				"id":       providerName + "/" + model,
+				"object":   "model",
+				"created":  created,
+				"owned_by": providerName,
+				"provider": providerName,
+			})
+		}
+	}
+
+	aliases := providers.ModelAliases()
+	aliasNames := make([]string, 0, len(aliases))
+	for alias := range aliases {
+		aliasNames = append(aliasNames, alias)
+	}
+	sort.Strings(aliasNames)
+	for _, alias := range aliasNames {
+		target, err := providers.ResolveModel(alias, h.isRegistered)
+		if err != nil || !h.isRegistered(target.Provider) {
+			continue
+		}
+		models = append(models, map[string]interface{}{
+			"id":       alias,
+			"object":   "model",
+			"created":  created,
+			"owned_by": target.Provider,
+			"provider": target.Provider,
+			"target":   target.String(),
+		})
 	}
 
 	return c.JSON(fiber.Map{
 		"object": "list",
 		"data":   models,
 	})
-}
\ No newline at end of file
+}
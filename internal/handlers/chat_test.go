@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/heyanxiao/llm-bridge/internal/providers"
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// fakeProvider 返回固定回复的提供商
+type fakeProvider struct{}
+
+func (fakeProvider) Transform(req *types.UnifiedRequest) ([]byte, error) { return []byte("{}"), nil }
+
+func (fakeProvider) CallAPI(ctx context.Context, data []byte) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+}
+
+func (fakeProvider) ParseResponse(resp *http.Response) (*types.UnifiedResponse, error) {
+	resp.Body.Close()
+	return &types.UnifiedResponse{
+		ID:      "resp-1",
+		Model:   "deepseek-chat",
+		Choices: []types.Choice{{Message: types.Message{Role: "assistant", Content: "你好"}, FinishReason: "stop"}},
+	}, nil
+}
+
+func (fakeProvider) ParseStreamResponse(resp *http.Response) (<-chan *types.StreamResponse, error) {
+	return nil, nil
+}
+
+func (fakeProvider) GetProviderName() string { return "deepseek" }
+
+func (fakeProvider) ValidateRequest(req *types.UnifiedRequest) error { return nil }
+
+// newTestChatApp 创建只注册了fakeProvider的聊天接口
+func newTestChatApp(t *testing.T) *fiber.App {
+	t.Helper()
+	factory := providers.NewProviderFactory()
+	factory.RegisterProvider("deepseek", fakeProvider{})
+	balancer, err := providers.NewLoadBalancer(providers.LoadBalancerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Post("/v1/chat/completions", NewChatHandler(factory, balancer).ChatCompletion)
+	return app
+}
+
+// TestChatCompletionFormat 响应格式由请求头决定，请求体中的provider和parameters字段不改变响应格式
+func TestChatCompletionFormat(t *testing.T) {
+	body := `{"model":"deepseek-chat","provider":"deepseek","parameters":{"max_tokens":10},"messages":[{"role":"user","content":"你好"}]}`
+
+	tests := []struct {
+		name         string
+		header       string
+		wantStatus   int
+		wantObject   string
+		wantProvider bool
+	}{
+		{name: "默认为OpenAI格式", wantStatus: 200, wantObject: "chat.completion"},
+		{name: "请求头选择原有格式", header: "unified", wantStatus: 200, wantProvider: true},
+		{name: "不支持的格式", header: "xml", wantStatus: fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.header != "" {
+				req.Header.Set(formatHeader, tt.header)
+			}
+
+			resp, err := newTestChatApp(t).Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("状态码 %d, 期望 %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus != 200 {
+				return
+			}
+
+			var got map[string]interface{}
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if object, _ := got["object"].(string); object != tt.wantObject {
+				t.Errorf("object = %q, 期望 %q", object, tt.wantObject)
+			}
+			if _, hasProvider := got["provider"]; hasProvider != tt.wantProvider {
+				t.Errorf("provider字段存在: %v, 期望 %v", hasProvider, tt.wantProvider)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/heyanxiao/llm-bridge/internal/providers"
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// GeminiGenerateContent 处理Gemini generateContent和streamGenerateContent格式的请求
+// 路径格式为 /v1beta/models/{model}:generateContent，模型名称可以包含提供商前缀
+func (h *ChatHandler) GeminiGenerateContent(c *fiber.Ctx) error {
+	responder := geminiResponder{}
+
+	path, err := url.PathUnescape(c.Params("*"))
+	if err != nil {
+		path = c.Params("*")
+	}
+
+	// 模型名称中可能包含冒号以外的任意字符，按最后一个冒号拆分
+	idx := strings.LastIndex(path, ":")
+	if idx <= 0 {
+		return responder.sendError(c, fiber.StatusNotFound, "not_found", "不支持的接口: "+path, "invalid_request_error")
+	}
+	model, method := path[:idx], path[idx+1:]
+
+	var stream bool
+	switch method {
+	case "generateContent":
+	case "streamGenerateContent":
+		stream = true
+	default:
+		return responder.sendError(c, fiber.StatusNotFound, "not_found", "不支持的方法: "+method, "invalid_request_error")
+	}
+
+	var geminiReq types.GeminiRequest
+	if err := json.Unmarshal(c.Body(), &geminiReq); err != nil {
+		return responder.sendError(c, fiber.StatusBadRequest, "invalid_request", "请求体格式错误: "+err.Error(), "invalid_request_error")
+	}
+
+	return h.complete(c, geminiReq.ToUnified(model, stream), responder)
+}
+
+// geminiErrorBody Gemini格式的错误响应
+type geminiErrorBody struct {
+	Error geminiError `json:"error"`
+}
+
+// geminiError Gemini格式的错误信息
+type geminiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+// geminiResponse Gemini格式的GenerateContentResponse，同时用于非流式响应和流式分片
+type geminiResponse struct {
+	Candidates    []geminiCandidate `json:"candidates"`
+	UsageMetadata *geminiUsage      `json:"usageMetadata,omitempty"`
+	ModelVersion  string            `json:"modelVersion"`
+	ResponseID    string            `json:"responseId"`
+}
+
+// geminiCandidate Gemini格式的候选结果
+type geminiCandidate struct {
+	Content      *types.GeminiContent `json:"content,omitempty"`
+	FinishReason string               `json:"finishReason,omitempty"`
+	Index        int                  `json:"index"`
+}
+
+// geminiUsage Gemini格式的token使用统计
+type geminiUsage struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+// geminiFinishReason 将统一的finish_reason映射为Gemini的finishReason
+func geminiFinishReason(reason string) string {
+	switch reason {
+	case "":
+		return ""
+	case "length":
+		return "MAX_TOKENS"
+	case "content_filter":
+		return "SAFETY"
+	default:
+		return "STOP"
+	}
+}
+
+// geminiErrorStatus 按HTTP状态码确定Gemini的错误状态
+func geminiErrorStatus(status int) string {
+	switch status {
+	case fiber.StatusBadRequest:
+		return "INVALID_ARGUMENT"
+	case fiber.StatusUnauthorized:
+		return "UNAUTHENTICATED"
+	case fiber.StatusForbidden:
+		return "PERMISSION_DENIED"
+	case fiber.StatusNotFound:
+		return "NOT_FOUND"
+	case fiber.StatusTooManyRequests:
+		return "RESOURCE_EXHAUSTED"
+	case fiber.StatusServiceUnavailable:
+		return "UNAVAILABLE"
+	case fiber.StatusGatewayTimeout:
+		return "DEADLINE_EXCEEDED"
+	default:
+		return "INTERNAL"
+	}
+}
+
+// toGeminiUsage 转换为Gemini格式的token使用统计
+func toGeminiUsage(usage *types.Usage) *geminiUsage {
+	return &geminiUsage{
+		PromptTokenCount:     usage.PromptTokens,
+		CandidatesTokenCount: usage.CompletionTokens,
+		TotalTokenCount:      usage.TotalTokens,
+	}
+}
+
+// geminiFunctionCallPart 生成functionCall片段
+func geminiFunctionCallPart(id, name, arguments string) types.GeminiPart {
+	return types.GeminiPart{
+		FunctionCall: &types.GeminiFunctionCall{ID: id, Name: name, Args: argumentsObject(arguments)},
+	}
+}
+
+// geminiResponder Gemini generateContent格式
+type geminiResponder struct{}
+
+// sendResponse 输出Gemini格式的响应
+func (r geminiResponder) sendResponse(c *fiber.Ctx, resp *types.UnifiedResponse) error {
+	if resp.Error != nil {
+		return r.sendError(c, upstreamStatus(resp.Error.Status), resp.Error.Code, resp.Error.Message, resp.Error.Type)
+	}
+
+	geminiResp := geminiResponse{
+		Candidates:    make([]geminiCandidate, len(resp.Choices)),
+		UsageMetadata: toGeminiUsage(&resp.Usage),
+		ModelVersion:  resp.Model,
+		ResponseID:    resp.ID,
+	}
+	for i, choice := range resp.Choices {
+		parts := make([]types.GeminiPart, 0, len(choice.Message.ToolCalls)+1)
+		if choice.Message.Content != "" || len(choice.Message.ToolCalls) == 0 {
+			parts = append(parts, types.GeminiPart{Text: choice.Message.Content})
+		}
+		for _, call := range choice.Message.ToolCalls {
+			parts = append(parts, geminiFunctionCallPart(call.ID, call.Function.Name, call.Function.Arguments))
+		}
+
+		geminiResp.Candidates[i] = geminiCandidate{
+			Content:      &types.GeminiContent{Role: "model", Parts: parts},
+			FinishReason: geminiFinishReason(choice.FinishReason),
+			Index:        choice.Index,
+		}
+	}
+
+	return c.JSON(geminiResp)
+}
+
+// sendError 输出Gemini格式的错误，错误状态由HTTP状态码决定
+func (geminiResponder) sendError(c *fiber.Ctx, status int, code, message, errType string) error {
+	return c.Status(status).JSON(geminiErrorBody{
+		Error: geminiError{Code: status, Message: message, Status: geminiErrorStatus(status)},
+	})
+}
+
+// newStreamEncoder 创建Gemini格式的分片编码器
+func (geminiResponder) newStreamEncoder(req *types.UnifiedRequest) streamEncoder {
+	return &geminiStreamEncoder{}
+}
+
+// geminiStreamEncoder Gemini格式的分片编码器，按 alt=sse 的格式输出，每个事件都是一个完整的GenerateContentResponse
+// Gemini的函数调用不分片，工具调用增量拼接完整后在带有完成原因的分片中输出
+type geminiStreamEncoder struct {
+	progress      providers.StreamProgress  // 各候选结果是否已收到完成原因
+	usageReceived bool                      // 已收到token使用统计
+	toolCalls     map[int][]*types.ToolCall // 各候选结果拼接中的工具调用，按增量的index排列
+	id, model     string                    // 最近一个分片的响应ID和模型
+}
+
+// encode 写出一个分片，只有角色没有内容的分片不输出
+func (e *geminiStreamEncoder) encode(w *bufio.Writer, resp *types.StreamResponse) (bool, error) {
+	// 上游返回错误后停止转发
+	if resp.Error != nil {
+		return true, writeSSE(w, geminiErrorBody{
+			Error: geminiError{Code: fiber.StatusBadGateway, Message: resp.Error.Message, Status: "INTERNAL"},
+		})
+	}
+
+	e.id, e.model = resp.ID, resp.Model
+	chunk := geminiResponse{
+		Candidates:   make([]geminiCandidate, 0, len(resp.Choices)),
+		ModelVersion: resp.Model,
+		ResponseID:   resp.ID,
+	}
+	for _, choice := range resp.Choices {
+		e.appendToolCalls(choice.Index, choice.Delta.ToolCalls)
+
+		candidate := geminiCandidate{
+			FinishReason: geminiFinishReason(choice.FinishReason),
+			Index:        choice.Index,
+		}
+
+		var parts []types.GeminiPart
+		if choice.Delta.Reasoning != "" {
+			parts = append(parts, types.GeminiPart{Text: choice.Delta.Reasoning, Thought: true})
+		}
+		if choice.Delta.Content != "" {
+			parts = append(parts, types.GeminiPart{Text: choice.Delta.Content})
+		}
+		if candidate.FinishReason != "" {
+			parts = append(parts, e.takeToolCalls(choice.Index)...)
+		}
+		if len(parts) > 0 {
+			candidate.Content = &types.GeminiContent{Role: "model", Parts: parts}
+		}
+
+		if candidate.Content != nil || candidate.FinishReason != "" {
+			chunk.Candidates = append(chunk.Candidates, candidate)
+		}
+	}
+	e.progress.Observe(resp)
+
+	if resp.Usage != nil {
+		e.usageReceived = true
+		chunk.UsageMetadata = toGeminiUsage(resp.Usage)
+	}
+
+	if len(chunk.Candidates) > 0 || chunk.UsageMetadata != nil {
+		if err := writeSSE(w, chunk); err != nil {
+			return true, err
+		}
+	}
+
+	// 等到收到token使用统计或上游结束再停止
+	return e.progress.Complete() && e.usageReceived, nil
+}
+
+// appendToolCalls 拼接工具调用增量
+func (e *geminiStreamEncoder) appendToolCalls(choice int, deltas []types.ToolCallDelta) {
+	if len(deltas) > 0 && e.toolCalls == nil {
+		e.toolCalls = make(map[int][]*types.ToolCall)
+	}
+	for _, delta := range deltas {
+		for len(e.toolCalls[choice]) <= delta.Index {
+			e.toolCalls[choice] = append(e.toolCalls[choice], &types.ToolCall{Type: "function"})
+		}
+
+		call := e.toolCalls[choice][delta.Index]
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		if delta.Function.Name != "" {
+			call.Function.Name = delta.Function.Name
+		}
+		call.Function.Arguments += delta.Function.Arguments
+	}
+}
+
+// takeToolCalls 取出候选结果拼接完成的工具调用，转换为functionCall片段
+func (e *geminiStreamEncoder) takeToolCalls(choice int) []types.GeminiPart {
+	parts := make([]types.GeminiPart, 0, len(e.toolCalls[choice]))
+	for _, call := range e.toolCalls[choice] {
+		parts = append(parts, geminiFunctionCallPart(call.ID, call.Function.Name, call.Function.Arguments))
+	}
+	delete(e.toolCalls, choice)
+	return parts
+}
+
+// finish Gemini的流没有结束事件，上游没有返回完成原因时在这里输出剩余的工具调用
+func (e *geminiStreamEncoder) finish(w *bufio.Writer) error {
+	if len(e.toolCalls) == 0 {
+		return nil
+	}
+
+	indexes := make([]int, 0, len(e.toolCalls))
+	for index := range e.toolCalls {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	candidates := make([]geminiCandidate, 0, len(indexes))
+	for _, index := range indexes {
+		candidates = append(candidates, geminiCandidate{
+			Content:      &types.GeminiContent{Role: "model", Parts: e.takeToolCalls(index)},
+			FinishReason: "STOP",
+			Index:        index,
+		})
+	}
+
+	return writeSSE(w, geminiResponse{
+		Candidates:   candidates,
+		ModelVersion: e.model,
+		ResponseID:   e.id,
+	})
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/heyanxiao/llm-bridge/internal/providers"
+	"github.com/heyanxiao/llm-bridge/internal/stats"
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// hedgeAttempt 对冲请求中的一个目标
+type hedgeAttempt struct {
+	provider providers.ProviderAdapter
+	req      *types.UnifiedRequest
+}
+
+// hedgeResult 对冲请求中一次调用的结果
+type hedgeResult struct {
+	index   int
+	attempt hedgeAttempt
+	resp    *types.UnifiedResponse       // 非流式请求的响应
+	first   *types.StreamResponse        // 流式请求的第一个分片
+	stream  <-chan *types.StreamResponse // 流式请求剩余的分片
+	failure *chatFailure
+	elapsed time.Duration
+	hedged  bool // 是否实际发出了对冲请求
+}
+
+// hedgeTarget 选择对冲请求的备选目标
+// 优先使用故障转移链中的下一个可用目标，自动选择的请求没有可用的备选目标时由负载均衡器选择其他提供商的默认模型
+// 指定了提供商或模型的请求只使用为该目标显式配置的备选链，不会被对冲到其他模型
+// skip为对冲失败后故障转移链继续尝试的起始位置
+func (h *ChatHandler) hedgeTarget(chain []providers.FailoverTarget, req *types.UnifiedRequest, autoRouted bool) (providers.ProviderAdapter, types.UnifiedRequest, int, bool) {
+	if !autoRouted && !h.failover.HasChain(chain[0]) {
+		return nil, *req, 0, false
+	}
+
+	for i := 1; i < len(chain); i++ {
+		if candidate, candidateReq, ok := h.prepareTarget(chain[i], *req); ok {
+			return candidate, candidateReq, i + 1, true
+		}
+	}
+	if !autoRouted {
+		return nil, *req, 0, false
+	}
+
+	others := make([]providers.ProviderAdapter, 0)
+	for _, candidate := range h.getAllProviders() {
+		if candidate.GetProviderName() != req.Provider {
+			others = append(others, candidate)
+		}
+	}
+
+	selected := h.loadBalancer.SelectProvider(others)
+	if selected == nil {
+		return nil, *req, 0, false
+	}
+
+	target := providers.FailoverTarget{
+		Provider: selected.GetProviderName(),
+		Model:    providers.GetDefaultModel(selected.GetProviderName()),
+	}
+	if target.Model == "" {
+		return nil, *req, 0, false
+	}
+
+	candidate, candidateReq, ok := h.prepareTarget(target, *req)
+	return candidate, candidateReq, len(chain), ok
+}
+
+// hedge 发起对冲请求
+// 先请求第一个目标，超过对冲延迟仍未完成(流式请求为未返回第一个分片)或已经失败时请求下一个目标，
+// 返回最先成功的结果并取消其余请求
+func (h *ChatHandler) hedge(ctx context.Context, attempts []hedgeAttempt) (*hedgeResult, *chatFailure) {
+	results := make(chan *hedgeResult, len(attempts))
+	cancels := make([]context.CancelFunc, 0, len(attempts))
+
+	launch := func() {
+		index := len(cancels)
+		attemptCtx, attemptCancel := context.WithCancel(ctx)
+		cancels = append(cancels, attemptCancel)
+		go func() {
+			results <- h.runHedgeAttempt(attemptCtx, index, attempts[index])
+		}()
+	}
+
+	timer := time.NewTimer(h.hedging.Delay())
+	defer timer.Stop()
+
+	launch()
+	pending := 1
+	var failed []*hedgeResult
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if len(cancels) < len(attempts) {
+				launch()
+				pending++
+				timer.Reset(h.hedging.Delay())
+			}
+
+		case result := <-results:
+			pending--
+			hedged := len(cancels) > 1
+
+			if result.failure != nil {
+				failed = append(failed, result)
+
+				// 当前目标已经失败，不再等待延迟，立即请求下一个目标
+				if len(cancels) < len(attempts) {
+					launch()
+					pending++
+				}
+				continue
+			}
+
+			// 取消落后的请求，它们的结果在后台丢弃
+			for i, cancel := range cancels {
+				if i != result.index {
+					cancel()
+				}
+			}
+			if pending > 0 {
+				go discardHedgeResults(results, pending)
+			}
+
+			result.hedged = hedged
+			if hedged {
+				recordHedgeAttempt(result, true)
+				for _, f := range failed {
+					recordHedgeAttempt(f, false)
+				}
+			}
+			return result, nil
+		}
+	}
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+	if len(cancels) > 1 {
+		for _, f := range failed {
+			recordHedgeAttempt(f, false)
+		}
+	}
+	return nil, failed[len(failed)-1].failure
+}
+
+// runHedgeAttempt 执行对冲请求中的一次调用
+// 非流式请求读取完整响应，流式请求读取到第一个分片为止
+func (h *ChatHandler) runHedgeAttempt(ctx context.Context, index int, attempt hedgeAttempt) *hedgeResult {
+	start := time.Now()
+	result := &hedgeResult{index: index, attempt: attempt}
+	defer func() {
+		result.elapsed = time.Since(start)
+	}()
+
+	providerName := attempt.provider.GetProviderName()
+
+	resp, failure := h.callProvider(ctx, attempt.provider, attempt.req, false)
+	if failure != nil {
+		result.failure = failure
+		return result
+	}
+
+	if !attempt.req.Parameters.Stream {
+		unifiedResp, err := attempt.provider.ParseResponse(resp)
+		if err != nil {
+			result.failure = &chatFailure{
+				status:  fiber.StatusInternalServerError,
+				code:    "response_parse_error",
+				message: "响应解析失败: " + err.Error(),
+				errType: "internal_server_error",
+			}
+			return result
+		}
+		result.resp = unifiedResp
+		return result
+	}
+
+	streamChan, err := attempt.provider.ParseStreamResponse(resp)
+	if err != nil {
+		result.failure = &chatFailure{
+			status:  fiber.StatusBadGateway,
+			code:    "stream_parse_error",
+			message: "流式响应解析失败: " + err.Error(),
+			errType: "upstream_error",
+		}
+		return result
+	}
+
+	first, ok := <-streamChan
+	if !ok {
+		result.failure = &chatFailure{
+			status:  fiber.StatusBadGateway,
+			code:    "upstream_error",
+			message: fmt.Sprintf("%s 流式响应在返回内容前结束", providerName),
+			errType: "upstream_error",
+		}
+		return result
+	}
+	if first.Error != nil {
+		go drainStream(streamChan)
+		result.failure = &chatFailure{
+			status:  fiber.StatusBadGateway,
+			code:    "upstream_error",
+			message: fmt.Sprintf("%s 流式响应返回错误: %s", providerName, first.Error.Message),
+			errType: "upstream_error",
+		}
+		return result
+	}
+
+	result.first = first
+	result.stream = streamChan
+	return result
+}
+
+// discardHedgeResults 丢弃已取消的对冲请求的结果
+func discardHedgeResults(results <-chan *hedgeResult, pending int) {
+	for i := 0; i < pending; i++ {
+		result := <-results
+		if result.stream != nil {
+			drainStream(result.stream)
+		}
+		recordHedgeAttempt(result, false)
+	}
+}
+
+// recordHedgeAttempt 记录对冲请求中一次调用的统计
+func recordHedgeAttempt(result *hedgeResult, won bool) {
+	if redisMetrics := stats.GetRedisMetrics(); redisMetrics != nil {
+		redisMetrics.RecordHedgeAttempt(result.attempt.provider.GetProviderName(), result.elapsed, won)
+	}
+}
+
+// sendHedgeResult 返回对冲请求中最先成功的结果，done在响应写出结束后调用
+func (h *ChatHandler) sendHedgeResult(ctx context.Context, c *fiber.Ctx, responder chatResponder, result *hedgeResult, startTime time.Time, done func()) error {
+	provider := result.attempt.provider
+
+	// 标注实际处理请求的提供商和模型
+	c.Set("X-LLM-Bridge-Provider", provider.GetProviderName())
+	c.Set("X-LLM-Bridge-Model", result.attempt.req.Model)
+	if result.hedged {
+		c.Set("X-LLM-Bridge-Hedged", "true")
+	}
+
+	if result.stream != nil {
+		return h.streamResponse(c, responder, result.attempt.req, provider, result.first, result.stream, done)
+	}
+
+	// 结构化输出：校验输出是否为符合要求的JSON
+	resp, failure := h.checkStructuredOutput(ctx, provider, result.attempt.req, result.resp)
+	if failure != nil {
+		return responder.sendError(c, failure.status, failure.code, failure.message, failure.errType)
+	}
+
+	return h.sendResponse(c, responder, provider, resp, startTime)
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"bufio"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/heyanxiao/llm-bridge/internal/providers"
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// openAIErrorBody OpenAI格式的错误响应
+type openAIErrorBody struct {
+	Error openAIError `json:"error"`
+}
+
+// openAIError OpenAI格式的错误信息
+type openAIError struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Param   *string `json:"param"`
+	Code    string  `json:"code"`
+}
+
+// openAIErrorTypes OpenAI使用的错误类型，OpenAI兼容的提供商返回这些类型时原样保留
+var openAIErrorTypes = map[string]bool{
+	"invalid_request_error": true,
+	"authentication_error":  true,
+	"permission_error":      true,
+	"rate_limit_error":      true,
+	"insufficient_quota":    true,
+	"server_error":          true,
+}
+
+// openAIErrorType 将上游错误类型映射为OpenAI的错误类型，其他提供商的类型按状态码决定
+func openAIErrorType(status int, errType string) string {
+	if openAIErrorTypes[errType] {
+		return errType
+	}
+
+	switch status {
+	case fiber.StatusUnauthorized:
+		return "authentication_error"
+	case fiber.StatusForbidden:
+		return "permission_error"
+	case fiber.StatusTooManyRequests:
+		return "rate_limit_error"
+	}
+	if status >= fiber.StatusBadRequest && status < fiber.StatusInternalServerError {
+		return "invalid_request_error"
+	}
+	return "server_error"
+}
+
+// openAIErrorCode 上游没有返回错误代码(只有状态码)时按状态码生成OpenAI的错误代码
+func openAIErrorCode(status int, code string) string {
+	if _, err := strconv.Atoi(code); code != "" && err != nil {
+		return code
+	}
+
+	switch status {
+	case fiber.StatusBadRequest:
+		return "invalid_request"
+	case fiber.StatusUnauthorized:
+		return "invalid_api_key"
+	case fiber.StatusForbidden:
+		return "permission_denied"
+	case fiber.StatusNotFound:
+		return "model_not_found"
+	case fiber.StatusTooManyRequests:
+		return "rate_limit_exceeded"
+	}
+	return "upstream_error"
+}
+
+// openAIChatCompletion OpenAI格式的聊天补全响应
+type openAIChatCompletion struct {
+	ID      string         `json:"id"`
+	Object  string         `json:"object"`
+	Created int64          `json:"created"`
+	Model   string         `json:"model"`
+	Choices []openAIChoice `json:"choices"`
+	Usage   types.Usage    `json:"usage"`
+}
+
+// openAIChoice OpenAI格式的候选回复
+type openAIChoice struct {
+	Index        int           `json:"index"`
+	Message      openAIMessage `json:"message"`
+	Logprobs     *struct{}     `json:"logprobs"`
+	FinishReason string        `json:"finish_reason"`
+}
+
+// openAIMessage OpenAI格式的回复消息，只有工具调用时content为null
+type openAIMessage struct {
+	Role      string           `json:"role"`
+	Content   *string          `json:"content"`
+	ToolCalls []types.ToolCall `json:"tool_calls,omitempty"`
+}
+
+// openAIChunk OpenAI格式的流式分片
+type openAIChunk struct {
+	ID      string              `json:"id"`
+	Object  string              `json:"object"`
+	Created int64               `json:"created"`
+	Model   string              `json:"model"`
+	Choices []openAIChunkChoice `json:"choices"`
+}
+
+// openAIChunkUsage 请求了stream_options.include_usage时的流式分片，usage字段始终输出
+type openAIChunkUsage struct {
+	ID      string              `json:"id"`
+	Object  string              `json:"object"`
+	Created int64               `json:"created"`
+	Model   string              `json:"model"`
+	Choices []openAIChunkChoice `json:"choices"`
+	Usage   *types.Usage        `json:"usage"`
+}
+
+// openAIChunkChoice OpenAI格式流式分片中的候选回复
+type openAIChunkChoice struct {
+	Index        int         `json:"index"`
+	Delta        openAIDelta `json:"delta"`
+	Logprobs     *struct{}   `json:"logprobs"`
+	FinishReason *string     `json:"finish_reason"`
+}
+
+// openAIDelta OpenAI格式的增量内容，推理过程使用reasoning_content字段
+type openAIDelta struct {
+	Role             string                `json:"role,omitempty"`
+	Content          string                `json:"content,omitempty"`
+	ReasoningContent string                `json:"reasoning_content,omitempty"`
+	ToolCalls        []types.ToolCallDelta `json:"tool_calls,omitempty"`
+}
+
+// openAIResponder OpenAI Chat Completions格式
+type openAIResponder struct{}
+
+// sendResponse 输出OpenAI格式的响应，上游错误的4xx状态码原样透传，错误类型和代码映射为OpenAI的格式
+func (r openAIResponder) sendResponse(c *fiber.Ctx, resp *types.UnifiedResponse) error {
+	if resp.Error != nil {
+		status := upstreamStatus(resp.Error.Status)
+		return r.sendError(c, status, openAIErrorCode(status, resp.Error.Code), resp.Error.Message, openAIErrorType(status, resp.Error.Type))
+	}
+
+	completion := openAIChatCompletion{
+		ID:      resp.ID,
+		Object:  "chat.completion",
+		Created: resp.Created,
+		Model:   resp.Model,
+		Choices: make([]openAIChoice, len(resp.Choices)),
+		Usage:   resp.Usage,
+	}
+	for i, choice := range resp.Choices {
+		message := openAIMessage{Role: choice.Message.Role, ToolCalls: choice.Message.ToolCalls}
+		if choice.Message.Content != "" || len(choice.Message.ToolCalls) == 0 {
+			content := choice.Message.Content
+			message.Content = &content
+		}
+
+		completion.Choices[i] = openAIChoice{
+			Index:        choice.Index,
+			Message:      message,
+			FinishReason: choice.FinishReason,
+		}
+	}
+
+	return c.JSON(completion)
+}
+
+// sendError 输出OpenAI格式的错误
+func (openAIResponder) sendError(c *fiber.Ctx, status int, code, message, errType string) error {
+	return c.Status(status).JSON(openAIErrorBody{
+		Error: openAIError{Message: message, Type: errType, Code: code},
+	})
+}
+
+// newStreamEncoder 创建OpenAI格式的分片编码器
+func (openAIResponder) newStreamEncoder(req *types.UnifiedRequest) streamEncoder {
+	return &openAIStreamEncoder{includeUsage: req.Parameters.IncludeUsage}
+}
+
+// openAIStreamEncoder OpenAI格式的分片编码器
+// 请求了stream_options.include_usage时，每个分片带有 "usage": null，token使用统计在最后单独的分片中返回
+type openAIStreamEncoder struct {
+	includeUsage bool
+	progress     providers.StreamProgress // 各候选结果是否已收到完成原因
+	usageSent    bool                     // 已发送token使用统计
+}
+
+// encode 写出一个分片
+func (e *openAIStreamEncoder) encode(w *bufio.Writer, resp *types.StreamResponse) (bool, error) {
+	// 上游返回错误后停止转发
+	if resp.Error != nil {
+		status := upstreamStatus(resp.Error.Status)
+		return true, writeSSE(w, openAIErrorBody{
+			Error: openAIError{
+				Message: resp.Error.Message,
+				Type:    openAIErrorType(status, resp.Error.Type),
+				Code:    openAIErrorCode(status, resp.Error.Code),
+			},
+		})
+	}
+
+	if len(resp.Choices) > 0 {
+		choices := make([]openAIChunkChoice, len(resp.Choices))
+		for i, choice := range resp.Choices {
+			choices[i] = openAIChunkChoice{
+				Index: choice.Index,
+				Delta: openAIDelta{
+					Role:             choice.Delta.Role,
+					Content:          choice.Delta.Content,
+					ReasoningContent: choice.Delta.Reasoning,
+					ToolCalls:        choice.Delta.ToolCalls,
+				},
+			}
+			if choice.FinishReason != "" {
+				finishReason := choice.FinishReason
+				choices[i].FinishReason = &finishReason
+			}
+		}
+
+		if err := writeSSE(w, e.chunk(resp, choices, nil)); err != nil {
+			return true, err
+		}
+		e.progress.Observe(resp)
+	}
+
+	if resp.Usage != nil && e.includeUsage && !e.usageSent {
+		e.usageSent = true
+		if err := writeSSE(w, e.chunk(resp, []openAIChunkChoice{}, resp.Usage)); err != nil {
+			return true, err
+		}
+	}
+
+	// 需要返回token使用统计时，等到收到统计或上游结束再停止
+	return e.progress.Complete() && (!e.includeUsage || e.usageSent), nil
+}
+
+// chunk 生成分片，include_usage时usage字段始终输出
+func (e *openAIStreamEncoder) chunk(resp *types.StreamResponse, choices []openAIChunkChoice, usage *types.Usage) interface{} {
+	if e.includeUsage {
+		return openAIChunkUsage{
+			ID:      resp.ID,
+			Object:  "chat.completion.chunk",
+			Created: resp.Created,
+			Model:   resp.Model,
+			Choices: choices,
+			Usage:   usage,
+		}
+	}
+
+	return openAIChunk{
+		ID:      resp.ID,
+		Object:  "chat.completion.chunk",
+		Created: resp.Created,
+		Model:   resp.Model,
+		Choices: choices,
+	}
+}
+
+// finish 发送完成事件
+func (e *openAIStreamEncoder) finish(w *bufio.Writer) error {
+	_, err := w.WriteString("data: [DONE]\n\n")
+	return err
+}
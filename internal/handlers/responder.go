@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/heyanxiao/llm-bridge/internal/providers"
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// chatResponder 按请求的接入协议输出响应和错误
+type chatResponder interface {
+	// sendResponse 输出非流式响应，响应中包含上游错误时按upstreamStatus决定状态码
+	sendResponse(c *fiber.Ctx, resp *types.UnifiedResponse) error
+
+	// sendError 输出错误
+	sendError(c *fiber.Ctx, status int, code, message, errType string) error
+
+	// newStreamEncoder 为一次流式请求创建分片编码器
+	newStreamEncoder(req *types.UnifiedRequest) streamEncoder
+}
+
+// streamEncoder 将统一格式的流式分片编码为SSE事件
+type streamEncoder interface {
+	// encode 写出一个分片，返回true表示应停止转发
+	encode(w *bufio.Writer, resp *types.StreamResponse) (bool, error)
+
+	// finish 流结束时写出收尾事件
+	finish(w *bufio.Writer) error
+}
+
+// newResponder 根据请求格式选择响应输出方式
+func newResponder(req *types.UnifiedRequest) chatResponder {
+	if req.Format == types.FormatOpenAI {
+		return openAIResponder{}
+	}
+	return unifiedResponder{}
+}
+
+// upstreamStatus 上游返回的4xx状态码原样透传给客户端，其余上游错误返回502
+func upstreamStatus(status int) int {
+	if status >= fiber.StatusBadRequest && status < fiber.StatusInternalServerError {
+		return status
+	}
+	return fiber.StatusBadGateway
+}
+
+// writeSSE 写出一个SSE data事件
+func writeSSE(w *bufio.Writer, data interface{}) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", jsonData)
+	return err
+}
+
+// argumentsObject 将JSON字符串形式的函数参数转换为对象，参数无效时为空对象
+// Anthropic和Gemini格式的函数参数是对象而不是字符串
+func argumentsObject(arguments string) json.RawMessage {
+	if !json.Valid([]byte(arguments)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(arguments)
+}
+
+// unifiedResponder 网关原有格式，响应中带有provider字段
+type unifiedResponder struct{}
+
+// sendResponse 输出统一格式的响应
+func (unifiedResponder) sendResponse(c *fiber.Ctx, resp *types.UnifiedResponse) error {
+	if resp.Error != nil {
+		return c.Status(upstreamStatus(resp.Error.Status)).JSON(resp)
+	}
+	return c.JSON(resp)
+}
+
+// sendError 错误格式与OpenAI一致
+func (unifiedResponder) sendError(c *fiber.Ctx, status int, code, message, errType string) error {
+	return openAIResponder{}.sendError(c, status, code, message, errType)
+}
+
+// newStreamEncoder 创建统一格式的分片编码器
+func (unifiedResponder) newStreamEncoder(req *types.UnifiedRequest) streamEncoder {
+	return &unifiedStreamEncoder{}
+}
+
+// unifiedStreamEncoder 统一格式的分片编码器，分片原样输出
+type unifiedStreamEncoder struct {
+	progress providers.StreamProgress // n>1时等所有候选结果都完成再停止
+}
+
+// encode 写出一个分片，上游返回错误或生成完成后停止
+func (e *unifiedStreamEncoder) encode(w *bufio.Writer, resp *types.StreamResponse) (bool, error) {
+	if err := writeSSE(w, resp); err != nil {
+		return true, err
+	}
+
+	// 上游返回错误后停止转发
+	if resp.Error != nil {
+		return true, nil
+	}
+
+	// 检查是否完成
+	e.progress.Observe(resp)
+	return e.progress.Complete(), nil
+}
+
+// finish 发送完成事件
+func (e *unifiedStreamEncoder) finish(w *bufio.Writer) error {
+	_, err := w.WriteString("data: [DONE]\n\n")
+	return err
+}
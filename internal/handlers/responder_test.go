@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// TestOpenAIUpstreamError 上游的4xx状态码原样透传，错误类型和代码映射为OpenAI的格式，其余错误返回502
+func TestOpenAIUpstreamError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        types.Error
+		wantStatus int
+		wantType   string
+		wantCode   string
+	}{
+		{
+			name:       "Claude限流",
+			err:        types.Error{Code: "rate_limit_error", Message: "rate limited", Type: "claude_error", Status: 429},
+			wantStatus: 429,
+			wantType:   "rate_limit_error",
+			wantCode:   "rate_limit_error",
+		},
+		{
+			name:       "只有状态码的鉴权失败",
+			err:        types.Error{Code: "401", Message: "unauthorized", Type: "gemini_error", Status: 401},
+			wantStatus: 401,
+			wantType:   "authentication_error",
+			wantCode:   "invalid_api_key",
+		},
+		{
+			name:       "OpenAI兼容提供商的错误类型原样保留",
+			err:        types.Error{Code: "context_length_exceeded", Message: "too long", Type: "invalid_request_error", Status: 400},
+			wantStatus: 400,
+			wantType:   "invalid_request_error",
+			wantCode:   "context_length_exceeded",
+		},
+		{
+			name:       "上游服务端错误",
+			err:        types.Error{Code: "503", Message: "unavailable", Type: "qwen_error", Status: 503},
+			wantStatus: fiber.StatusBadGateway,
+			wantType:   "server_error",
+			wantCode:   "upstream_error",
+		},
+		{
+			name:       "无法解析的响应",
+			err:        types.Error{Code: "invalid_response", Message: "bad json", Type: "ollama_error"},
+			wantStatus: fiber.StatusBadGateway,
+			wantType:   "server_error",
+			wantCode:   "invalid_response",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				upstreamErr := tt.err
+				return openAIResponder{}.sendResponse(c, &types.UnifiedResponse{Error: &upstreamErr})
+			})
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			var body openAIErrorBody
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus || body.Error.Type != tt.wantType || body.Error.Code != tt.wantCode {
+				t.Errorf("状态码 %d, type %q, code %q; 期望 %d, %q, %q",
+					resp.StatusCode, body.Error.Type, body.Error.Code, tt.wantStatus, tt.wantType, tt.wantCode)
+			}
+		})
+	}
+}
+
+// TestStreamEncoderWaitsForAllChoices n>1时所有候选结果都收到完成原因后才停止转发
+func TestStreamEncoderWaitsForAllChoices(t *testing.T) {
+	finish := func(index int, reason string) *types.StreamResponse {
+		chunk := streamChunk(index, "")
+		chunk.Choices[0].FinishReason = reason
+		chunk.Usage = &types.Usage{TotalTokens: 1}
+		return chunk
+	}
+	chunks := []*types.StreamResponse{streamChunk(0, "甲"), streamChunk(1, "乙"), finish(0, "stop"), finish(1, "length")}
+
+	encoders := map[string]streamEncoder{
+		"unified": &unifiedStreamEncoder{},
+		"openai":  &openAIStreamEncoder{},
+		"gemini":  &geminiStreamEncoder{},
+	}
+	for name, encoder := range encoders {
+		t.Run(name, func(t *testing.T) {
+			w := bufio.NewWriter(io.Discard)
+			for i, chunk := range chunks {
+				stop, err := encoder.encode(w, chunk)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if last := i == len(chunks)-1; stop != last {
+					t.Errorf("第%d个分片后 stop = %v, 期望 %v", i, stop, last)
+				}
+			}
+		})
+	}
+}
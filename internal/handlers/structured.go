@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/heyanxiao/llm-bridge/internal/providers"
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// 输出不符合结构化输出要求时的错误代码
+const structuredOutputErrorCode = "structured_output_invalid"
+
+// checkStructuredOutput 校验非流式响应是否符合response_format的要求，通过时去掉包裹JSON的Markdown代码块
+// 不符合时按配置带上错误原因向同一个提供商重试一次，仍不符合时返回structured_output_invalid错误
+func (h *ChatHandler) checkStructuredOutput(ctx context.Context, provider providers.ProviderAdapter, req *types.UnifiedRequest, resp *types.UnifiedResponse) (*types.UnifiedResponse, *chatFailure) {
+	format := req.Parameters.ResponseFormat
+	if !format.IsJSON() || resp.Error != nil {
+		return resp, nil
+	}
+
+	invalid, err := normalizeStructuredOutput(format, resp)
+	if err == nil {
+		return resp, nil
+	}
+	if !h.structuredOutput.RetryEnabled() {
+		return nil, structuredOutputFailure(err)
+	}
+
+	// 把不符合要求的输出和原因告诉模型，要求重新输出
+	retryReq := *req
+	retryReq.Messages = make([]types.Message, 0, len(req.Messages)+2)
+	retryReq.Messages = append(retryReq.Messages, req.Messages...)
+	retryReq.Messages = append(retryReq.Messages,
+		types.Message{Role: "assistant", Content: invalid},
+		types.Message{Role: "user", Content: err.Error() + "\n" + providers.StructuredOutputInstruction(format)},
+	)
+
+	httpResp, failure := h.callProvider(ctx, provider, &retryReq, true)
+	if failure != nil {
+		return nil, structuredOutputFailure(err)
+	}
+	retryResp, parseErr := provider.ParseResponse(httpResp)
+	if parseErr != nil || retryResp.Error != nil {
+		return nil, structuredOutputFailure(err)
+	}
+
+	// token使用统计包含两次调用
+	retryResp.Usage.PromptTokens += resp.Usage.PromptTokens
+	retryResp.Usage.CompletionTokens += resp.Usage.CompletionTokens
+	retryResp.Usage.TotalTokens += resp.Usage.TotalTokens
+
+	if _, err := normalizeStructuredOutput(format, retryResp); err != nil {
+		return nil, structuredOutputFailure(err)
+	}
+	return retryResp, nil
+}
+
+// normalizeStructuredOutput 校验每个候选结果的内容，返回第一个不符合要求的内容和原因
+// 只有工具调用的候选结果不做校验
+func normalizeStructuredOutput(format *types.ResponseFormat, resp *types.UnifiedResponse) (string, error) {
+	for i, choice := range resp.Choices {
+		if len(choice.Message.ToolCalls) > 0 {
+			continue
+		}
+
+		content, err := providers.ValidateStructuredOutput(format, choice.Message.Content)
+		if err != nil {
+			return choice.Message.Content, err
+		}
+		resp.Choices[i].Message.Content = content
+	}
+	return "", nil
+}
+
+// structuredOutputFailure 生成输出不符合结构化输出要求的错误
+func structuredOutputFailure(err error) *chatFailure {
+	return &chatFailure{
+		status:  fiber.StatusBadGateway,
+		code:    structuredOutputErrorCode,
+		message: err.Error(),
+		errType: "upstream_error",
+	}
+}
+
+// structuredStreamEncoder 在流结束时按候选结果分别校验拼接后的输出，不符合要求时在收尾事件之前输出错误
+// 流式内容已经发送给客户端，因此不会重试，也不会去掉Markdown代码块
+type structuredStreamEncoder struct {
+	streamEncoder
+	format   *types.ResponseFormat
+	contents map[int]*strings.Builder // 每个候选结果的输出，键为候选结果的index
+	toolCall map[int]bool             // 调用了工具的候选结果，不做校验
+	skip     bool                     // 上游返回错误时不校验
+}
+
+// encode 记录输出内容后交给原编码器写出
+func (e *structuredStreamEncoder) encode(w *bufio.Writer, resp *types.StreamResponse) (bool, error) {
+	if resp.Error != nil {
+		e.skip = true
+	}
+	if e.contents == nil {
+		e.contents = make(map[int]*strings.Builder)
+		e.toolCall = make(map[int]bool)
+	}
+	for _, choice := range resp.Choices {
+		content, ok := e.contents[choice.Index]
+		if !ok {
+			content = &strings.Builder{}
+			e.contents[choice.Index] = content
+		}
+		content.WriteString(choice.Delta.Content)
+		if len(choice.Delta.ToolCalls) > 0 {
+			e.toolCall[choice.Index] = true
+		}
+	}
+
+	return e.streamEncoder.encode(w, resp)
+}
+
+// finish 按index顺序校验每个候选结果的输出，第一个不符合要求的以流中错误的形式告知客户端
+func (e *structuredStreamEncoder) finish(w *bufio.Writer) error {
+	if err := e.validate(); err != nil {
+		if _, writeErr := e.streamEncoder.encode(w, &types.StreamResponse{
+			Error: &types.Error{Code: structuredOutputErrorCode, Message: err.Error(), Type: "upstream_error"},
+		}); writeErr != nil {
+			return writeErr
+		}
+	}
+
+	return e.streamEncoder.finish(w)
+}
+
+// validate 校验每个候选结果的输出，有多个候选结果时错误信息中带有index
+func (e *structuredStreamEncoder) validate() error {
+	if e.skip {
+		return nil
+	}
+
+	if len(e.contents) == 0 {
+		// 没有收到任何内容时校验空输出
+		_, err := providers.ValidateStructuredOutput(e.format, "")
+		return err
+	}
+
+	indexes := make([]int, 0, len(e.contents))
+	for index := range e.contents {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	for _, index := range indexes {
+		if e.toolCall[index] {
+			continue
+		}
+		if _, err := providers.ValidateStructuredOutput(e.format, e.contents[index].String()); err != nil {
+			if len(indexes) > 1 {
+				return fmt.Errorf("候选结果 %d: %w", index, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
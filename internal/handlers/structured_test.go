@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/heyanxiao/llm-bridge/internal/providers"
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// answerFormat 要求输出 {"ok": <boolean>} 的结构化输出格式
+var answerFormat = &types.ResponseFormat{
+	Type: types.ResponseFormatJSONSchema,
+	JSONSchema: &types.JSONSchemaFormat{
+		Name:   "answer",
+		Schema: json.RawMessage(`{"type":"object","properties":{"ok":{"type":"boolean"}},"required":["ok"]}`),
+	},
+}
+
+// streamChunk 生成只有一个候选结果增量的分片
+func streamChunk(index int, content string) *types.StreamResponse {
+	return &types.StreamResponse{
+		ID:      "chunk",
+		Choices: []types.StreamChoice{{Index: index, Delta: types.StreamDelta{Content: content}}},
+	}
+}
+
+// TestStructuredStreamEncoderPerChoice n>1时每个候选结果分别拼接和校验
+func TestStructuredStreamEncoderPerChoice(t *testing.T) {
+	tests := []struct {
+		name    string
+		chunks  []*types.StreamResponse
+		wantErr string
+	}{
+		{
+			name: "交错输出的候选结果都符合要求",
+			chunks: []*types.StreamResponse{
+				streamChunk(0, `{"ok":`), streamChunk(1, `{"ok":`),
+				streamChunk(1, `false}`), streamChunk(0, `true}`),
+			},
+		},
+		{
+			name: "第二个候选结果不符合要求",
+			chunks: []*types.StreamResponse{
+				streamChunk(0, `{"ok":true}`), streamChunk(1, `{"ok":"yes"}`),
+			},
+			wantErr: "候选结果 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := bufio.NewWriter(&buf)
+			encoder := &structuredStreamEncoder{streamEncoder: &openAIStreamEncoder{}, format: answerFormat}
+			for _, chunk := range tt.chunks {
+				if _, err := encoder.encode(w, chunk); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := encoder.finish(w); err != nil {
+				t.Fatal(err)
+			}
+			w.Flush()
+
+			output := buf.String()
+			hasErr := strings.Contains(output, structuredOutputErrorCode)
+			if hasErr != (tt.wantErr != "") || !strings.Contains(output, tt.wantErr) {
+				t.Errorf("输出不符合预期:\n%s", output)
+			}
+		})
+	}
+}
+
+// TestStructuredStreamFromProvider 上游n>1的流经提供商转换后，第二个候选结果不符合要求时返回错误
+func TestStructuredStreamFromProvider(t *testing.T) {
+	body := `data: {"id":"1","choices":[{"index":0,"delta":{"role":"assistant","content":"{\"ok\":"}},{"index":1,"delta":{"role":"assistant","content":"{\"ok\":"}}]}` + "\n\n" +
+		`data: {"id":"1","choices":[{"index":0,"delta":{"content":"true}"},"finish_reason":"stop"},{"index":1,"delta":{"content":"\"yes\"}"},"finish_reason":"stop"}]}` + "\n\n" +
+		"data: [DONE]\n\n"
+
+	provider := providers.NewOpenAIProvider(&providers.OpenAIConfig{})
+	streamChan, err := provider.ParseStreamResponse(&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	encoder := &structuredStreamEncoder{streamEncoder: &openAIStreamEncoder{}, format: answerFormat}
+	for chunk := range streamChan {
+		if _, err := encoder.encode(w, chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := encoder.finish(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Flush()
+
+	output := buf.String()
+	if !strings.Contains(output, structuredOutputErrorCode) || !strings.Contains(output, "候选结果 1") {
+		t.Errorf("应返回第二个候选结果的校验错误:\n%s", output)
+	}
+}
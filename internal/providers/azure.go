@@ -0,0 +1,172 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// AzureOpenAIProvider Azure OpenAI适配器实现
+// 请求和响应格式与OpenAI一致，只有URL和鉴权方式不同，因此复用OpenAIProvider的转换和解析逻辑
+type AzureOpenAIProvider struct {
+	OpenAIProvider
+	Endpoint          string            // 资源端点，如 https://xxx.openai.azure.com
+	APIVersion        string            // api-version查询参数
+	DefaultDeployment string            // 默认部署名称
+	Deployments       map[string]string // 逻辑模型名 -> 部署名称
+}
+
+// NewAzureOpenAIProvider 创建Azure OpenAI提供商实例
+func NewAzureOpenAIProvider(config *AzureConfig) *AzureOpenAIProvider {
+	endpoint := strings.TrimSuffix(config.Endpoint, "/")
+
+	apiVersion := config.APIVersion
+	if apiVersion == "" {
+		apiVersion = "2024-06-01"
+	}
+
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = 30
+	}
+
+	retries := config.Retries
+	if retries == 0 {
+		retries = 3
+	}
+
+	deployments := make(map[string]string, len(config.Deployments)+1)
+	for model, deployment := range config.Deployments {
+		// 配置文件中引用的环境变量未设置时部署名称为空，忽略该模型
+		if deployment == "" {
+			continue
+		}
+		deployments[model] = deployment
+	}
+	// 默认部署也可以直接以部署名称作为模型名使用
+	if config.Deployment != "" {
+		if _, exists := deployments[config.Deployment]; !exists {
+			deployments[config.Deployment] = config.Deployment
+		}
+	}
+
+	provider := &AzureOpenAIProvider{
+		OpenAIProvider: OpenAIProvider{
+			BaseProvider: BaseProvider{
+				Name:    "azure",
+				APIKey:  config.APIKey,
+				BaseURL: endpoint,
+				Headers: map[string]string{
+					"Content-Type": "application/json",
+					"api-key":      config.APIKey,
+				},
+				Timeout: timeout,
+				Retries: retries,
+			},
+		},
+		Endpoint:          endpoint,
+		APIVersion:        apiVersion,
+		DefaultDeployment: config.Deployment,
+		Deployments:       deployments,
+	}
+
+	// Azure的可用模型由部署决定，注册到模型配置中
+	models := make([]string, 0, len(deployments))
+	for model := range deployments {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+
+	defaultModel := config.Deployment
+	if defaultModel == "" && len(models) > 0 {
+		defaultModel = models[0]
+	}
+	RegisterModels("azure", ModelConfig{
+		Models:       models,
+		DefaultModel: defaultModel,
+	})
+
+	return provider
+}
+
+// ValidateRequest 验证请求参数
+func (p *AzureOpenAIProvider) ValidateRequest(req *types.UnifiedRequest) error {
+	if err := p.OpenAIProvider.ValidateRequest(req); err != nil {
+		return err
+	}
+
+	if p.resolveDeployment(req.Model) == "" {
+		return fmt.Errorf("模型 %s 没有对应的Azure部署", req.Model)
+	}
+
+	return nil
+}
+
+// resolveDeployment 将逻辑模型名解析为部署名称，没有对应的部署时返回空
+// 只有未指定模型或模型为提供商名称时使用默认部署
+func (p *AzureOpenAIProvider) resolveDeployment(model string) string {
+	if model == "" || model == p.Name {
+		return p.DefaultDeployment
+	}
+
+	if deployment, exists := p.Deployments[model]; exists {
+		return deployment
+	}
+
+	// 允许直接使用部署名称
+	for _, deployment := range p.Deployments {
+		if deployment == model {
+			return deployment
+		}
+	}
+
+	return ""
+}
+
+// CallAPI 调用Azure OpenAI API
+func (p *AzureOpenAIProvider) CallAPI(ctx context.Context, data []byte) (*http.Response, error) {
+	// 从请求数据中解析模型名称以确定部署
+	var reqData map[string]interface{}
+	if err := json.Unmarshal(data, &reqData); err != nil {
+		return nil, fmt.Errorf("解析请求数据失败: %w", err)
+	}
+
+	model, _ := reqData["model"].(string)
+	deployment := p.resolveDeployment(model)
+	if deployment == "" {
+		return nil, fmt.Errorf("模型 %s 没有对应的Azure部署", model)
+	}
+
+	// Azure URL格式: {endpoint}/openai/deployments/{deployment}/chat/completions?api-version={version}
+	apiURL := fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+		p.Endpoint, url.PathEscape(deployment), url.QueryEscape(p.APIVersion))
+
+	// 创建HTTP请求
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
+	}
+
+	// 设置请求头
+	for key, value := range p.Headers {
+		req.Header.Set(key, value)
+	}
+
+	// 创建HTTP客户端
+	client := p.httpClient(isStreamRequest(data))
+
+	// 发送请求
+	resp, err := p.doWithRetry(client, req)
+	if err != nil {
+		return nil, fmt.Errorf("调用Azure OpenAI API失败: %w", err)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,278 @@
+package providers
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 负载均衡策略
+const (
+	StrategyRoundRobin       = "round_robin"
+	StrategyWeighted         = "weighted"
+	StrategyLeastConnections = "least_connections"
+	StrategyLeastLatency     = "least_latency"
+)
+
+// EWMA延迟的平滑系数，越大越偏向最近的请求
+const latencyEWMAAlpha = 0.3
+
+// 最低延迟策略默认的探索比例
+const defaultExploreRate = 0.05
+
+// NewLoadBalancer 根据配置创建负载均衡器
+func NewLoadBalancer(config LoadBalancerConfig) (LoadBalancer, error) {
+	switch strings.ToLower(config.Strategy) {
+	case "", StrategyRoundRobin:
+		return NewRoundRobinBalancer(config.CircuitBreaker), nil
+	case StrategyWeighted:
+		return NewWeightedBalancer(config.CircuitBreaker, config.Weights), nil
+	case StrategyLeastConnections:
+		return NewLeastConnectionsBalancer(config.CircuitBreaker), nil
+	case StrategyLeastLatency:
+		exploreRate := defaultExploreRate
+		if config.ExploreRate != nil {
+			exploreRate = *config.ExploreRate
+			if exploreRate < 0 || exploreRate >= 1 {
+				return nil, fmt.Errorf("explore_rate必须在[0, 1)之间: %v", exploreRate)
+			}
+		}
+		return NewLeastLatencyBalancer(config.CircuitBreaker, exploreRate), nil
+	default:
+		return nil, fmt.Errorf("不支持的负载均衡策略: %s", config.Strategy)
+	}
+}
+
+// providerLoad 提供商的负载统计
+type providerLoad struct {
+	inFlight int64 // 在途请求数，原子操作
+
+	mu      sync.Mutex
+	latency float64 // 响应延迟的EWMA(毫秒)
+	samples int64
+}
+
+// LoadSnapshot 提供商负载快照，用于管理接口展示
+type LoadSnapshot struct {
+	InFlight  int64   `json:"inFlight"`
+	LatencyMs float64 `json:"latencyMs"` // 响应延迟的EWMA
+	Samples   int64   `json:"samples"`
+}
+
+// load 获取或创建提供商的负载统计
+func (t *HealthTracker) load(providerName string) *providerLoad {
+	t.mu.RLock()
+	load, exists := t.loads[providerName]
+	t.mu.RUnlock()
+	if exists {
+		return load
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if load, exists = t.loads[providerName]; !exists {
+		load = &providerLoad{}
+		t.loads[providerName] = load
+	}
+	return load
+}
+
+// Acquire 请求发出时增加在途请求数
+func (t *HealthTracker) Acquire(providerName string) {
+	atomic.AddInt64(&t.load(providerName).inFlight, 1)
+}
+
+// Release 请求结束时减少在途请求数
+func (t *HealthTracker) Release(providerName string) {
+	atomic.AddInt64(&t.load(providerName).inFlight, -1)
+}
+
+// RecordLatency 记录一次成功请求的响应延迟
+func (t *HealthTracker) RecordLatency(providerName string, latency time.Duration) {
+	load := t.load(providerName)
+	ms := float64(latency) / float64(time.Millisecond)
+
+	load.mu.Lock()
+	defer load.mu.Unlock()
+
+	if load.samples == 0 {
+		load.latency = ms
+	} else {
+		load.latency = latencyEWMAAlpha*ms + (1-latencyEWMAAlpha)*load.latency
+	}
+	load.samples++
+}
+
+// LoadSnapshot 获取提供商的负载快照
+func (t *HealthTracker) LoadSnapshot(providerName string) LoadSnapshot {
+	load := t.load(providerName)
+
+	load.mu.Lock()
+	defer load.mu.Unlock()
+
+	return LoadSnapshot{
+		InFlight:  atomic.LoadInt64(&load.inFlight),
+		LatencyMs: load.latency,
+		Samples:   load.samples,
+	}
+}
+
+// availableProviders 过滤熔断器未打开的提供商
+func (t *HealthTracker) availableProviders(providers []ProviderAdapter) []ProviderAdapter {
+	available := make([]ProviderAdapter, 0, len(providers))
+	for _, provider := range providers {
+		if t.IsAvailable(provider.GetProviderName(), "") {
+			available = append(available, provider)
+		}
+	}
+	return available
+}
+
+// WeightedBalancer 平滑加权轮询负载均衡器
+// 未配置权重的提供商权重为1，权重为0的提供商不参与自动选择
+type WeightedBalancer struct {
+	*HealthTracker
+
+	mu      sync.Mutex
+	weights map[string]int
+	current map[string]int
+}
+
+// NewWeightedBalancer 创建加权轮询负载均衡器
+func NewWeightedBalancer(config BreakerConfig, weights map[string]int) *WeightedBalancer {
+	return &WeightedBalancer{
+		HealthTracker: NewHealthTracker(config),
+		weights:       weights,
+		current:       make(map[string]int),
+	}
+}
+
+// weight 获取提供商的权重
+func (wb *WeightedBalancer) weight(providerName string) int {
+	if weight, exists := wb.weights[providerName]; exists {
+		return weight
+	}
+	return 1
+}
+
+// SelectProvider 按权重选择提供商，每轮选中当前权重最大的提供商后减去总权重
+func (wb *WeightedBalancer) SelectProvider(providers []ProviderAdapter) ProviderAdapter {
+	available := wb.availableProviders(providers)
+	if len(available) == 0 {
+		return nil
+	}
+
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	var selected ProviderAdapter
+	total := 0
+	for _, provider := range available {
+		name := provider.GetProviderName()
+		weight := wb.weight(name)
+		if weight <= 0 {
+			continue
+		}
+
+		wb.current[name] += weight
+		total += weight
+		if selected == nil || wb.current[name] > wb.current[selected.GetProviderName()] {
+			selected = provider
+		}
+	}
+
+	if selected == nil {
+		return nil
+	}
+
+	wb.current[selected.GetProviderName()] -= total
+	return selected
+}
+
+// LeastConnectionsBalancer 最少在途请求负载均衡器
+type LeastConnectionsBalancer struct {
+	*HealthTracker
+	counter uint64 // 在途请求数相同时轮询
+}
+
+// NewLeastConnectionsBalancer 创建最少在途请求负载均衡器
+func NewLeastConnectionsBalancer(config BreakerConfig) *LeastConnectionsBalancer {
+	return &LeastConnectionsBalancer{
+		HealthTracker: NewHealthTracker(config),
+	}
+}
+
+// SelectProvider 选择在途请求数最少的提供商
+func (lb *LeastConnectionsBalancer) SelectProvider(providers []ProviderAdapter) ProviderAdapter {
+	available := lb.availableProviders(providers)
+	if len(available) == 0 {
+		return nil
+	}
+
+	var candidates []ProviderAdapter
+	least := int64(-1)
+	for _, provider := range available {
+		inFlight := atomic.LoadInt64(&lb.load(provider.GetProviderName()).inFlight)
+		switch {
+		case least < 0 || inFlight < least:
+			least = inFlight
+			candidates = []ProviderAdapter{provider}
+		case inFlight == least:
+			candidates = append(candidates, provider)
+		}
+	}
+
+	index := atomic.AddUint64(&lb.counter, 1) - 1
+	return candidates[index%uint64(len(candidates))]
+}
+
+// LeastLatencyBalancer 最低延迟负载均衡器
+// 按响应延迟的EWMA选择最快的提供商，没有延迟数据的提供商优先尝试
+// 并按exploreRate随机选择其他提供商，避免慢提供商恢复后因没有流量而无法更新延迟
+type LeastLatencyBalancer struct {
+	*HealthTracker
+	exploreRate float64
+}
+
+// NewLeastLatencyBalancer 创建最低延迟负载均衡器，exploreRate为0时不探索
+func NewLeastLatencyBalancer(config BreakerConfig, exploreRate float64) *LeastLatencyBalancer {
+	if exploreRate < 0 || exploreRate >= 1 {
+		exploreRate = defaultExploreRate
+	}
+
+	return &LeastLatencyBalancer{
+		HealthTracker: NewHealthTracker(config),
+		exploreRate:   exploreRate,
+	}
+}
+
+// SelectProvider 选择延迟最低的提供商
+func (lb *LeastLatencyBalancer) SelectProvider(providers []ProviderAdapter) ProviderAdapter {
+	available := lb.availableProviders(providers)
+	if len(available) == 0 {
+		return nil
+	}
+
+	if len(available) > 1 && rand.Float64() < lb.exploreRate {
+		return available[rand.Intn(len(available))]
+	}
+
+	var selected ProviderAdapter
+	best := 0.0
+	for _, provider := range available {
+		snapshot := lb.LoadSnapshot(provider.GetProviderName())
+		if snapshot.Samples == 0 {
+			return provider
+		}
+
+		if selected == nil || snapshot.LatencyMs < best {
+			selected = provider
+			best = snapshot.LatencyMs
+		}
+	}
+
+	return selected
+}
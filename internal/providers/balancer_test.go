@@ -0,0 +1,312 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// stubProvider 只有名称的提供商，用于负载均衡测试
+type stubProvider struct {
+	name string
+}
+
+func (p *stubProvider) Transform(req *types.UnifiedRequest) ([]byte, error) { return nil, nil }
+
+func (p *stubProvider) CallAPI(ctx context.Context, data []byte) (*http.Response, error) {
+	return nil, nil
+}
+
+func (p *stubProvider) ParseResponse(resp *http.Response) (*types.UnifiedResponse, error) {
+	return nil, nil
+}
+
+func (p *stubProvider) ParseStreamResponse(resp *http.Response) (<-chan *types.StreamResponse, error) {
+	return nil, nil
+}
+
+func (p *stubProvider) GetProviderName() string { return p.name }
+
+func (p *stubProvider) ValidateRequest(req *types.UnifiedRequest) error { return nil }
+
+// stubProviders 创建指定名称的提供商列表
+func stubProviders(names ...string) []ProviderAdapter {
+	providers := make([]ProviderAdapter, len(names))
+	for i, name := range names {
+		providers[i] = &stubProvider{name: name}
+	}
+	return providers
+}
+
+// 所有负载均衡策略
+var allStrategies = []string{StrategyRoundRobin, StrategyWeighted, StrategyLeastConnections, StrategyLeastLatency}
+
+// TestRoundRobinBalancer 轮询选择，跳过熔断的提供商
+func TestRoundRobinBalancer(t *testing.T) {
+	lb := NewRoundRobinBalancer(BreakerConfig{})
+	providers := stubProviders("a", "b", "c")
+
+	var got []string
+	for i := 0; i < 6; i++ {
+		got = append(got, lb.SelectProvider(providers).GetProviderName())
+	}
+	if fmt.Sprint(got) != "[a b c a b c]" {
+		t.Errorf("轮询顺序 %v", got)
+	}
+
+	tripNow(lb.breaker(breakerKey("b", "")))
+	for i := 0; i < 4; i++ {
+		if name := lb.SelectProvider(providers).GetProviderName(); name == "b" {
+			t.Fatal("不应选择熔断的提供商")
+		}
+	}
+}
+
+// TestWeightedBalancer 平滑加权轮询按权重分配，权重为0的提供商不参与
+func TestWeightedBalancer(t *testing.T) {
+	lb := NewWeightedBalancer(BreakerConfig{}, map[string]int{"a": 3, "b": 1, "c": 0})
+	providers := stubProviders("a", "b", "c")
+
+	counts := make(map[string]int)
+	for i := 0; i < 8; i++ {
+		counts[lb.SelectProvider(providers).GetProviderName()]++
+	}
+	if counts["a"] != 6 || counts["b"] != 2 || counts["c"] != 0 {
+		t.Errorf("选择次数 %v", counts)
+	}
+}
+
+// TestLeastConnectionsBalancer 选择在途请求最少的提供商
+func TestLeastConnectionsBalancer(t *testing.T) {
+	lb := NewLeastConnectionsBalancer(BreakerConfig{})
+	providers := stubProviders("a", "b")
+
+	lb.Acquire("a")
+	if name := lb.SelectProvider(providers).GetProviderName(); name != "b" {
+		t.Errorf("应选择在途请求最少的b，实际 %s", name)
+	}
+	lb.Release("a")
+}
+
+// TestLeastLatencyBalancer 优先尝试没有延迟数据的提供商，之后选择延迟最低的
+func TestLeastLatencyBalancer(t *testing.T) {
+	lb := NewLeastLatencyBalancer(BreakerConfig{}, 0)
+	providers := stubProviders("a", "b")
+
+	lb.RecordLatency("a", 100*time.Millisecond)
+	if name := lb.SelectProvider(providers).GetProviderName(); name != "b" {
+		t.Errorf("应优先尝试没有延迟数据的b，实际 %s", name)
+	}
+
+	lb.RecordLatency("b", 300*time.Millisecond)
+	if name := lb.SelectProvider(providers).GetProviderName(); name != "a" {
+		t.Errorf("应选择延迟最低的a，实际 %s", name)
+	}
+}
+
+// TestLeastLatencyExploreRate explore_rate未配置时使用默认值，配置为0时不探索，超出范围时返回错误
+func TestLeastLatencyExploreRate(t *testing.T) {
+	zero, half, invalid := 0.0, 0.5, 1.5
+
+	tests := []struct {
+		name    string
+		rate    *float64
+		want    float64
+		wantErr bool
+	}{
+		{name: "未配置", want: defaultExploreRate},
+		{name: "配置为0", rate: &zero, want: 0},
+		{name: "配置为0.5", rate: &half, want: 0.5},
+		{name: "超出范围", rate: &invalid, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb, err := NewLoadBalancer(LoadBalancerConfig{Strategy: StrategyLeastLatency, ExploreRate: tt.rate})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("应返回错误")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := lb.(*LeastLatencyBalancer).exploreRate; got != tt.want {
+				t.Errorf("exploreRate = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestProviderFactoryConcurrent 并发注册和查询提供商
+func TestProviderFactoryConcurrent(t *testing.T) {
+	factory := NewProviderFactory()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				name := fmt.Sprintf("p%d-%d", i, j%10)
+				factory.RegisterProvider(name, &stubProvider{name: name})
+				if _, ok := factory.GetProvider(name); !ok {
+					t.Errorf("刚注册的提供商 %s 不存在", name)
+					return
+				}
+				factory.ListProviders()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := len(factory.ListProviders()); got != 80 {
+		t.Errorf("应有80个提供商，实际 %d", got)
+	}
+}
+
+// TestLoadBalancerConcurrent 每种策略下并发选择提供商、更新健康状态和注册新的提供商
+// 需要以 go test -race 运行才能发现数据竞争
+func TestLoadBalancerConcurrent(t *testing.T) {
+	for _, strategy := range allStrategies {
+		t.Run(strategy, func(t *testing.T) {
+			lb, err := NewLoadBalancer(LoadBalancerConfig{
+				Strategy:       strategy,
+				Weights:        map[string]int{"p0": 2},
+				CircuitBreaker: BreakerConfig{MinRequests: 20},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			factory := NewProviderFactory()
+			for _, provider := range stubProviders("p0", "p1", "p2") {
+				factory.RegisterProvider(provider.GetProviderName(), provider)
+			}
+
+			// 当前已注册的提供商
+			current := func() []ProviderAdapter {
+				var providers []ProviderAdapter
+				for _, name := range factory.ListProviders() {
+					provider, _ := factory.GetProvider(name)
+					providers = append(providers, provider)
+				}
+				return providers
+			}
+
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					for j := 0; j < 200; j++ {
+						providers := current()
+						selected := lb.SelectProvider(providers)
+						lb.RankSticky(fmt.Sprintf("session-%d", j), providers)
+						if selected == nil {
+							continue
+						}
+
+						name := selected.GetProviderName()
+						model := fmt.Sprintf("m%d", j%3)
+						if !lb.Allow(name, model) {
+							continue
+						}
+						lb.Acquire(name)
+						lb.RecordLatency(name, time.Duration(j%50)*time.Millisecond)
+						lb.RecordResult(name, model, (i+j)%4 != 0)
+						lb.Release(name)
+					}
+				}(i)
+			}
+
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 200; j++ {
+					lb.UpdateHealth(fmt.Sprintf("p%d", j%3), j%5 != 0)
+					lb.IsAvailable(fmt.Sprintf("p%d", j%3), "m0")
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				for j := 3; j < 20; j++ {
+					name := fmt.Sprintf("p%d", j)
+					factory.RegisterProvider(name, &stubProvider{name: name})
+					lb.BreakerSnapshot(name)
+					lb.ModelBreakerSnapshots(name)
+					lb.LoadSnapshot(name)
+				}
+			}()
+			wg.Wait()
+
+			for _, name := range factory.ListProviders() {
+				if inFlight := lb.LoadSnapshot(name).InFlight; inFlight != 0 {
+					t.Errorf("%s 的在途请求数应为0，实际 %d", name, inFlight)
+				}
+			}
+		})
+	}
+}
+
+// TestHealthTrackerConcurrent 并发申请放行、记录结果和读取熔断器状态
+func TestHealthTrackerConcurrent(t *testing.T) {
+	tracker := NewHealthTracker(BreakerConfig{MinRequests: 10, HalfOpenProbes: 2})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 300; j++ {
+				provider := fmt.Sprintf("p%d", j%2)
+				model := fmt.Sprintf("m%d", (i+j)%4)
+				if tracker.Allow(provider, model) {
+					tracker.RecordResult(provider, model, j%3 != 0)
+				}
+				tracker.IsAvailable(provider, model)
+				tracker.ModelBreakerSnapshots(provider)
+				tracker.BreakerSnapshot(provider)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// TestModelRegistryConcurrent 后台刷新模型列表时并发读取
+func TestModelRegistryConcurrent(t *testing.T) {
+	const provider = "concurrent-test"
+	defer func() {
+		modelsMu.Lock()
+		delete(SupportedModels, provider)
+		modelsMu.Unlock()
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 200; j++ {
+			RegisterModels(provider, ModelConfig{
+				Models:       []string{fmt.Sprintf("model-%d", j)},
+				DefaultModel: fmt.Sprintf("model-%d", j),
+			})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 200; j++ {
+			GetProviderModels(provider)
+			GetDefaultModel(provider)
+			IsModelSupported(provider, "model-1")
+			AllModelConfigs()
+		}
+	}()
+	wg.Wait()
+}
@@ -3,6 +3,10 @@ package providers
 import (
 	"context"
 	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/heyanxiao/llm-bridge/pkg/types"
 )
@@ -36,7 +40,7 @@ type BaseProvider struct {
 	BaseURL  string            // API基础URL
 	Headers  map[string]string // 默认请求头
 	Timeout  int               // 请求超时时间(秒)
-	Retries  int               // 重试次数
+	Retries  int               // 失败后的重试次数，不含首次请求，3表示最多发送4次
 }
 
 // ProviderConfig 提供商配置结构
@@ -48,6 +52,10 @@ type ProviderConfig struct {
 	DeepSeek *DeepSeekConfig `yaml:"deepseek"`
 	Qwen     *QwenConfig     `yaml:"qwen"`
 	Moonshot *MoonshotConfig `yaml:"moonshot"`
+	Ollama   *OllamaConfig   `yaml:"ollama"`
+
+	// OpenAI兼容提供商列表，无需编写代码即可接入
+	Compatible []CompatibleProviderConfig `yaml:"openai_compatible"`
 }
 
 // OpenAI配置
@@ -80,16 +88,18 @@ type GeminiConfig struct {
 
 // Azure OpenAI配置
 type AzureConfig struct {
-	APIKey      string `yaml:"api_key"`
-	Endpoint    string `yaml:"endpoint"`
-	Deployment  string `yaml:"deployment"`
-	APIVersion  string `yaml:"api_version"`
-	Timeout     int    `yaml:"timeout"`
-	Retries     int    `yaml:"retries"`
+	APIKey      string            `yaml:"api_key"`
+	Endpoint    string            `yaml:"endpoint"`
+	Deployment  string            `yaml:"deployment"`  // 默认部署名称
+	Deployments map[string]string `yaml:"deployments"` // 逻辑模型名 -> 部署名称
+	APIVersion  string            `yaml:"api_version"`
+	Timeout     int               `yaml:"timeout"`
+	Retries     int               `yaml:"retries"`
 }
 
-// ProviderFactory 提供商工厂
+// ProviderFactory 提供商工厂，可在多个请求goroutine中并发使用
 type ProviderFactory struct {
+	mu        sync.RWMutex
 	providers map[string]ProviderAdapter
 }
 
@@ -102,73 +112,116 @@ func NewProviderFactory() *ProviderFactory {
 
 // RegisterProvider 注册提供商适配器
 func (f *ProviderFactory) RegisterProvider(name string, provider ProviderAdapter) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
 	f.providers[name] = provider
 }
 
 // GetProvider 根据名称获取提供商适配器
 func (f *ProviderFactory) GetProvider(name string) (ProviderAdapter, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
 	provider, exists := f.providers[name]
 	return provider, exists
 }
 
 // ListProviders 获取所有已注册的提供商名称
 func (f *ProviderFactory) ListProviders() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
 	names := make([]string, 0, len(f.providers))
 	for name := range f.providers {
 		names = append(names, name)
 	}
+	sort.Strings(names)
 	return names
 }
 
+// LoadBalancerConfig 负载均衡配置
+type LoadBalancerConfig struct {
+	Strategy       string         `yaml:"strategy"`        // round_robin, weighted, least_connections, least_latency
+	Weights        map[string]int `yaml:"weights"`         // weighted策略的提供商权重
+	ExploreRate    *float64       `yaml:"explore_rate"`    // least_latency策略随机选择其他提供商的比例，未配置时为0.05，0表示不探索
+	CircuitBreaker BreakerConfig  `yaml:"circuit_breaker"` // 熔断器配置
+	Sticky         StickyConfig   `yaml:"sticky"`          // 会话粘性路由配置
+}
+
 // LoadBalancer 负载均衡器接口
 type LoadBalancer interface {
 	// SelectProvider 根据负载均衡策略选择提供商
 	SelectProvider(providers []ProviderAdapter) ProviderAdapter
 	
+	// RankSticky 按会话key对可用的提供商排序，同一会话始终优先选择第一个
+	RankSticky(key string, providers []ProviderAdapter) []ProviderAdapter
+
 	// UpdateHealth 更新提供商健康状态
 	UpdateHealth(providerName string, isHealthy bool)
+
+	// IsAvailable 判断提供商(及指定模型)的熔断器是否处于可用状态，不占用探测名额
+	IsAvailable(providerName, model string) bool
+
+	// Allow 判断熔断器是否放行对提供商指定模型的请求
+	Allow(providerName, model string) bool
+
+	// RecordResult 记录请求结果，用于熔断器统计失败率
+	RecordResult(providerName, model string, success bool)
+
+	// RecordModelResult 只记录模型级熔断器的请求结果，用于只与该模型有关的错误
+	RecordModelResult(providerName, model string, success bool)
+
+	// ReleaseProbe 归还Allow占用的探测名额，用于请求被取消、没有结果的情况
+	ReleaseProbe(providerName, model string)
+
+	// BreakerSnapshot 获取提供商的熔断器状态
+	BreakerSnapshot(providerName string) BreakerSnapshot
+
+	// ModelBreakerSnapshots 获取提供商下各模型的熔断器状态
+	ModelBreakerSnapshots(providerName string) map[string]BreakerSnapshot
+
+	// Acquire 请求发出时增加提供商的在途请求数
+	Acquire(providerName string)
+
+	// Release 请求结束时减少提供商的在途请求数
+	Release(providerName string)
+
+	// RecordLatency 记录提供商的响应延迟
+	RecordLatency(providerName string, latency time.Duration)
+
+	// LoadSnapshot 获取提供商的在途请求数和延迟统计
+	LoadSnapshot(providerName string) LoadSnapshot
 }
 
 // RoundRobinBalancer 轮询负载均衡器
 type RoundRobinBalancer struct {
-	current int
-	health  map[string]bool
+	*HealthTracker
+	current uint64 // 原子计数
 }
 
 // NewRoundRobinBalancer 创建轮询负载均衡器
-func NewRoundRobinBalancer() *RoundRobinBalancer {
+func NewRoundRobinBalancer(config BreakerConfig) *RoundRobinBalancer {
 	return &RoundRobinBalancer{
-		current: 0,
-		health:  make(map[string]bool),
+		HealthTracker: NewHealthTracker(config),
 	}
 }
 
-// SelectProvider 轮询选择健康的提供商
+// SelectProvider 轮询选择熔断器未打开的提供商
+// 所有提供商都处于熔断状态时返回nil，由调用方快速失败
 func (rb *RoundRobinBalancer) SelectProvider(providers []ProviderAdapter) ProviderAdapter {
 	if len(providers) == 0 {
 		return nil
 	}
 	
-	// 过滤健康的提供商
-	healthyProviders := make([]ProviderAdapter, 0)
-	for _, provider := range providers {
-		if isHealthy, exists := rb.health[provider.GetProviderName()]; !exists || isHealthy {
-			healthyProviders = append(healthyProviders, provider)
-		}
-	}
+	// 过滤可用的提供商
+	healthyProviders := rb.availableProviders(providers)
 	
 	if len(healthyProviders) == 0 {
-		// 如果没有健康的提供商，返回第一个
-		return providers[0]
+		return nil
 	}
 	
 	// 轮询选择
-	selected := healthyProviders[rb.current%len(healthyProviders)]
-	rb.current++
-	return selected
+	index := atomic.AddUint64(&rb.current, 1) - 1
+	return healthyProviders[index%uint64(len(healthyProviders))]
 }
-
-// UpdateHealth 更新提供商健康状态
-func (rb *RoundRobinBalancer) UpdateHealth(providerName string, isHealthy bool) {
-	rb.health[providerName] = isHealthy
-}
\ No newline at end of file
@@ -0,0 +1,390 @@
+package providers
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// BreakerState 熔断器状态
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // 正常放行
+	BreakerOpen     BreakerState = "open"      // 熔断中，拒绝请求
+	BreakerHalfOpen BreakerState = "half_open" // 冷却结束，放行少量探测请求
+)
+
+// 滚动窗口划分的桶数
+const breakerBuckets = 10
+
+// BreakerConfig 熔断器配置
+type BreakerConfig struct {
+	Window         int     `yaml:"window"`           // 统计失败率的滚动窗口(秒)
+	MinRequests    int     `yaml:"min_requests"`     // 窗口内请求数达到该值才计算失败率
+	FailureRate    float64 `yaml:"failure_rate"`     // 触发熔断的失败率 (0-1)
+	OpenSeconds    int     `yaml:"open_seconds"`     // 熔断后的冷却时间(秒)，之后进入半开状态
+	HalfOpenProbes int     `yaml:"half_open_probes"` // 半开状态下连续成功多少次后恢复
+}
+
+// withDefaults 补全未配置的字段
+func (c BreakerConfig) withDefaults() BreakerConfig {
+	if c.Window <= 0 {
+		c.Window = 60
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = 5
+	}
+	if c.FailureRate <= 0 || c.FailureRate > 1 {
+		c.FailureRate = 0.5
+	}
+	if c.OpenSeconds <= 0 {
+		c.OpenSeconds = 30
+	}
+	if c.HalfOpenProbes <= 0 {
+		c.HalfOpenProbes = 1
+	}
+	return c
+}
+
+// breakerBucket 滚动窗口中的一个时间桶
+type breakerBucket struct {
+	index    int64 // 桶对应的时间序号，用于判断是否过期
+	success  int
+	failures int
+}
+
+// CircuitBreaker 基于滚动窗口失败率的熔断器
+type CircuitBreaker struct {
+	mu     sync.Mutex
+	config BreakerConfig
+
+	state     BreakerState
+	openedAt  time.Time
+	buckets   [breakerBuckets]breakerBucket
+	probes    int       // 半开状态下已放行但尚未返回结果的探测请求数
+	probeAt   time.Time // 最近一次放行探测请求的时间
+	successes int       // 半开状态下连续成功的探测次数
+}
+
+// BreakerSnapshot 熔断器状态快照，用于管理接口展示
+type BreakerSnapshot struct {
+	State       BreakerState `json:"state"`
+	Requests    int          `json:"requests"`
+	Failures    int          `json:"failures"`
+	FailureRate float64      `json:"failureRate"`
+	OpenedAt    int64        `json:"openedAt,omitempty"`
+	RetryAt     int64        `json:"retryAt,omitempty"` // 预计进入半开状态的时间
+}
+
+// NewCircuitBreaker 创建熔断器
+func NewCircuitBreaker(config BreakerConfig) *CircuitBreaker {
+	return &CircuitBreaker{
+		config: config.withDefaults(),
+		state:  BreakerClosed,
+	}
+}
+
+// Ready 判断当前是否可以放行请求，不占用半开状态的探测名额
+// 用于负载均衡时过滤提供商
+func (b *CircuitBreaker) Ready() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance(time.Now())
+	return b.state != BreakerOpen && (b.state != BreakerHalfOpen || b.probeAvailable(time.Now()))
+}
+
+// Allow 申请放行一个请求，半开状态下会占用一个探测名额
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.advance(now)
+
+	switch b.state {
+	case BreakerClosed:
+		return true
+	case BreakerHalfOpen:
+		if !b.probeAvailable(now) {
+			return false
+		}
+		b.probes++
+		b.probeAt = now
+		return true
+	default:
+		return false
+	}
+}
+
+// Release 归还Allow占用的探测名额，用于放行后请求没有实际发出的情况
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// Record 记录一次请求结果
+func (b *CircuitBreaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.advance(now)
+
+	switch b.state {
+	case BreakerHalfOpen:
+		if b.probes > 0 {
+			b.probes--
+		}
+		if !success {
+			// 探测失败，重新熔断
+			b.trip(now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.config.HalfOpenProbes {
+			b.reset()
+		}
+		return
+	case BreakerOpen:
+		// 熔断前已放行的请求返回结果，不影响状态
+		return
+	}
+
+	bucket := b.bucket(now)
+	if success {
+		bucket.success++
+	} else {
+		bucket.failures++
+	}
+
+	requests, failures := b.counts(now)
+	if requests >= b.config.MinRequests && float64(failures)/float64(requests) >= b.config.FailureRate {
+		b.trip(now)
+	}
+}
+
+// Snapshot 获取状态快照
+func (b *CircuitBreaker) Snapshot() BreakerSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.advance(now)
+
+	requests, failures := b.counts(now)
+	snapshot := BreakerSnapshot{
+		State:    b.state,
+		Requests: requests,
+		Failures: failures,
+	}
+	if requests > 0 {
+		snapshot.FailureRate = float64(failures) / float64(requests)
+	}
+	if b.state != BreakerClosed {
+		snapshot.OpenedAt = b.openedAt.Unix()
+	}
+	if b.state == BreakerOpen {
+		snapshot.RetryAt = b.openedAt.Add(b.openDuration()).Unix()
+	}
+	return snapshot
+}
+
+// advance 冷却时间结束后从熔断状态进入半开状态
+func (b *CircuitBreaker) advance(now time.Time) {
+	if b.state == BreakerOpen && now.Sub(b.openedAt) >= b.openDuration() {
+		b.state = BreakerHalfOpen
+		b.probes = 0
+		b.successes = 0
+	}
+}
+
+// probeAvailable 半开状态下是否还有探测名额
+// 探测请求长时间没有返回结果(如客户端中途断开)时视为名额已释放，避免永远卡在半开状态
+func (b *CircuitBreaker) probeAvailable(now time.Time) bool {
+	return b.probes < b.config.HalfOpenProbes || now.Sub(b.probeAt) >= b.openDuration()
+}
+
+// trip 进入熔断状态
+func (b *CircuitBreaker) trip(now time.Time) {
+	b.state = BreakerOpen
+	b.openedAt = now
+	b.probes = 0
+	b.successes = 0
+}
+
+// reset 恢复为正常状态并清空统计窗口
+func (b *CircuitBreaker) reset() {
+	b.state = BreakerClosed
+	b.probes = 0
+	b.successes = 0
+	b.buckets = [breakerBuckets]breakerBucket{}
+}
+
+// bucketDuration 每个时间桶的长度
+func (b *CircuitBreaker) bucketDuration() time.Duration {
+	return time.Duration(b.config.Window) * time.Second / breakerBuckets
+}
+
+// openDuration 熔断冷却时间
+func (b *CircuitBreaker) openDuration() time.Duration {
+	return time.Duration(b.config.OpenSeconds) * time.Second
+}
+
+// bucket 获取当前时间对应的桶，过期的桶会被重置
+func (b *CircuitBreaker) bucket(now time.Time) *breakerBucket {
+	index := now.UnixNano() / int64(b.bucketDuration())
+	bucket := &b.buckets[index%breakerBuckets]
+	if bucket.index != index {
+		*bucket = breakerBucket{index: index}
+	}
+	return bucket
+}
+
+// counts 统计滚动窗口内的请求数和失败数
+func (b *CircuitBreaker) counts(now time.Time) (requests, failures int) {
+	current := now.UnixNano() / int64(b.bucketDuration())
+	for _, bucket := range b.buckets {
+		if current-bucket.index < breakerBuckets {
+			requests += bucket.success + bucket.failures
+			failures += bucket.failures
+		}
+	}
+	return requests, failures
+}
+
+// HealthTracker 按提供商和提供商+模型维护熔断器，并记录各提供商的在途请求数和响应延迟
+// 嵌入到负载均衡器中，为LoadBalancer接口提供熔断和统计相关的方法
+type HealthTracker struct {
+	mu       sync.RWMutex
+	config   BreakerConfig
+	breakers map[string]*CircuitBreaker
+	loads    map[string]*providerLoad
+}
+
+// NewHealthTracker 创建健康状态跟踪器
+func NewHealthTracker(config BreakerConfig) *HealthTracker {
+	return &HealthTracker{
+		config:   config.withDefaults(),
+		breakers: make(map[string]*CircuitBreaker),
+		loads:    make(map[string]*providerLoad),
+	}
+}
+
+// breakerKey 熔断器的key，model为空时为提供商级别
+func breakerKey(providerName, model string) string {
+	if model == "" {
+		return providerName
+	}
+	return providerName + "/" + model
+}
+
+// breaker 获取或创建熔断器
+func (t *HealthTracker) breaker(key string) *CircuitBreaker {
+	t.mu.RLock()
+	breaker, exists := t.breakers[key]
+	t.mu.RUnlock()
+	if exists {
+		return breaker
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if breaker, exists = t.breakers[key]; !exists {
+		breaker = NewCircuitBreaker(t.config)
+		t.breakers[key] = breaker
+	}
+	return breaker
+}
+
+// IsAvailable 判断提供商(model不为空时还包括该模型)当前是否可以接收请求，不占用探测名额
+func (t *HealthTracker) IsAvailable(providerName, model string) bool {
+	if model != "" && !t.breaker(breakerKey(providerName, model)).Ready() {
+		return false
+	}
+	return t.breaker(breakerKey(providerName, "")).Ready()
+}
+
+// Allow 申请向提供商的指定模型发送请求
+// 模型级熔断器和提供商级熔断器都放行时才返回true
+func (t *HealthTracker) Allow(providerName, model string) bool {
+	providerBreaker := t.breaker(breakerKey(providerName, ""))
+	if model == "" {
+		return providerBreaker.Allow()
+	}
+
+	// 先确认两级熔断器都可以放行，避免模型级熔断器占用探测名额后被提供商级熔断器拒绝
+	modelBreaker := t.breaker(breakerKey(providerName, model))
+	if !modelBreaker.Ready() || !providerBreaker.Ready() {
+		return false
+	}
+	if !modelBreaker.Allow() {
+		return false
+	}
+	// 两次检查之间提供商级熔断器的状态可能已经变化，被拒绝时归还模型级的探测名额
+	if !providerBreaker.Allow() {
+		modelBreaker.Release()
+		return false
+	}
+	return true
+}
+
+// RecordResult 记录请求结果，同时更新提供商级和模型级熔断器
+func (t *HealthTracker) RecordResult(providerName, model string, success bool) {
+	t.breaker(breakerKey(providerName, "")).Record(success)
+	if model != "" {
+		t.breaker(breakerKey(providerName, model)).Record(success)
+	}
+}
+
+// RecordModelResult 只记录模型级熔断器的请求结果
+// 模型不存在、模型限流等错误不说明提供商整体不可用，不计入提供商级熔断器
+func (t *HealthTracker) RecordModelResult(providerName, model string, success bool) {
+	if model == "" {
+		return
+	}
+	t.breaker(breakerKey(providerName, model)).Record(success)
+}
+
+// ReleaseProbe 归还Allow在提供商级和模型级熔断器上占用的探测名额，不计入成功或失败
+func (t *HealthTracker) ReleaseProbe(providerName, model string) {
+	t.breaker(breakerKey(providerName, "")).Release()
+	if model != "" {
+		t.breaker(breakerKey(providerName, model)).Release()
+	}
+}
+
+// UpdateHealth 更新提供商健康状态
+func (t *HealthTracker) UpdateHealth(providerName string, isHealthy bool) {
+	t.RecordResult(providerName, "", isHealthy)
+}
+
+// BreakerSnapshot 获取提供商级熔断器状态
+func (t *HealthTracker) BreakerSnapshot(providerName string) BreakerSnapshot {
+	return t.breaker(breakerKey(providerName, "")).Snapshot()
+}
+
+// ModelBreakerSnapshots 获取提供商下已有请求记录的模型级熔断器状态
+func (t *HealthTracker) ModelBreakerSnapshots(providerName string) map[string]BreakerSnapshot {
+	prefix := providerName + "/"
+
+	t.mu.RLock()
+	keys := make([]string, 0)
+	for key := range t.breakers {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	t.mu.RUnlock()
+
+	snapshots := make(map[string]BreakerSnapshot, len(keys))
+	for _, key := range keys {
+		snapshots[strings.TrimPrefix(key, prefix)] = t.breaker(key).Snapshot()
+	}
+	return snapshots
+}
@@ -0,0 +1,120 @@
+package providers
+
+import (
+	"testing"
+	"time"
+)
+
+// halfOpen 将熔断器置为刚进入半开状态
+func halfOpen(b *CircuitBreaker) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = BreakerHalfOpen
+	b.probes = 0
+	b.successes = 0
+}
+
+// tripNow 将熔断器置为刚熔断的状态
+func tripNow(b *CircuitBreaker) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trip(time.Now())
+}
+
+// TestCircuitBreakerTrip 失败率达到阈值后熔断，半开探测成功后恢复
+func TestCircuitBreakerTrip(t *testing.T) {
+	b := NewCircuitBreaker(BreakerConfig{MinRequests: 4, FailureRate: 0.5})
+	for i := 0; i < 2; i++ {
+		b.Record(true)
+		b.Record(false)
+	}
+	if b.Snapshot().State != BreakerOpen || b.Allow() {
+		t.Fatalf("失败率达到阈值后应熔断，实际状态 %s", b.Snapshot().State)
+	}
+
+	halfOpen(b)
+	if !b.Allow() {
+		t.Fatal("半开状态应放行一个探测请求")
+	}
+	if b.Allow() || b.Ready() {
+		t.Fatal("探测名额用完后不应再放行")
+	}
+	b.Record(true)
+	if b.Snapshot().State != BreakerClosed {
+		t.Fatalf("探测成功后应恢复，实际状态 %s", b.Snapshot().State)
+	}
+}
+
+// TestHealthTrackerAllowKeepsModelProbe 提供商级熔断时，模型级熔断器的探测名额不能被占用
+func TestHealthTrackerAllowKeepsModelProbe(t *testing.T) {
+	tracker := NewHealthTracker(BreakerConfig{})
+	modelBreaker := tracker.breaker(breakerKey("openai", "gpt-4o"))
+	providerBreaker := tracker.breaker(breakerKey("openai", ""))
+
+	halfOpen(modelBreaker)
+	tripNow(providerBreaker)
+	if tracker.Allow("openai", "gpt-4o") {
+		t.Fatal("提供商级熔断时不应放行")
+	}
+
+	// 提供商恢复后，模型级熔断器的探测名额仍然可用
+	providerBreaker.mu.Lock()
+	providerBreaker.reset()
+	providerBreaker.mu.Unlock()
+	if !tracker.Allow("openai", "gpt-4o") {
+		t.Fatal("提供商恢复后应放行模型级的探测请求")
+	}
+	if tracker.Allow("openai", "gpt-4o") {
+		t.Fatal("探测名额已被占用，不应再放行")
+	}
+}
+
+// TestCircuitBreakerRelease 归还的探测名额可以再次使用
+func TestCircuitBreakerRelease(t *testing.T) {
+	b := NewCircuitBreaker(BreakerConfig{})
+	halfOpen(b)
+	if !b.Allow() {
+		t.Fatal("半开状态应放行一个探测请求")
+	}
+	b.Release()
+	if !b.Ready() || !b.Allow() {
+		t.Fatal("归还后探测名额应可用")
+	}
+}
+
+// TestHealthTrackerReleaseProbe 取消的请求归还两级熔断器的探测名额，不计入成功或失败
+func TestHealthTrackerReleaseProbe(t *testing.T) {
+	tracker := NewHealthTracker(BreakerConfig{})
+	modelBreaker := tracker.breaker(breakerKey("openai", "gpt-4o"))
+	providerBreaker := tracker.breaker(breakerKey("openai", ""))
+	halfOpen(modelBreaker)
+	halfOpen(providerBreaker)
+
+	if !tracker.Allow("openai", "gpt-4o") {
+		t.Fatal("半开状态应放行一个探测请求")
+	}
+	tracker.ReleaseProbe("openai", "gpt-4o")
+
+	if !tracker.Allow("openai", "gpt-4o") {
+		t.Fatal("归还后的探测名额应可以再次使用")
+	}
+	if modelBreaker.Snapshot().State != BreakerHalfOpen || providerBreaker.Snapshot().State != BreakerHalfOpen {
+		t.Error("归还探测名额不应改变熔断器状态")
+	}
+}
+
+// TestHealthTrackerRecordModelResult 只与模型有关的失败不计入提供商级熔断器
+func TestHealthTrackerRecordModelResult(t *testing.T) {
+	tracker := NewHealthTracker(BreakerConfig{MinRequests: 4, FailureRate: 0.5})
+	for i := 0; i < 4; i++ {
+		tracker.RecordResult("openai", "", true)
+		tracker.RecordModelResult("openai", "gpt-4o", false)
+	}
+
+	if tracker.IsAvailable("openai", "gpt-4o") {
+		t.Error("模型级熔断器应熔断")
+	}
+	if !tracker.IsAvailable("openai", "gpt-4o-mini") {
+		t.Error("提供商的其他模型不应受影响")
+	}
+}
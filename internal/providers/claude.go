@@ -0,0 +1,578 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// ClaudeProvider Anthropic Claude适配器实现
+type ClaudeProvider struct {
+	BaseProvider
+	Version string // anthropic-version请求头
+}
+
+// Claude默认最大输出token数 (Messages API要求必须指定max_tokens)
+const claudeDefaultMaxTokens = 4096
+
+// NewClaudeProvider 创建Claude提供商实例
+func NewClaudeProvider(config *ClaudeConfig) *ClaudeProvider {
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com"
+	}
+
+	version := config.Version
+	if version == "" {
+		version = "2023-06-01"
+	}
+
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = 60
+	}
+
+	retries := config.Retries
+	if retries == 0 {
+		retries = 3
+	}
+
+	return &ClaudeProvider{
+		BaseProvider: BaseProvider{
+			Name:    "claude",
+			APIKey:  config.APIKey,
+			BaseURL: strings.TrimSuffix(baseURL, "/"),
+			Headers: map[string]string{
+				"Content-Type":      "application/json",
+				"x-api-key":         config.APIKey,
+				"anthropic-version": version,
+			},
+			Timeout: timeout,
+			Retries: retries,
+		},
+		Version: version,
+	}
+}
+
+// GetProviderName 获取提供商名称
+func (p *ClaudeProvider) GetProviderName() string {
+	return p.Name
+}
+
+// ValidateRequest 验证请求参数
+func (p *ClaudeProvider) ValidateRequest(req *types.UnifiedRequest) error {
+	if req.Model == "" {
+		return fmt.Errorf("模型名称不能为空")
+	}
+
+	if len(req.Messages) == 0 {
+		return fmt.Errorf("消息列表不能为空")
+	}
+
+	// Claude的温度参数范围为0-1
+	if temperature := req.Parameters.Temperature; temperature != nil && (*temperature < 0 || *temperature > 1) {
+		return fmt.Errorf("温度参数必须在0-1之间")
+	}
+
+	// 验证TopP参数范围
+	if topP := req.Parameters.TopP; topP != nil && (*topP < 0 || *topP > 1) {
+		return fmt.Errorf("TopP参数必须在0-1之间")
+	}
+
+	// system消息会被提取，至少需要一条对话消息
+	for _, msg := range req.Messages {
+		if msg.Role != "system" {
+			return nil
+		}
+	}
+	return fmt.Errorf("至少需要一条user或assistant消息")
+}
+
+// Transform 将统一请求转换为Claude Messages API格式
+func (p *ClaudeProvider) Transform(req *types.UnifiedRequest) ([]byte, error) {
+	// 不支持原生结构化输出，以提示词要求模型输出JSON
+	req = withFormatInstruction(req)
+
+	model := req.Model
+	if model == "" || model == "claude" {
+		model = GetDefaultModel("claude")
+	}
+
+	// Claude的system提示词不属于messages，需要提取到顶层
+	var systemParts []string
+	messages := make([]map[string]interface{}, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		switch {
+		case msg.Role == "system":
+			systemParts = append(systemParts, msg.Content)
+
+		case msg.Role == "tool":
+			// 工具结果作为user消息中的tool_result内容块，连续的工具结果合并到同一条消息
+			block := map[string]interface{}{
+				"type":        "tool_result",
+				"tool_use_id": msg.ToolCallID,
+				"content":     msg.Content,
+			}
+			if n := len(messages); n > 0 && messages[n-1]["role"] == "user" {
+				if blocks, ok := messages[n-1]["content"].([]map[string]interface{}); ok {
+					messages[n-1]["content"] = append(blocks, block)
+					continue
+				}
+			}
+			messages = append(messages, map[string]interface{}{
+				"role":    "user",
+				"content": []map[string]interface{}{block},
+			})
+
+		case len(msg.ToolCalls) > 0:
+			// assistant的工具调用转换为tool_use内容块
+			blocks := make([]map[string]interface{}, 0, len(msg.ToolCalls)+1)
+			if msg.Content != "" {
+				blocks = append(blocks, map[string]interface{}{"type": "text", "text": msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				blocks = append(blocks, map[string]interface{}{
+					"type":  "tool_use",
+					"id":    call.ID,
+					"name":  call.Function.Name,
+					"input": toolArguments(call.Function.Arguments),
+				})
+			}
+			messages = append(messages, map[string]interface{}{
+				"role":    "assistant",
+				"content": blocks,
+			})
+
+		default:
+			messages = append(messages, map[string]interface{}{
+				"role":    msg.Role,
+				"content": msg.Content,
+			})
+		}
+	}
+
+	maxTokens := req.Parameters.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = claudeDefaultMaxTokens
+	}
+
+	// 构建Claude请求结构
+	claudeReq := map[string]interface{}{
+		"model":    model,
+		"messages": messages,
+	}
+
+	if len(systemParts) > 0 {
+		claudeReq["system"] = strings.Join(systemParts, "\n\n")
+	}
+
+	// 扩展思考模式 (适用于claude-3-7及以上模型)
+	if req.Parameters.Reasoning {
+		budget := claudeThinkingBudget(req.Parameters.ReasoningEffort)
+		claudeReq["thinking"] = map[string]interface{}{
+			"type":          "enabled",
+			"budget_tokens": budget,
+		}
+		// max_tokens必须大于思考预算
+		if maxTokens <= budget {
+			maxTokens = budget + claudeDefaultMaxTokens
+		}
+	} else if req.Parameters.Temperature != nil {
+		// 开启思考时Claude不允许修改温度
+		claudeReq["temperature"] = *req.Parameters.Temperature
+	}
+
+	claudeReq["max_tokens"] = maxTokens
+
+	if req.Parameters.TopP != nil && !req.Parameters.Reasoning {
+		claudeReq["top_p"] = *req.Parameters.TopP
+	}
+
+	if len(req.Parameters.Stop) > 0 {
+		claudeReq["stop_sequences"] = req.Parameters.Stop
+	}
+
+	if req.Parameters.Stream {
+		claudeReq["stream"] = true
+	}
+
+	// 添加用户ID（如果存在）
+	if req.Metadata.UserID != "" {
+		claudeReq["metadata"] = map[string]interface{}{
+			"user_id": req.Metadata.UserID,
+		}
+	}
+
+	// 工具定义使用input_schema描述参数
+	if len(req.Tools) > 0 {
+		tools := make([]map[string]interface{}, len(req.Tools))
+		for i, tool := range req.Tools {
+			schema := tool.Function.Parameters
+			if len(schema) == 0 {
+				schema = json.RawMessage(`{"type":"object","properties":{}}`)
+			}
+			tools[i] = map[string]interface{}{
+				"name":         tool.Function.Name,
+				"input_schema": schema,
+			}
+			if tool.Function.Description != "" {
+				tools[i]["description"] = tool.Function.Description
+			}
+		}
+		claudeReq["tools"] = tools
+
+		if req.ToolChoice != nil {
+			claudeReq["tool_choice"] = claudeToolChoice(req.ToolChoice)
+		}
+	}
+
+	return json.Marshal(claudeReq)
+}
+
+// claudeToolChoice 将工具选择策略转换为Claude格式，required对应any
+func claudeToolChoice(choice *types.ToolChoice) map[string]interface{} {
+	switch choice.Mode {
+	case types.ToolChoiceNone:
+		return map[string]interface{}{"type": "none"}
+	case types.ToolChoiceRequired:
+		return map[string]interface{}{"type": "any"}
+	case types.ToolChoiceFunction:
+		return map[string]interface{}{"type": "tool", "name": choice.Function}
+	default:
+		return map[string]interface{}{"type": "auto"}
+	}
+}
+
+// claudeThinkingBudget 根据推理强度计算思考token预算
+func claudeThinkingBudget(effort string) int {
+	switch effort {
+	case "low":
+		return 1024
+	case "high":
+		return 16384
+	default:
+		return 4096
+	}
+}
+
+// CallAPI 调用Claude API
+func (p *ClaudeProvider) CallAPI(ctx context.Context, data []byte) (*http.Response, error) {
+	url := p.BaseURL + "/v1/messages"
+
+	// 创建HTTP请求
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
+	}
+
+	// 设置请求头
+	for key, value := range p.Headers {
+		req.Header.Set(key, value)
+	}
+
+	// 创建HTTP客户端
+	client := p.httpClient(isStreamRequest(data))
+
+	// 发送请求
+	resp, err := p.doWithRetry(client, req)
+	if err != nil {
+		return nil, fmt.Errorf("调用Claude API失败: %w", err)
+	}
+
+	return resp, nil
+}
+
+// claudeMessage Claude Messages API响应结构
+type claudeMessage struct {
+	ID         flexString           `json:"id"`
+	Model      flexString           `json:"model"`
+	Content    []claudeContentBlock `json:"content"`
+	StopReason flexString           `json:"stop_reason"`
+	Usage      *claudeUsage         `json:"usage"`
+	Error      *claudeError         `json:"error"`
+}
+
+// claudeContentBlock Claude内容块，同时用于content数组和流式的delta
+type claudeContentBlock struct {
+	Type        flexString      `json:"type"`
+	Text        flexString      `json:"text"`
+	Thinking    flexString      `json:"thinking"`
+	ID          flexString      `json:"id"`           // tool_use内容块
+	Name        flexString      `json:"name"`         // tool_use内容块
+	Input       json.RawMessage `json:"input"`        // tool_use内容块的参数
+	PartialJSON flexString      `json:"partial_json"` // input_json_delta中的参数片段
+}
+
+// claudeUsage Claude的usage结构
+type claudeUsage struct {
+	InputTokens  flexInt `json:"input_tokens"`
+	OutputTokens flexInt `json:"output_tokens"`
+}
+
+// claudeError Claude错误结构: {"type":"error","error":{"type":"...","message":"..."}}
+type claudeError struct {
+	Type    flexString `json:"type"`
+	Message flexString `json:"message"`
+}
+
+// claudeStreamEvent Claude流式事件结构
+type claudeStreamEvent struct {
+	Type         flexString          `json:"type"`
+	Message      *claudeMessage      `json:"message"`
+	Index        flexInt             `json:"index"`
+	ContentBlock *claudeContentBlock `json:"content_block"`
+	Delta        claudeStreamDelta   `json:"delta"`
+	Usage        *claudeUsage        `json:"usage"`
+	Error        *claudeError        `json:"error"`
+}
+
+// claudeStreamDelta content_block_delta和message_delta事件的delta字段
+type claudeStreamDelta struct {
+	claudeContentBlock
+	StopReason flexString `json:"stop_reason"`
+}
+
+// ParseResponse 解析Claude响应
+func (p *ClaudeProvider) ParseResponse(resp *http.Response) (*types.UnifiedResponse, error) {
+	defer resp.Body.Close()
+
+	body, err := readResponseBody(resp)
+	if err != nil {
+		return nil, fmt.Errorf("读取Claude响应失败: %w", err)
+	}
+
+	// 解析响应JSON
+	var claudeResp claudeMessage
+	if err := json.Unmarshal(body, &claudeResp); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return upstreamStatusError(p.Name, resp.StatusCode, body), nil
+		}
+		return invalidResponseError(p.Name, err), nil
+	}
+
+	// 检查错误
+	if claudeResp.Error != nil {
+		return upstreamError(p.Name, resp.StatusCode, string(claudeResp.Error.Type), string(claudeResp.Error.Message), "claude_error"), nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return upstreamStatusError(p.Name, resp.StatusCode, body), nil
+	}
+
+	// 转换为统一响应格式
+	unifiedResp := &types.UnifiedResponse{
+		ID:      string(claudeResp.ID),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   string(claudeResp.Model),
+	}
+
+	// 拼接所有text内容块，thinking内容块不计入正文
+	var content strings.Builder
+	var toolCalls []types.ToolCall
+	for _, block := range claudeResp.Content {
+		switch block.Type {
+		case "text":
+			content.WriteString(string(block.Text))
+		case "tool_use":
+			toolCalls = append(toolCalls, types.ToolCall{
+				ID:   string(block.ID),
+				Type: "function",
+				Function: types.FunctionCall{
+					Name:      string(block.Name),
+					Arguments: toolArgumentsText(block.Input),
+				},
+			})
+		}
+	}
+
+	unifiedResp.Choices = []types.Choice{
+		{
+			Index: 0,
+			Message: types.Message{
+				Role:      "assistant",
+				Content:   content.String(),
+				ToolCalls: toolCalls,
+			},
+			FinishReason: mapClaudeStopReason(string(claudeResp.StopReason)),
+		},
+	}
+
+	// 解析usage
+	if claudeResp.Usage != nil {
+		unifiedResp.Usage = types.Usage{
+			PromptTokens:     int(claudeResp.Usage.InputTokens),
+			CompletionTokens: int(claudeResp.Usage.OutputTokens),
+			TotalTokens:      int(claudeResp.Usage.InputTokens + claudeResp.Usage.OutputTokens),
+		}
+	}
+
+	return unifiedResp, nil
+}
+
+// mapClaudeStopReason 将Claude的stop_reason映射为统一的finish_reason
+func mapClaudeStopReason(reason string) string {
+	switch reason {
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	case "refusal":
+		return "content_filter"
+	case "":
+		return ""
+	default:
+		return reason
+	}
+}
+
+// ParseStreamResponse 解析Claude流式响应
+// Claude使用带event类型的SSE，每个data负载中也包含type字段
+func (p *ClaudeProvider) ParseStreamResponse(resp *http.Response) (<-chan *types.StreamResponse, error) {
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("Claude API返回错误状态码: %d, %s", resp.StatusCode, readErrorBody(resp))
+	}
+
+	responseChan := make(chan *types.StreamResponse)
+
+	go func() {
+		defer close(responseChan)
+		defer resp.Body.Close()
+
+		// 在message_start中获取的消息ID、模型和输入token数
+		var messageID, model string
+		var inputTokens int
+
+		// tool_use内容块的序号到工具调用序号的映射
+		toolIndexes := make(map[int]int)
+
+		var progress StreamProgress
+		decoder := NewSSEDecoder(resp.Body)
+		for {
+			sseEvent, err := decoder.Next()
+			if err != nil {
+				// 没有收到message_stop时，只有收到完成原因才视为正常结束
+				if interrupted := progress.interruption(p.Name, err); interrupted != nil {
+					responseChan <- interrupted
+				}
+				return
+			}
+
+			// event字段的信息在data的type字段中同样存在，以data为准
+			var event claudeStreamEvent
+			if err := json.Unmarshal([]byte(sseEvent.Data), &event); err != nil {
+				continue // 跳过无效的JSON
+			}
+
+			newChunk := func() *types.StreamResponse {
+				return &types.StreamResponse{
+					ID:      messageID,
+					Object:  "chat.completion.chunk",
+					Created: time.Now().Unix(),
+					Model:   model,
+				}
+			}
+
+			switch event.Type {
+			case "message_start":
+				if event.Message != nil {
+					messageID = string(event.Message.ID)
+					model = string(event.Message.Model)
+					if event.Message.Usage != nil {
+						inputTokens = int(event.Message.Usage.InputTokens)
+					}
+				}
+
+				chunk := newChunk()
+				chunk.Choices = []types.StreamChoice{
+					{Index: 0, Delta: types.StreamDelta{Role: "assistant"}},
+				}
+				responseChan <- chunk
+
+			case "content_block_start":
+				if event.ContentBlock == nil || event.ContentBlock.Type != "tool_use" {
+					continue
+				}
+
+				// 工具调用的ID和函数名称在content_block_start中返回，参数随后以input_json_delta分片返回
+				toolIndex := len(toolIndexes)
+				toolIndexes[int(event.Index)] = toolIndex
+
+				chunk := newChunk()
+				chunk.Choices = []types.StreamChoice{
+					{Index: 0, Delta: types.StreamDelta{ToolCalls: []types.ToolCallDelta{{
+						Index:    toolIndex,
+						ID:       string(event.ContentBlock.ID),
+						Type:     "function",
+						Function: types.FunctionCallDelta{Name: string(event.ContentBlock.Name)},
+					}}}},
+				}
+				responseChan <- chunk
+
+			case "content_block_delta":
+				streamDelta := types.StreamDelta{}
+				switch event.Delta.Type {
+				case "text_delta":
+					streamDelta.Content = string(event.Delta.Text)
+				case "thinking_delta":
+					streamDelta.Reasoning = string(event.Delta.Thinking)
+				case "input_json_delta":
+					toolIndex, ok := toolIndexes[int(event.Index)]
+					if !ok {
+						continue
+					}
+					streamDelta.ToolCalls = []types.ToolCallDelta{{
+						Index:    toolIndex,
+						Function: types.FunctionCallDelta{Arguments: string(event.Delta.PartialJSON)},
+					}}
+				default:
+					continue
+				}
+
+				chunk := newChunk()
+				chunk.Choices = []types.StreamChoice{{Index: 0, Delta: streamDelta}}
+				responseChan <- chunk
+
+			case "message_delta":
+				chunk := newChunk()
+				chunk.Choices = []types.StreamChoice{
+					{Index: 0, FinishReason: mapClaudeStopReason(string(event.Delta.StopReason))},
+				}
+				if event.Usage != nil {
+					outputTokens := int(event.Usage.OutputTokens)
+					chunk.Usage = &types.Usage{
+						PromptTokens:     inputTokens,
+						CompletionTokens: outputTokens,
+						TotalTokens:      inputTokens + outputTokens,
+					}
+				}
+				progress.Observe(chunk)
+				responseChan <- chunk
+
+			case "error":
+				chunk := newChunk()
+				if event.Error != nil {
+					chunk.Error = upstreamError(p.Name, http.StatusOK, string(event.Error.Type), string(event.Error.Message), "claude_error").Error
+				} else {
+					chunk.Error = upstreamError(p.Name, http.StatusOK, "stream_error", "", "claude_error").Error
+				}
+				responseChan <- chunk
+				return
+
+			case "message_stop":
+				return
+			}
+		}
+	}()
+
+	return responseChan, nil
+}
@@ -0,0 +1,120 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 流式请求使用的Transport，按等待响应头的超时时间复用，保留连接池
+var (
+	streamTransportsMu sync.Mutex
+	streamTransports   = make(map[time.Duration]*http.Transport)
+)
+
+// httpClient 创建调用上游API的HTTP客户端，各提供商的CallAPI共用
+// 非流式请求的timeout限制整个请求(包括读取响应体)；
+// 流式请求的推理过程可能持续数分钟，不限制总时长，timeout只限制等待响应头的时间和两次收到数据之间的间隔
+func (p *BaseProvider) httpClient(stream bool) *http.Client {
+	timeout := time.Duration(p.Timeout) * time.Second
+	if !stream || timeout <= 0 {
+		return &http.Client{Timeout: timeout}
+	}
+
+	return &http.Client{
+		Transport: &idleTimeoutTransport{base: streamTransport(timeout), idle: timeout},
+	}
+}
+
+// streamTransport 返回等待响应头超时时间为timeout的Transport
+func streamTransport(timeout time.Duration) *http.Transport {
+	streamTransportsMu.Lock()
+	defer streamTransportsMu.Unlock()
+
+	transport, ok := streamTransports[timeout]
+	if !ok {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+		transport.ResponseHeaderTimeout = timeout
+		streamTransports[timeout] = transport
+	}
+	return transport
+}
+
+// idleTimeoutTransport 响应体超过idle没有读到新数据时中断请求
+type idleTimeoutTransport struct {
+	base http.RoundTripper
+	idle time.Duration
+}
+
+// RoundTrip 发送请求，返回的响应体在空闲超时后读取失败
+func (t *idleTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	body := &idleTimeoutBody{ReadCloser: resp.Body, idle: t.idle, cancel: cancel}
+	body.timer = time.AfterFunc(t.idle, body.expire)
+	resp.Body = body
+	return resp, nil
+}
+
+// idleTimeoutBody 每次读到数据后重新计时，超时后取消请求，之后的读取返回空闲超时错误
+type idleTimeoutBody struct {
+	io.ReadCloser
+	idle   time.Duration
+	cancel context.CancelFunc
+	timer  *time.Timer
+
+	mu      sync.Mutex
+	expired bool
+}
+
+// expire 空闲超时，取消请求使阻塞中的读取返回
+func (b *idleTimeoutBody) expire() {
+	b.mu.Lock()
+	b.expired = true
+	b.mu.Unlock()
+	b.cancel()
+}
+
+// Read 读取响应体，读到数据时重置空闲计时
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.timer.Reset(b.idle)
+	}
+
+	b.mu.Lock()
+	expired := b.expired
+	b.mu.Unlock()
+	if err != nil && err != io.EOF && expired {
+		err = fmt.Errorf("超过 %s 没有收到新数据", b.idle)
+	}
+	return n, err
+}
+
+// Close 关闭响应体并停止计时
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// isStreamRequest 判断请求体是否为流式请求("stream": true)
+func isStreamRequest(data []byte) bool {
+	var reqData struct {
+		Stream bool `json:"stream"`
+	}
+	if err := json.Unmarshal(data, &reqData); err != nil {
+		return false
+	}
+	return reqData.Stream
+}
@@ -0,0 +1,87 @@
+package providers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowStreamServer 每隔interval输出一个分片，共输出count个
+func slowStreamServer(t *testing.T, interval time.Duration, count int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher := w.(http.Flusher)
+		for i := 0; i < count; i++ {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(interval):
+			}
+			io.WriteString(w, "data: {}\n\n")
+			flusher.Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestHTTPClientStreamTimeouts 流式请求不限制总时长，只限制等待响应头的时间
+func TestHTTPClientStreamTimeouts(t *testing.T) {
+	p := &BaseProvider{Timeout: 30}
+
+	if client := p.httpClient(false); client.Timeout != 30*time.Second {
+		t.Errorf("非流式请求的总超时应为30s，实际 %s", client.Timeout)
+	}
+
+	client := p.httpClient(true)
+	if client.Timeout != 0 {
+		t.Errorf("流式请求不应限制总时长，实际 %s", client.Timeout)
+	}
+	transport, ok := client.Transport.(*idleTimeoutTransport)
+	if !ok || transport.idle != 30*time.Second {
+		t.Fatalf("流式请求应使用分片间隔超时，实际 %#v", client.Transport)
+	}
+	if header := transport.base.(*http.Transport).ResponseHeaderTimeout; header != 30*time.Second {
+		t.Errorf("等待响应头的超时应为30s，实际 %s", header)
+	}
+}
+
+// TestIdleTimeoutTransport 总时长超过空闲超时的流可以读完，分片间隔超过空闲超时时读取失败
+func TestIdleTimeoutTransport(t *testing.T) {
+	const idle = 100 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		wantErr  bool
+	}{
+		{name: "总时长超过空闲超时", interval: idle / 4},
+		{name: "分片间隔超过空闲超时", interval: idle * 3, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := slowStreamServer(t, tt.interval, 8)
+			client := &http.Client{Transport: &idleTimeoutTransport{base: http.DefaultTransport, idle: idle}}
+
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "没有收到新数据") {
+					t.Errorf("应返回空闲超时错误，实际 %v", err)
+				}
+				return
+			}
+			if err != nil || strings.Count(string(body), "data:") != 8 {
+				t.Errorf("应读完8个分片，实际 %q, %v", body, err)
+			}
+		})
+	}
+}
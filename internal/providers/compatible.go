@@ -0,0 +1,154 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// CompatibleProviderConfig OpenAI兼容提供商配置
+// 用于通过配置文件接入vLLM、Together、Groq、OpenRouter等兼容OpenAI协议的服务
+type CompatibleProviderConfig struct {
+	Name         string            `yaml:"name"`          // 提供商名称，注册到工厂时使用
+	BaseURL      string            `yaml:"base_url"`      // API基础URL，如 https://api.groq.com/openai/v1
+	APIKey       string            `yaml:"api_key"`       // API密钥，为空时不发送鉴权头
+	AuthHeader   string            `yaml:"auth_header"`   // 鉴权请求头名称，默认Authorization
+	AuthScheme   string            `yaml:"auth_scheme"`   // 鉴权前缀，Authorization默认为Bearer
+	ChatPath     string            `yaml:"chat_path"`     // 聊天接口路径，默认/chat/completions
+	Headers      map[string]string `yaml:"headers"`       // 额外请求头
+	Models       []string          `yaml:"models"`        // 支持的模型列表
+	DefaultModel string            `yaml:"default_model"` // 默认模型
+	Timeout      int               `yaml:"timeout"`
+	Retries      int               `yaml:"retries"`
+}
+
+// GenericOpenAICompatibleProvider 通用OpenAI兼容适配器实现
+// 请求转换和响应解析复用OpenAIProvider，只有URL和请求头由配置决定
+type GenericOpenAICompatibleProvider struct {
+	OpenAIProvider
+	ChatPath     string
+	DefaultModel string
+}
+
+// NewGenericOpenAICompatibleProvider 根据配置创建OpenAI兼容提供商实例
+func NewGenericOpenAICompatibleProvider(config *CompatibleProviderConfig) (*GenericOpenAICompatibleProvider, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("OpenAI兼容提供商必须配置name")
+	}
+
+	if config.BaseURL == "" {
+		return nil, fmt.Errorf("OpenAI兼容提供商 %s 必须配置base_url", config.Name)
+	}
+
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = 30
+	}
+
+	retries := config.Retries
+	if retries == 0 {
+		retries = 3
+	}
+
+	chatPath := config.ChatPath
+	if chatPath == "" {
+		chatPath = "/chat/completions"
+	}
+	if !strings.HasPrefix(chatPath, "/") {
+		chatPath = "/" + chatPath
+	}
+
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+	for key, value := range config.Headers {
+		headers[key] = value
+	}
+
+	// 鉴权请求头，如 Authorization: Bearer xxx 或 api-key: xxx
+	if config.APIKey != "" {
+		authHeader := config.AuthHeader
+		if authHeader == "" {
+			authHeader = "Authorization"
+		}
+
+		authScheme := config.AuthScheme
+		if authScheme == "" && strings.EqualFold(authHeader, "Authorization") {
+			authScheme = "Bearer"
+		}
+
+		if authScheme != "" {
+			headers[authHeader] = authScheme + " " + config.APIKey
+		} else {
+			headers[authHeader] = config.APIKey
+		}
+	}
+
+	defaultModel := config.DefaultModel
+	if defaultModel == "" && len(config.Models) > 0 {
+		defaultModel = config.Models[0]
+	}
+
+	RegisterModels(config.Name, ModelConfig{
+		Models:       config.Models,
+		DefaultModel: defaultModel,
+	})
+
+	return &GenericOpenAICompatibleProvider{
+		OpenAIProvider: OpenAIProvider{
+			BaseProvider: BaseProvider{
+				Name:    config.Name,
+				APIKey:  config.APIKey,
+				BaseURL: strings.TrimSuffix(config.BaseURL, "/"),
+				Headers: headers,
+				Timeout: timeout,
+				Retries: retries,
+			},
+		},
+		ChatPath:     chatPath,
+		DefaultModel: defaultModel,
+	}, nil
+}
+
+// Transform 将统一请求转换为OpenAI兼容格式
+func (p *GenericOpenAICompatibleProvider) Transform(req *types.UnifiedRequest) ([]byte, error) {
+	// 如果没有指定模型，使用配置的默认模型
+	if req.Model == "" || req.Model == p.Name {
+		converted := *req
+		converted.Model = p.DefaultModel
+		return p.OpenAIProvider.Transform(&converted)
+	}
+
+	return p.OpenAIProvider.Transform(req)
+}
+
+// CallAPI 调用OpenAI兼容API
+func (p *GenericOpenAICompatibleProvider) CallAPI(ctx context.Context, data []byte) (*http.Response, error) {
+	url := p.BaseURL + p.ChatPath
+
+	// 创建HTTP请求
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
+	}
+
+	// 设置请求头
+	for key, value := range p.Headers {
+		req.Header.Set(key, value)
+	}
+
+	// 创建HTTP客户端
+	client := p.httpClient(isStreamRequest(data))
+
+	// 发送请求
+	resp, err := p.doWithRetry(client, req)
+	if err != nil {
+		return nil, fmt.Errorf("调用%s API失败: %w", p.Name, err)
+	}
+
+	return resp, nil
+}
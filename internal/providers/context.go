@@ -0,0 +1,156 @@
+package providers
+
+import (
+	"fmt"
+	"unicode"
+
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// 未指定max_tokens时为输出预留的token数
+const defaultCompletionReserve = 1024
+
+// ContextConfig 上下文长度检查配置
+type ContextConfig struct {
+	AutoUpgrade       *bool `yaml:"auto_upgrade"`       // 超出上下文窗口时是否自动切换到更大上下文的同系列模型，默认开启
+	CompletionReserve int   `yaml:"completion_reserve"` // 未指定max_tokens时为输出预留的token数
+}
+
+// autoUpgrade 是否自动升级模型
+func (c *ContextConfig) autoUpgrade() bool {
+	return c == nil || c.AutoUpgrade == nil || *c.AutoUpgrade
+}
+
+// completionReserve 输出预留的token数
+func (c *ContextConfig) completionReserve(maxTokens int) int {
+	if maxTokens > 0 {
+		return maxTokens
+	}
+	if c != nil && c.CompletionReserve > 0 {
+		return c.CompletionReserve
+	}
+	return defaultCompletionReserve
+}
+
+// contextFamilies 同一系列、仅上下文窗口不同的模型，按上下文从小到大排列
+var contextFamilies = map[string][][]string{
+	"moonshot": {
+		{"moonshot-v1-8k", "moonshot-v1-32k", "moonshot-v1-128k"},
+	},
+}
+
+// modelAliases 模型别名 -> 正式名称
+var modelAliases = map[string]map[string]string{
+	"moonshot": {
+		"moonshot-8k":   "moonshot-v1-8k",
+		"moonshot-32k":  "moonshot-v1-32k",
+		"moonshot-128k": "moonshot-v1-128k",
+	},
+}
+
+// CanonicalModel 将模型别名转换为正式名称，不是别名时原样返回
+func CanonicalModel(provider, model string) string {
+	if canonical, exists := modelAliases[provider][model]; exists {
+		return canonical
+	}
+	return model
+}
+
+// EstimateTokens 估算文本的token数
+// 中日韩字符约1个token，其余字符约4个字符1个token，结果偏保守，仅用于上下文长度检查
+func EstimateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+			unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+// EstimateMessagesTokens 估算消息列表的token数，每条消息额外计入角色等格式开销
+func EstimateMessagesTokens(messages []types.Message) int {
+	const perMessageOverhead = 4
+
+	total := 3 // 回复的起始标记
+	for _, msg := range messages {
+		total += perMessageOverhead + EstimateTokens(msg.Role) + EstimateTokens(msg.Content)
+		for _, call := range msg.ToolCalls {
+			total += EstimateTokens(call.Function.Name) + EstimateTokens(call.Function.Arguments)
+		}
+	}
+	return total
+}
+
+// ContextLengthError 请求超出模型上下文窗口
+type ContextLengthError struct {
+	Provider       string
+	Model          string
+	RequiredTokens int
+	ContextWindow  int
+}
+
+// Error 实现error接口
+func (e *ContextLengthError) Error() string {
+	return fmt.Sprintf("请求预计需要约 %d 个token，超出 %s/%s 的上下文窗口 %d", e.RequiredTokens, e.Provider, e.Model, e.ContextWindow)
+}
+
+// FitContextWindow 检查请求是否超出模型的上下文窗口
+// 超出时在允许的情况下切换到同系列中第一个放得下的更大上下文模型，返回最终使用的模型
+// 价格表中没有上下文窗口信息的模型不做检查
+func FitContextWindow(provider, model string, req *types.UnifiedRequest, config *ContextConfig) (string, error) {
+	model = CanonicalModel(provider, model)
+
+	info, exists := GetModelInfo(provider, model)
+	if !exists || info.ContextWindow <= 0 {
+		return model, nil
+	}
+
+	required := RequiredContextTokens(req, config)
+	if required <= info.ContextWindow {
+		return model, nil
+	}
+
+	if config.autoUpgrade() {
+		if larger := largerContextModel(provider, model, required); larger != "" {
+			return larger, nil
+		}
+	}
+
+	return model, &ContextLengthError{
+		Provider:       provider,
+		Model:          model,
+		RequiredTokens: required,
+		ContextWindow:  info.ContextWindow,
+	}
+}
+
+// RequiredContextTokens 估算请求需要的上下文长度 (输入 + 输出预留)
+func RequiredContextTokens(req *types.UnifiedRequest, config *ContextConfig) int {
+	return EstimateMessagesTokens(req.Messages) + config.completionReserve(req.Parameters.MaxTokens)
+}
+
+// largerContextModel 在同系列中查找上下文窗口足够的更大模型
+func largerContextModel(provider, model string, required int) string {
+	for _, family := range contextFamilies[provider] {
+		found := false
+		for _, candidate := range family {
+			if candidate == model {
+				found = true
+				continue
+			}
+			if !found {
+				continue
+			}
+
+			info, exists := GetModelInfo(provider, candidate)
+			if exists && info.ContextWindow >= required {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
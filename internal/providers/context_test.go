@@ -0,0 +1,87 @@
+package providers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// contextRequest 创建需要约tokens个token上下文的请求(输入加上max_tokens=1000)
+func contextRequest(tokens int) *types.UnifiedRequest {
+	// 单条用户消息的token数为内容长度(中文每字1个)加8
+	return &types.UnifiedRequest{
+		Messages:   []types.Message{{Role: "user", Content: strings.Repeat("字", tokens-1008)}},
+		Parameters: types.Parameters{MaxTokens: 1000},
+	}
+}
+
+// TestFitContextWindow 超出上下文窗口时升级到同系列第一个放得下的模型，关闭自动升级或没有更大的模型时返回错误
+func TestFitContextWindow(t *testing.T) {
+	disabled := false
+
+	tests := []struct {
+		name      string
+		model     string
+		tokens    int
+		config    *ContextConfig
+		wantModel string
+		wantErr   bool
+	}{
+		{name: "放得下时不切换", model: "moonshot-v1-8k", tokens: 8192, wantModel: "moonshot-v1-8k"},
+		{name: "8k升级到32k", model: "moonshot-v1-8k", tokens: 8193, wantModel: "moonshot-v1-32k"},
+		{name: "8k跳过32k升级到128k", model: "moonshot-v1-8k", tokens: 40000, wantModel: "moonshot-v1-128k"},
+		{name: "32k升级到128k", model: "moonshot-v1-32k", tokens: 40000, wantModel: "moonshot-v1-128k"},
+		{name: "别名转换为正式名称后升级", model: "moonshot-8k", tokens: 10000, wantModel: "moonshot-v1-32k"},
+		{name: "128k也放不下", model: "moonshot-v1-8k", tokens: 140000, wantModel: "moonshot-v1-8k", wantErr: true},
+		{
+			name:      "关闭自动升级",
+			model:     "moonshot-v1-8k",
+			tokens:    10000,
+			config:    &ContextConfig{AutoUpgrade: &disabled},
+			wantModel: "moonshot-v1-8k",
+			wantErr:   true,
+		},
+		{name: "没有上下文窗口信息的模型不检查", model: "moonshot-unknown", tokens: 140000, wantModel: "moonshot-unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, err := FitContextWindow("moonshot", tt.model, contextRequest(tt.tokens), tt.config)
+			if model != tt.wantModel {
+				t.Errorf("模型 %s, 期望 %s", model, tt.wantModel)
+			}
+
+			var lengthErr *ContextLengthError
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("不应返回错误: %v", err)
+				}
+				return
+			}
+			if !errors.As(err, &lengthErr) {
+				t.Fatalf("应返回ContextLengthError，实际 %v", err)
+			}
+			if lengthErr.RequiredTokens != tt.tokens || lengthErr.ContextWindow != 8192 {
+				t.Errorf("需要 %d 个token, 上下文窗口 %d; 期望 %d, 8192", lengthErr.RequiredTokens, lengthErr.ContextWindow, tt.tokens)
+			}
+		})
+	}
+}
+
+// TestRequiredContextTokens 未指定max_tokens时按配置为输出预留token
+func TestRequiredContextTokens(t *testing.T) {
+	req := &types.UnifiedRequest{Messages: []types.Message{{Role: "user", Content: "你好"}}}
+
+	if got := RequiredContextTokens(req, nil); got != 10+defaultCompletionReserve {
+		t.Errorf("默认预留: %d", got)
+	}
+	if got := RequiredContextTokens(req, &ContextConfig{CompletionReserve: 100}); got != 110 {
+		t.Errorf("配置预留: %d", got)
+	}
+	req.Parameters.MaxTokens = 50
+	if got := RequiredContextTokens(req, &ContextConfig{CompletionReserve: 100}); got != 60 {
+		t.Errorf("指定max_tokens: %d", got)
+	}
+}
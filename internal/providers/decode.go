@@ -0,0 +1,180 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// 响应体最大读取长度，防止异常响应占用过多内存
+const maxResponseBodySize = 32 << 20
+
+// flexString 容错字符串类型
+// 上游返回null时为空字符串，数字和布尔值转换为字符串，内容块数组([{"type":"text","text":"..."}])拼接其中的文本
+type flexString string
+
+// UnmarshalJSON 实现json.Unmarshaler接口
+func (s *flexString) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		*s = ""
+		return nil
+	}
+
+	switch data[0] {
+	case '"':
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		*s = flexString(value)
+	case '[':
+		var parts []struct {
+			Text flexString `json:"text"`
+		}
+		if err := json.Unmarshal(data, &parts); err != nil {
+			return err
+		}
+		var builder strings.Builder
+		for _, part := range parts {
+			builder.WriteString(string(part.Text))
+		}
+		*s = flexString(builder.String())
+	case '{':
+		return fmt.Errorf("无法将对象解析为字符串: %s", truncateForError(data))
+	default:
+		*s = flexString(data)
+	}
+	return nil
+}
+
+// flexJSON 容错JSON文本类型
+// 字符串取其内容，对象和数组保留原始JSON文本，null为空字符串；用于部分上游以对象而不是字符串返回的函数参数
+type flexJSON string
+
+// UnmarshalJSON 实现json.Unmarshaler接口
+func (s *flexJSON) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		*s = ""
+		return nil
+	}
+
+	if data[0] == '"' {
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		*s = flexJSON(value)
+		return nil
+	}
+
+	*s = flexJSON(data)
+	return nil
+}
+
+// flexInt 容错整数类型
+// 接受整数、浮点数、数字字符串和null
+type flexInt int64
+
+// UnmarshalJSON 实现json.Unmarshaler接口
+func (n *flexInt) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		*n = 0
+		return nil
+	}
+
+	text := string(data)
+	if data[0] == '"' {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		if text == "" {
+			*n = 0
+			return nil
+		}
+	}
+
+	if value, err := strconv.ParseInt(text, 10, 64); err == nil {
+		*n = flexInt(value)
+		return nil
+	}
+
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return fmt.Errorf("无法解析为整数: %s", truncateForError(data))
+	}
+	*n = flexInt(value)
+	return nil
+}
+
+// readResponseBody 读取响应体
+func readResponseBody(resp *http.Response) ([]byte, error) {
+	return io.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+}
+
+// invalidResponseError 上游返回的数据无法解析时的统一错误
+func invalidResponseError(providerName string, err error) *types.UnifiedResponse {
+	return &types.UnifiedResponse{
+		Error: &types.Error{
+			Code:    "invalid_response",
+			Message: fmt.Sprintf("%s 返回的响应格式无效: %v", providerName, err),
+			Type:    providerName + "_error",
+		},
+	}
+}
+
+// upstreamStatusError 上游返回非200状态码且没有可解析的错误信息时的统一错误
+func upstreamStatusError(providerName string, statusCode int, body []byte) *types.UnifiedResponse {
+	message := fmt.Sprintf("%s API返回错误状态码: %d", providerName, statusCode)
+	if text := strings.TrimSpace(string(body)); text != "" {
+		message += ", " + truncateForError([]byte(text))
+	}
+
+	return &types.UnifiedResponse{
+		Error: &types.Error{
+			Code:    strconv.Itoa(statusCode),
+			Message: message,
+			Type:    providerName + "_error",
+			Status:  statusCode,
+		},
+	}
+}
+
+// upstreamError 根据上游返回的错误信息构造统一错误，缺失的字段使用状态码和提供商名称补全
+func upstreamError(providerName string, statusCode int, code, message, errType string) *types.UnifiedResponse {
+	if code == "" {
+		code = strconv.Itoa(statusCode)
+	}
+	if errType == "" {
+		errType = providerName + "_error"
+	}
+	if message == "" {
+		message = fmt.Sprintf("%s API返回错误状态码: %d", providerName, statusCode)
+	}
+
+	return &types.UnifiedResponse{
+		Error: &types.Error{
+			Code:    code,
+			Message: message,
+			Type:    errType,
+			Status:  statusCode,
+		},
+	}
+}
+
+// truncateForError 截断过长的内容，避免错误信息过大
+func truncateForError(data []byte) string {
+	const limit = 512
+	if len(data) <= limit {
+		return string(data)
+	}
+	return string(data[:limit]) + "..."
+}
@@ -0,0 +1,127 @@
+package providers
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+)
+
+// flexSeeds 上游实际返回过的各种字段值
+var flexSeeds = []string{
+	`null`,
+	`""`,
+	`"你好"`,
+	`"你\"好\n"`,
+	`123`,
+	`-7`,
+	`12.0`,
+	`1e3`,
+	`"42"`,
+	`true`,
+	`[{"type":"text","text":"a"},{"type":"text","text":null}]`,
+	`[]`,
+	`{"city":"北京"}`,
+	`{"a":[1,2,{"b":null}]}`,
+	`"{\"city\":\"北京\"}"`,
+	`"`,
+	`[{"text":`,
+}
+
+// FuzzFlexString 任意JSON值都不能使flexString崩溃，合法的字符串必须原样解析
+func FuzzFlexString(f *testing.F) {
+	for _, seed := range flexSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var direct flexString
+		_ = direct.UnmarshalJSON(data)
+
+		var wrapped struct {
+			Value flexString `json:"value"`
+		}
+		if err := json.Unmarshal(wrapValue(data), &wrapped); err != nil {
+			return
+		}
+
+		var want string
+		if err := json.Unmarshal(data, &want); err == nil && string(wrapped.Value) != want {
+			t.Fatalf("flexString(%q) = %q, 期望 %q", data, wrapped.Value, want)
+		}
+	})
+}
+
+// FuzzFlexJSON 任意JSON值都不能使flexJSON崩溃，对象和数组必须保留为合法的JSON文本
+func FuzzFlexJSON(f *testing.F) {
+	for _, seed := range flexSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var direct flexJSON
+		_ = direct.UnmarshalJSON(data)
+
+		var wrapped struct {
+			Value flexJSON `json:"value"`
+		}
+		if err := json.Unmarshal(wrapValue(data), &wrapped); err != nil {
+			return
+		}
+
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return
+		}
+		switch v := value.(type) {
+		case string:
+			if string(wrapped.Value) != v {
+				t.Fatalf("flexJSON(%q) = %q, 期望 %q", data, wrapped.Value, v)
+			}
+		case map[string]interface{}, []interface{}:
+			if !json.Valid([]byte(wrapped.Value)) {
+				t.Fatalf("flexJSON(%q) = %q, 不是合法的JSON", data, wrapped.Value)
+			}
+		}
+	})
+}
+
+// FuzzFlexInt 任意JSON值都不能使flexInt崩溃，合法的整数和数字字符串必须解析为相同的值
+func FuzzFlexInt(f *testing.F) {
+	for _, seed := range flexSeeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var direct flexInt
+		_ = direct.UnmarshalJSON(data)
+
+		var wrapped struct {
+			Value flexInt `json:"value"`
+		}
+		if err := json.Unmarshal(wrapValue(data), &wrapped); err != nil {
+			return
+		}
+
+		want, err := strconv.ParseInt(string(data), 10, 64)
+		if err != nil {
+			var text string
+			if json.Unmarshal(data, &text) != nil {
+				return
+			}
+			if want, err = strconv.ParseInt(text, 10, 64); err != nil {
+				return
+			}
+		}
+		if int64(wrapped.Value) != want {
+			t.Fatalf("flexInt(%q) = %d, 期望 %d", data, wrapped.Value, want)
+		}
+	})
+}
+
+// wrapValue 将值放入对象中，与上游响应中字段的解析方式一致
+func wrapValue(data []byte) []byte {
+	wrapped := make([]byte, 0, len(data)+11)
+	wrapped = append(wrapped, `{"value":`...)
+	wrapped = append(wrapped, data...)
+	return append(wrapped, '}')
+}
@@ -1,14 +1,11 @@
 package providers
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
-	"time"
 
 	"github.com/heyanxiao/llm-bridge/pkg/types"
 )
@@ -74,12 +71,12 @@ func (p *DeepSeekProvider) ValidateRequest(req *types.UnifiedRequest) error {
 	}
 	
 	// 验证温度参数范围
-	if req.Parameters.Temperature < 0 || req.Parameters.Temperature > 2 {
+	if temperature := req.Parameters.Temperature; temperature != nil && (*temperature < 0 || *temperature > 2) {
 		return fmt.Errorf("温度参数必须在0-2之间")
 	}
 	
 	// 验证TopP参数范围
-	if req.Parameters.TopP < 0 || req.Parameters.TopP > 1 {
+	if topP := req.Parameters.TopP; topP != nil && (*topP < 0 || *topP > 1) {
 		return fmt.Errorf("TopP参数必须在0-1之间")
 	}
 	
@@ -88,6 +85,9 @@ func (p *DeepSeekProvider) ValidateRequest(req *types.UnifiedRequest) error {
 
 // Transform 将统一请求转换为DeepSeek格式（与OpenAI兼容）
 func (p *DeepSeekProvider) Transform(req *types.UnifiedRequest) ([]byte, error) {
+	// DeepSeek只支持json_object，schema以提示词的形式告知模型，同时满足提示词中必须包含json的要求
+	req = withFormatInstruction(req)
+	
 	// DeepSeek API与OpenAI格式兼容
 	// 如果没有指定模型，使用默认模型
 	model := req.Model
@@ -102,16 +102,16 @@ func (p *DeepSeekProvider) Transform(req *types.UnifiedRequest) ([]byte, error)
 	}
 	
 	// 添加可选参数
-	if req.Parameters.Temperature > 0 {
-		deepseekReq["temperature"] = req.Parameters.Temperature
+	if req.Parameters.Temperature != nil {
+		deepseekReq["temperature"] = *req.Parameters.Temperature
 	}
 	
 	if req.Parameters.MaxTokens > 0 {
 		deepseekReq["max_tokens"] = req.Parameters.MaxTokens
 	}
 	
-	if req.Parameters.TopP > 0 {
-		deepseekReq["top_p"] = req.Parameters.TopP
+	if req.Parameters.TopP != nil {
+		deepseekReq["top_p"] = *req.Parameters.TopP
 	}
 	
 	if req.Parameters.Stream {
@@ -130,6 +130,19 @@ func (p *DeepSeekProvider) Transform(req *types.UnifiedRequest) ([]byte, error)
 		deepseekReq["stop"] = req.Parameters.Stop
 	}
 	
+	if req.Parameters.N > 1 {
+		deepseekReq["n"] = req.Parameters.N
+	}
+	
+	if req.Parameters.Seed != nil {
+		deepseekReq["seed"] = *req.Parameters.Seed
+	}
+	
+	// 流式输出结束时返回token使用统计
+	if req.Parameters.Stream && req.Parameters.IncludeUsage {
+		deepseekReq["stream_options"] = map[string]interface{}{"include_usage": true}
+	}
+	
 	// 添加推理相关参数 (适用于deepseek-reasoner模型)
 	if req.Parameters.Reasoning {
 		deepseekReq["reasoning"] = true
@@ -139,6 +152,14 @@ func (p *DeepSeekProvider) Transform(req *types.UnifiedRequest) ([]byte, error)
 		deepseekReq["reasoning_effort"] = req.Parameters.ReasoningEffort
 	}
 	
+	// 工具调用
+	applyOpenAITools(deepseekReq, req)
+	
+	// 结构化输出
+	if req.Parameters.ResponseFormat.IsJSON() {
+		deepseekReq["response_format"] = map[string]string{"type": types.ResponseFormatJSONObject}
+	}
+	
 	return json.Marshal(deepseekReq)
 }
 
@@ -158,12 +179,10 @@ func (p *DeepSeekProvider) CallAPI(ctx context.Context, data []byte) (*http.Resp
 	}
 	
 	// 创建HTTP客户端
-	client := &http.Client{
-		Timeout: time.Duration(p.Timeout) * time.Second,
-	}
+	client := p.httpClient(isStreamRequest(data))
 	
 	// 发送请求
-	resp, err := client.Do(req)
+	resp, err := p.doWithRetry(client, req)
 	if err != nil {
 		return nil, fmt.Errorf("调用DeepSeek API失败: %w", err)
 	}
@@ -173,170 +192,10 @@ func (p *DeepSeekProvider) CallAPI(ctx context.Context, data []byte) (*http.Resp
 
 // ParseResponse 解析DeepSeek响应（与OpenAI格式兼容）
 func (p *DeepSeekProvider) ParseResponse(resp *http.Response) (*types.UnifiedResponse, error) {
-	defer resp.Body.Close()
-	
-	// 检查HTTP状态码
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("DeepSeek API返回错误状态码: %d", resp.StatusCode)
-	}
-	
-	// 解析响应JSON
-	var deepseekResp map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&deepseekResp); err != nil {
-		return nil, fmt.Errorf("解析DeepSeek响应失败: %w", err)
-	}
-	
-	// 检查错误
-	if errorData, exists := deepseekResp["error"]; exists {
-		errorMap := errorData.(map[string]interface{})
-		return &types.UnifiedResponse{
-			Error: &types.Error{
-				Code:    fmt.Sprintf("%v", errorMap["code"]),
-				Message: fmt.Sprintf("%v", errorMap["message"]),
-				Type:    "deepseek_error",
-			},
-		}, nil
-	}
-	
-	// 转换为统一响应格式
-	unifiedResp := &types.UnifiedResponse{
-		ID:      deepseekResp["id"].(string),
-		Object:  deepseekResp["object"].(string),
-		Created: int64(deepseekResp["created"].(float64)),
-		Model:   deepseekResp["model"].(string),
-	}
-	
-	// 解析choices
-	if choicesData, exists := deepseekResp["choices"]; exists {
-		choicesArray := choicesData.([]interface{})
-		choices := make([]types.Choice, len(choicesArray))
-		
-		for i, choiceData := range choicesArray {
-			choiceMap := choiceData.(map[string]interface{})
-			messageMap := choiceMap["message"].(map[string]interface{})
-			
-			choices[i] = types.Choice{
-				Index: int(choiceMap["index"].(float64)),
-				Message: types.Message{
-					Role:    messageMap["role"].(string),
-					Content: messageMap["content"].(string),
-				},
-				FinishReason: fmt.Sprintf("%v", choiceMap["finish_reason"]),
-			}
-		}
-		unifiedResp.Choices = choices
-	}
-	
-	// 解析usage
-	if usageData, exists := deepseekResp["usage"]; exists {
-		usageMap := usageData.(map[string]interface{})
-		unifiedResp.Usage = types.Usage{
-			PromptTokens:     int(usageMap["prompt_tokens"].(float64)),
-			CompletionTokens: int(usageMap["completion_tokens"].(float64)),
-			TotalTokens:      int(usageMap["total_tokens"].(float64)),
-		}
-	}
-	
-	return unifiedResp, nil
+	return parseOpenAICompatibleResponse(resp, p.Name)
 }
 
-// ParseStreamResponse 解析DeepSeek流式响应
+// ParseStreamResponse 解析DeepSeek流式响应（与OpenAI格式兼容）
 func (p *DeepSeekProvider) ParseStreamResponse(resp *http.Response) (<-chan *types.StreamResponse, error) {
-	responseChan := make(chan *types.StreamResponse)
-	
-	go func() {
-		defer close(responseChan)
-		defer resp.Body.Close()
-		
-		scanner := bufio.NewScanner(resp.Body)
-		for scanner.Scan() {
-			line := strings.TrimSpace(scanner.Text())
-			
-			// 跳过空行
-			if line == "" {
-				continue
-			}
-			
-			// 检查是否是SSE数据行
-			if strings.HasPrefix(line, "data: ") {
-				data := strings.TrimPrefix(line, "data: ")
-				
-				// 检查是否是结束标志
-				if data == "[DONE]" {
-					break
-				}
-				
-				// 解析JSON数据
-				var deepseekStreamResp map[string]interface{}
-				if err := json.Unmarshal([]byte(data), &deepseekStreamResp); err != nil {
-					continue // 跳过无效的JSON
-				}
-				
-				// 转换为统一格式
-				streamResp := p.convertDeepSeekStreamResponse(deepseekStreamResp)
-				if streamResp != nil {
-					responseChan <- streamResp
-				}
-			}
-		}
-	}()
-	
-	return responseChan, nil
+	return parseOpenAICompatibleStream(resp, p.Name)
 }
-
-// convertDeepSeekStreamResponse 转换DeepSeek流式响应为统一格式
-func (p *DeepSeekProvider) convertDeepSeekStreamResponse(data map[string]interface{}) *types.StreamResponse {
-	streamResp := &types.StreamResponse{
-		Object:  "chat.completion.chunk",
-		Model:   p.Name,
-		Created: time.Now().Unix(),
-	}
-	
-	// 提取ID
-	if id, ok := data["id"].(string); ok {
-		streamResp.ID = id
-	}
-	
-	// 提取模型
-	if model, ok := data["model"].(string); ok {
-		streamResp.Model = model
-	}
-	
-	// 提取选择
-	if choices, ok := data["choices"].([]interface{}); ok && len(choices) > 0 {
-		choice := choices[0].(map[string]interface{})
-		
-		streamChoice := types.StreamChoice{
-			Index: 0,
-		}
-		
-		// 提取增量内容
-		if delta, ok := choice["delta"].(map[string]interface{}); ok {
-			streamDelta := types.StreamDelta{}
-			
-			if role, ok := delta["role"].(string); ok {
-				streamDelta.Role = role
-			}
-			
-			if content, ok := delta["content"].(string); ok {
-				streamDelta.Content = content
-			}
-			
-			// 检查是否有推理内容 (适用于deepseek-reasoner模型)
-			if reasoning, ok := delta["reasoning"].(string); ok {
-				streamDelta.Reasoning = reasoning
-			}
-			
-			streamChoice.Delta = streamDelta
-		}
-		
-		// 提取完成原因
-		if finishReason, ok := choice["finish_reason"].(string); ok {
-			streamChoice.FinishReason = finishReason
-		}
-		
-		streamResp.Choices = []types.StreamChoice{streamChoice}
-	}
-	
-	return streamResp
-}
\ No newline at end of file
@@ -0,0 +1,78 @@
+package providers
+
+// FailoverConfig 跨提供商故障转移配置
+// chains的key为模型名称(或provider/model)，value为按顺序尝试的备选列表
+// 备选目标的写法与请求中只指定model时相同: provider/model、提供商名称、模型别名或只有一个提供商支持的模型名称
+//
+//	failover:
+//	  chains:
+//	    deepseek-chat: ["qwen/qwen-plus", "openai/gpt-4o"]
+//	    "*": ["deepseek/deepseek-chat"]
+type FailoverConfig struct {
+	Enabled     *bool               `yaml:"enabled"`      // 未配置时默认开启
+	Chains      map[string][]string `yaml:"chains"`       // 模型 -> 备选链
+	MaxAttempts int                 `yaml:"max_attempts"` // 最多尝试的目标数(包含首选)，0表示不限制
+}
+
+// FailoverTarget 故障转移目标
+type FailoverTarget struct {
+	Provider string
+	Model    string
+}
+
+// String 返回 provider/model 形式的字符串
+func (t FailoverTarget) String() string {
+	return t.Provider + "/" + t.Model
+}
+
+// IsEnabled 是否开启故障转移
+func (c *FailoverConfig) IsEnabled() bool {
+	if c == nil {
+		return false
+	}
+	return c.Enabled == nil || *c.Enabled
+}
+
+// HasChain 是否为目标显式配置了备选链(provider/model或model)，*对应的通用备选链不计入
+func (c *FailoverConfig) HasChain(target FailoverTarget) bool {
+	if !c.IsEnabled() {
+		return false
+	}
+	_, byTarget := c.Chains[target.String()]
+	_, byModel := c.Chains[target.Model]
+	return byTarget || byModel
+}
+
+// BuildChain 根据首选的提供商和模型生成完整的尝试顺序
+// 依次查找 provider/model、model 和 * 对应的备选链，无法解析、有歧义或重复的目标会被跳过
+// available用于按模型名称查找提供商时过滤未注册的提供商，与请求中只指定模型时的解析规则一致
+func (c *FailoverConfig) BuildChain(primary FailoverTarget, available func(provider string) bool) []FailoverTarget {
+	chain := []FailoverTarget{primary}
+	if !c.IsEnabled() {
+		return chain
+	}
+
+	var entries []string
+	for _, key := range []string{primary.String(), primary.Model, "*"} {
+		if list, exists := c.Chains[key]; exists {
+			entries = list
+			break
+		}
+	}
+
+	seen := map[string]bool{primary.String(): true}
+	for _, entry := range entries {
+		target, err := ResolveModel(entry, available)
+		if err != nil || seen[target.String()] {
+			continue
+		}
+		seen[target.String()] = true
+		chain = append(chain, target)
+
+		if c.MaxAttempts > 0 && len(chain) >= c.MaxAttempts {
+			break
+		}
+	}
+
+	return chain
+}
@@ -0,0 +1,113 @@
+package providers
+
+import (
+	"reflect"
+	"testing"
+)
+
+// registerTestModels 注册测试用的模型配置，测试结束后删除
+func registerTestModels(t *testing.T, configs map[string]ModelConfig) {
+	t.Helper()
+	for provider, config := range configs {
+		RegisterModels(provider, config)
+	}
+	t.Cleanup(func() {
+		modelsMu.Lock()
+		defer modelsMu.Unlock()
+		for provider := range configs {
+			delete(SupportedModels, provider)
+		}
+	})
+}
+
+// TestBuildChainResolvesLikeRequests 备选目标与请求中的model使用相同的解析规则
+func TestBuildChainResolvesLikeRequests(t *testing.T) {
+	registerTestModels(t, map[string]ModelConfig{
+		"test-a": {Models: []string{"shared-model", "only-a"}, DefaultModel: "only-a"},
+		"test-b": {Models: []string{"shared-model"}, DefaultModel: "shared-model"},
+	})
+
+	config := &FailoverConfig{Chains: map[string][]string{
+		"only-a": {"shared-model", "moonshot-128k", "test-b", "missing-model", "test-b/shared-model"},
+	}}
+	primary := FailoverTarget{Provider: "test-a", Model: "only-a"}
+
+	tests := []struct {
+		name      string
+		available func(string) bool
+		want      []FailoverTarget
+	}{
+		{
+			name:      "有歧义的模型名称被跳过",
+			available: nil,
+			want: []FailoverTarget{
+				primary,
+				{Provider: "moonshot", Model: "moonshot-128k"},
+				{Provider: "test-b", Model: "shared-model"},
+			},
+		},
+		{
+			name:      "只在已注册的提供商中查找模型名称",
+			available: func(provider string) bool { return provider != "test-a" && provider != "moonshot" },
+			want: []FailoverTarget{
+				primary,
+				{Provider: "test-b", Model: "shared-model"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := config.BuildChain(primary, tt.available)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildChain() = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBuildChainMaxAttempts 尝试的目标数包含首选目标
+func TestBuildChainMaxAttempts(t *testing.T) {
+	config := &FailoverConfig{
+		Chains:      map[string][]string{"*": {"qwen/qwen-plus", "deepseek", "openai/gpt-4o-2024-08-06"}},
+		MaxAttempts: 2,
+	}
+
+	got := config.BuildChain(FailoverTarget{Provider: "openai", Model: "gpt-3.5-turbo"}, nil)
+	want := []FailoverTarget{
+		{Provider: "openai", Model: "gpt-3.5-turbo"},
+		{Provider: "qwen", Model: "qwen-plus"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildChain() = %v, 期望 %v", got, want)
+	}
+}
+
+// TestHasChain 只有provider/model和model对应的备选链视为显式配置
+func TestHasChain(t *testing.T) {
+	config := &FailoverConfig{Chains: map[string][]string{
+		"openai/gpt-4o-2024-08-06": {"deepseek"},
+		"deepseek-chat":            {"qwen"},
+		"*":                        {"openai"},
+	}}
+
+	tests := []struct {
+		target FailoverTarget
+		want   bool
+	}{
+		{FailoverTarget{Provider: "openai", Model: "gpt-4o-2024-08-06"}, true},
+		{FailoverTarget{Provider: "deepseek", Model: "deepseek-chat"}, true},
+		{FailoverTarget{Provider: "qwen", Model: "qwen-plus"}, false},
+	}
+	for _, tt := range tests {
+		if got := config.HasChain(tt.target); got != tt.want {
+			t.Errorf("HasChain(%s) = %v, 期望 %v", tt.target, got, tt.want)
+		}
+	}
+
+	disabled := false
+	config.Enabled = &disabled
+	if config.HasChain(tests[0].target) {
+		t.Error("关闭故障转移后不应有备选链")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/heyanxiao/llm-bridge/pkg/types"
@@ -22,12 +23,12 @@ func NewGeminiProvider(config *GeminiConfig) *GeminiProvider {
 	if baseURL == "" {
 		baseURL = "https://generativelanguage.googleapis.com/v1beta"
 	}
-	
+
 	timeout := config.Timeout
 	if timeout == 0 {
 		timeout = 30
 	}
-	
+
 	retries := config.Retries
 	if retries == 0 {
 		retries = 3
@@ -39,7 +40,7 @@ func NewGeminiProvider(config *GeminiConfig) *GeminiProvider {
 			APIKey:  config.APIKey,
 			BaseURL: baseURL,
 			Headers: map[string]string{
-				"Content-Type":    "application/json",
+				"Content-Type":   "application/json",
 				"x-goog-api-key": config.APIKey,
 			},
 			Timeout: timeout,
@@ -58,72 +59,190 @@ func (p *GeminiProvider) ValidateRequest(req *types.UnifiedRequest) error {
 	if req.Model == "" {
 		return fmt.Errorf("模型名称不能为空")
 	}
-	
+
 	if len(req.Messages) == 0 {
 		return fmt.Errorf("消息列表不能为空")
 	}
-	
+
 	// 验证温度参数范围
-	if req.Parameters.Temperature < 0 || req.Parameters.Temperature > 2 {
+	if temperature := req.Parameters.Temperature; temperature != nil && (*temperature < 0 || *temperature > 2) {
 		return fmt.Errorf("温度参数必须在0-2之间")
 	}
-	
+
+	// Gemini的Schema不支持$ref，无法内联的schema(如递归引用)在发送前拒绝
+	if schema := req.Parameters.ResponseFormat.Schema(); schema != nil {
+		if _, err := geminiSchema(schema); err != nil {
+			return fmt.Errorf("response_format的schema无效: %w", err)
+		}
+	}
+	for _, tool := range req.Tools {
+		if len(tool.Function.Parameters) > 0 {
+			if _, err := geminiSchema(tool.Function.Parameters); err != nil {
+				return fmt.Errorf("函数 %s 的参数定义无效: %w", tool.Function.Name, err)
+			}
+		}
+	}
+
 	return nil
 }
 
 // Transform 将统一请求转换为Gemini格式
 func (p *GeminiProvider) Transform(req *types.UnifiedRequest) ([]byte, error) {
-	// Gemini使用简化的消息格式，只取最后一条用户消息
-	var text string
-	for i := len(req.Messages) - 1; i >= 0; i-- {
-		msg := req.Messages[i]
-		if msg.Role == "user" {
-			text = msg.Content
-			break
-		}
-	}
-	
-	if text == "" && len(req.Messages) > 0 {
-		text = req.Messages[len(req.Messages)-1].Content
-	}
-	
+	// 转换对话历史，system消息提取为systemInstruction
+	contents, systemInstruction := buildGeminiContents(req.Messages)
+
 	// 构建Gemini请求结构
 	geminiReq := map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{
-				"parts": []map[string]interface{}{
-					{"text": text},
-				},
-			},
-		},
+		"contents": contents,
 	}
-	
+
+	if systemInstruction != nil {
+		geminiReq["systemInstruction"] = systemInstruction
+	}
+
 	// 添加生成配置
 	generationConfig := make(map[string]interface{})
-	
-	if req.Parameters.Temperature > 0 {
-		generationConfig["temperature"] = req.Parameters.Temperature
+
+	if req.Parameters.Temperature != nil {
+		generationConfig["temperature"] = *req.Parameters.Temperature
 	}
-	
+
 	if req.Parameters.MaxTokens > 0 {
 		generationConfig["maxOutputTokens"] = req.Parameters.MaxTokens
 	}
-	
-	if req.Parameters.TopP > 0 {
-		generationConfig["topP"] = req.Parameters.TopP
+
+	if req.Parameters.TopP != nil {
+		generationConfig["topP"] = *req.Parameters.TopP
 	}
-	
+
 	if len(req.Parameters.Stop) > 0 {
 		generationConfig["stopSequences"] = req.Parameters.Stop
 	}
-	
+
+	if req.Parameters.N > 1 {
+		generationConfig["candidateCount"] = req.Parameters.N
+	}
+
+	// 结构化输出，schema移除Gemini不支持的关键字后作为responseSchema
+	if format := req.Parameters.ResponseFormat; format.IsJSON() {
+		generationConfig["responseMimeType"] = "application/json"
+		if schema := format.Schema(); schema != nil {
+			responseSchema, err := geminiSchema(schema)
+			if err != nil {
+				return nil, fmt.Errorf("response_format的schema无效: %w", err)
+			}
+			generationConfig["responseSchema"] = responseSchema
+		}
+	}
+
 	if len(generationConfig) > 0 {
 		geminiReq["generationConfig"] = generationConfig
 	}
-	
+
+	// 工具定义转换为functionDeclarations
+	if len(req.Tools) > 0 {
+		declarations := make([]map[string]interface{}, len(req.Tools))
+		for i, tool := range req.Tools {
+			declarations[i] = map[string]interface{}{"name": tool.Function.Name}
+			if tool.Function.Description != "" {
+				declarations[i]["description"] = tool.Function.Description
+			}
+			if len(tool.Function.Parameters) > 0 {
+				schema, err := geminiSchema(tool.Function.Parameters)
+				if err != nil {
+					return nil, fmt.Errorf("函数 %s 的参数定义无效: %w", tool.Function.Name, err)
+				}
+				declarations[i]["parameters"] = schema
+			}
+		}
+		geminiReq["tools"] = []map[string]interface{}{
+			{"functionDeclarations": declarations},
+		}
+
+		if req.ToolChoice != nil {
+			geminiReq["toolConfig"] = geminiToolConfig(req.ToolChoice)
+		}
+	}
+
+	// model和stream不属于Gemini请求体，由CallAPI提取后用于构建URL
+	model := req.Model
+	if model == "" || model == "gemini" {
+		model = GetDefaultModel("gemini")
+	}
+	geminiReq["model"] = model
+
+	if req.Parameters.Stream {
+		geminiReq["stream"] = true
+	}
+
 	return json.Marshal(geminiReq)
 }
 
+// buildGeminiContents 将统一消息列表转换为Gemini的contents和systemInstruction
+// Gemini只有user和model两种角色，且相邻的同角色消息需要合并为一条
+// 工具调用转换为functionCall片段，工具结果转换为user角色的functionResponse片段
+func buildGeminiContents(messages []types.Message) ([]map[string]interface{}, map[string]interface{}) {
+	contents := make([]map[string]interface{}, 0, len(messages))
+	var systemParts []map[string]interface{}
+	toolNames := toolCallNames(messages)
+
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			systemParts = append(systemParts, map[string]interface{}{"text": msg.Content})
+			continue
+		}
+
+		role := "user"
+		if msg.Role == "assistant" {
+			role = "model"
+		}
+
+		var parts []map[string]interface{}
+		if msg.Role == "tool" {
+			// functionResponse以函数名称关联调用
+			parts = append(parts, map[string]interface{}{
+				"functionResponse": map[string]interface{}{
+					"name":     toolNames[msg.ToolCallID],
+					"response": toolResult(msg.Content),
+				},
+			})
+		} else {
+			if msg.Content != "" || len(msg.ToolCalls) == 0 {
+				parts = append(parts, map[string]interface{}{"text": msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				parts = append(parts, map[string]interface{}{
+					"functionCall": map[string]interface{}{
+						"name": call.Function.Name,
+						"args": toolArguments(call.Function.Arguments),
+					},
+				})
+			}
+		}
+
+		// 与上一条消息角色相同时合并parts
+		if n := len(contents); n > 0 && contents[n-1]["role"] == role {
+			previous := contents[n-1]["parts"].([]map[string]interface{})
+			contents[n-1]["parts"] = append(previous, parts...)
+			continue
+		}
+
+		contents = append(contents, map[string]interface{}{
+			"role":  role,
+			"parts": parts,
+		})
+	}
+
+	var systemInstruction map[string]interface{}
+	if len(systemParts) > 0 {
+		systemInstruction = map[string]interface{}{
+			"parts": systemParts,
+		}
+	}
+
+	return contents, systemInstruction
+}
+
 // CallAPI 调用Gemini API
 func (p *GeminiProvider) CallAPI(ctx context.Context, data []byte) (*http.Response, error) {
 	// 从请求数据中解析模型名称
@@ -131,145 +250,527 @@ func (p *GeminiProvider) CallAPI(ctx context.Context, data []byte) (*http.Respon
 	if err := json.Unmarshal(data, &reqData); err != nil {
 		return nil, fmt.Errorf("解析请求数据失败: %w", err)
 	}
-	
+
 	// 获取模型名称，如果没有则使用默认值
 	model := GetDefaultModel("gemini")
-	if modelData, exists := reqData["model"]; exists {
-		model = modelData.(string)
+	if modelData, ok := reqData["model"].(string); ok && modelData != "" {
+		model = modelData
 	}
-	
-	// 删除model从请求体中，避免Gemini API错误
+
+	// 是否为流式请求
+	stream, _ := reqData["stream"].(bool)
+
+	// 删除model和stream从请求体中，避免Gemini API错误
 	delete(reqData, "model")
-	
+	delete(reqData, "stream")
+
 	// 重新序列化数据
 	cleanData, err := json.Marshal(reqData)
 	if err != nil {
 		return nil, fmt.Errorf("重新序列化请求数据失败: %w", err)
 	}
-	
+
 	// Gemini API URL格式: /v1beta/models/{model}:generateContent
+	// 流式请求使用 :streamGenerateContent?alt=sse 返回SSE格式
 	url := fmt.Sprintf("%s/models/%s:generateContent", p.BaseURL, model)
-	
+	if stream {
+		url = fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse", p.BaseURL, model)
+	}
+
 	// 创建HTTP请求
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(cleanData))
 	if err != nil {
 		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
 	}
-	
+
 	// 设置请求头
 	for key, value := range p.Headers {
 		req.Header.Set(key, value)
 	}
-	
+
 	// 创建HTTP客户端
-	client := &http.Client{
-		Timeout: time.Duration(p.Timeout) * time.Second,
-	}
-	
+	client := p.httpClient(stream)
+
 	// 发送请求
-	resp, err := client.Do(req)
+	resp, err := p.doWithRetry(client, req)
 	if err != nil {
 		return nil, fmt.Errorf("调用Gemini API失败: %w", err)
 	}
-	
+
 	return resp, nil
 }
 
+// geminiResponse Gemini GenerateContentResponse结构，同时用于非流式响应和流式分片
+type geminiResponse struct {
+	Candidates    []geminiCandidate `json:"candidates"`
+	UsageMetadata *geminiUsage      `json:"usageMetadata"`
+	ModelVersion  flexString        `json:"modelVersion"`
+	ResponseID    flexString        `json:"responseId"`
+	Error         *geminiError      `json:"error"`
+	// 提示词被拦截时没有candidates，拦截原因在promptFeedback中
+	PromptFeedback *struct {
+		BlockReason        flexString `json:"blockReason"`
+		BlockReasonMessage flexString `json:"blockReasonMessage"`
+	} `json:"promptFeedback"`
+}
+
+// blockedError 提示词被拦截时返回带有拦截原因的错误，未被拦截时返回nil
+func (r *geminiResponse) blockedError(providerName string) *types.Error {
+	if len(r.Candidates) > 0 || r.PromptFeedback == nil || r.PromptFeedback.BlockReason == "" {
+		return nil
+	}
+
+	message := fmt.Sprintf("%s 拦截了请求: %s", providerName, r.PromptFeedback.BlockReason)
+	if r.PromptFeedback.BlockReasonMessage != "" {
+		message += ", " + string(r.PromptFeedback.BlockReasonMessage)
+	}
+	return &types.Error{
+		Code:    "prompt_blocked",
+		Message: message,
+		Type:    providerName + "_error",
+		Status:  http.StatusBadRequest,
+	}
+}
+
+// geminiCandidate Gemini候选结果
+type geminiCandidate struct {
+	Index   flexInt `json:"index"`
+	Content struct {
+		Parts []geminiPart `json:"parts"`
+	} `json:"content"`
+	FinishReason flexString `json:"finishReason"`
+}
+
+// geminiPart Gemini内容片段，thought为true时是思考过程
+type geminiPart struct {
+	Text         flexString `json:"text"`
+	Thought      bool       `json:"thought"`
+	FunctionCall *struct {
+		ID   flexString      `json:"id"`
+		Name flexString      `json:"name"`
+		Args json.RawMessage `json:"args"`
+	} `json:"functionCall"`
+}
+
+// geminiUsage Gemini的usageMetadata结构
+type geminiUsage struct {
+	PromptTokenCount     flexInt `json:"promptTokenCount"`
+	CandidatesTokenCount flexInt `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   flexInt `json:"thoughtsTokenCount"`
+	TotalTokenCount      flexInt `json:"totalTokenCount"`
+}
+
+// geminiError Gemini错误结构
+type geminiError struct {
+	Code    flexString `json:"code"`
+	Message flexString `json:"message"`
+	Status  flexString `json:"status"`
+}
+
+// text 拼接候选结果中的正文和思考过程
+func (c *geminiCandidate) text() (content, reasoning string) {
+	var contentBuilder, reasoningBuilder strings.Builder
+	for _, part := range c.Content.Parts {
+		if part.Thought {
+			reasoningBuilder.WriteString(string(part.Text))
+		} else {
+			contentBuilder.WriteString(string(part.Text))
+		}
+	}
+	return contentBuilder.String(), reasoningBuilder.String()
+}
+
+// toolCalls 提取候选结果中的函数调用，Gemini未返回调用ID时生成ID
+func (c *geminiCandidate) toolCalls() []types.ToolCall {
+	var toolCalls []types.ToolCall
+	for _, part := range c.Content.Parts {
+		if part.FunctionCall == nil {
+			continue
+		}
+
+		id := string(part.FunctionCall.ID)
+		if id == "" {
+			id = generateToolCallID()
+		}
+		toolCalls = append(toolCalls, types.ToolCall{
+			ID:   id,
+			Type: "function",
+			Function: types.FunctionCall{
+				Name:      string(part.FunctionCall.Name),
+				Arguments: toolArgumentsText(part.FunctionCall.Args),
+			},
+		})
+	}
+	return toolCalls
+}
+
+// toUsage 将Gemini的usageMetadata转换为统一的Usage
+func (u *geminiUsage) toUsage() types.Usage {
+	// 思考token也按输出token计费
+	completionTokens := u.CandidatesTokenCount + u.ThoughtsTokenCount
+	totalTokens := u.TotalTokenCount
+	if totalTokens == 0 {
+		totalTokens = u.PromptTokenCount + completionTokens
+	}
+
+	return types.Usage{
+		PromptTokens:     int(u.PromptTokenCount),
+		CompletionTokens: int(completionTokens),
+		TotalTokens:      int(totalTokens),
+	}
+}
+
 // ParseResponse 解析Gemini响应
 func (p *GeminiProvider) ParseResponse(resp *http.Response) (*types.UnifiedResponse, error) {
 	defer resp.Body.Close()
-	
-	// 检查HTTP状态码
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Gemini API返回错误状态码: %d", resp.StatusCode)
+
+	body, err := readResponseBody(resp)
+	if err != nil {
+		return nil, fmt.Errorf("读取Gemini响应失败: %w", err)
 	}
-	
+
 	// 解析响应JSON
-	var geminiResp map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&geminiResp); err != nil {
-		return nil, fmt.Errorf("解析Gemini响应失败: %w", err)
+	var geminiResp geminiResponse
+	if err := json.Unmarshal(body, &geminiResp); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return upstreamStatusError(p.Name, resp.StatusCode, body), nil
+		}
+		return invalidResponseError(p.Name, err), nil
 	}
-	
+
 	// 检查错误
-	if errorData, exists := geminiResp["error"]; exists {
-		errorMap := errorData.(map[string]interface{})
-		return &types.UnifiedResponse{
-			Error: &types.Error{
-				Code:    fmt.Sprintf("%v", errorMap["code"]),
-				Message: fmt.Sprintf("%v", errorMap["message"]),
-				Type:    "gemini_error",
-			},
-		}, nil
+	if geminiResp.Error != nil {
+		return upstreamError(p.Name, resp.StatusCode, string(geminiResp.Error.Code), string(geminiResp.Error.Message), "gemini_error"), nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return upstreamStatusError(p.Name, resp.StatusCode, body), nil
+	}
+
+	// 提示词被拦截或没有任何候选结果
+	if blocked := geminiResp.blockedError(p.Name); blocked != nil {
+		return &types.UnifiedResponse{Error: blocked}, nil
 	}
-	
+	if len(geminiResp.Candidates) == 0 {
+		return invalidResponseError(p.Name, fmt.Errorf("响应中没有candidates")), nil
+	}
+
 	// 转换为统一响应格式
 	unifiedResp := &types.UnifiedResponse{
 		ID:      fmt.Sprintf("gemini-%d", time.Now().Unix()),
 		Object:  "chat.completion",
 		Created: time.Now().Unix(),
-		Model:   "gemini-pro", // 暂时返回固定值，待后续优化
+		Model:   geminiModelFromResponse(resp),
+	}
+
+	// modelVersion为实际响应的模型版本
+	if geminiResp.ModelVersion != "" {
+		unifiedResp.Model = string(geminiResp.ModelVersion)
 	}
-	
+
+	if geminiResp.ResponseID != "" {
+		unifiedResp.ID = string(geminiResp.ResponseID)
+	}
+
 	// 解析candidates
-	if candidatesData, exists := geminiResp["candidates"]; exists {
-		candidatesArray := candidatesData.([]interface{})
-		choices := make([]types.Choice, len(candidatesArray))
-		
-		for i, candidateData := range candidatesArray {
-			candidateMap := candidateData.(map[string]interface{})
-			
-			var content string
-			if contentData, exists := candidateMap["content"]; exists {
-				contentMap := contentData.(map[string]interface{})
-				if partsData, exists := contentMap["parts"]; exists {
-					partsArray := partsData.([]interface{})
-					if len(partsArray) > 0 {
-						partMap := partsArray[0].(map[string]interface{})
-						if text, exists := partMap["text"]; exists {
-							content = text.(string)
-						}
-					}
-				}
-			}
-			
-			finishReason := "stop"
-			if reasonData, exists := candidateMap["finishReason"]; exists {
-				finishReason = reasonData.(string)
-			}
-			
-			choices[i] = types.Choice{
-				Index: i,
-				Message: types.Message{
-					Role:    "assistant",
-					Content: content,
-				},
-				FinishReason: finishReason,
-			}
+	choices := make([]types.Choice, len(geminiResp.Candidates))
+	for i, candidate := range geminiResp.Candidates {
+		content, _ := candidate.text()
+		toolCalls := candidate.toolCalls()
+
+		finishReason := "stop"
+		if candidate.FinishReason != "" {
+			finishReason = mapGeminiFinishReason(string(candidate.FinishReason))
+		}
+
+		// Gemini调用函数时finishReason仍为STOP
+		if finishReason == "stop" && len(toolCalls) > 0 {
+			finishReason = "tool_calls"
+		}
+
+		choices[i] = types.Choice{
+			Index: i,
+			Message: types.Message{
+				Role:      "assistant",
+				Content:   content,
+				ToolCalls: toolCalls,
+			},
+			FinishReason: finishReason,
 		}
-		unifiedResp.Choices = choices
 	}
-	
-	// Gemini通常不返回详细的token使用统计
-	unifiedResp.Usage = types.Usage{
-		PromptTokens:     0, // Gemini API可能不提供此信息
-		CompletionTokens: 0,
-		TotalTokens:      0,
+	unifiedResp.Choices = choices
+
+	// 解析usageMetadata
+	if geminiResp.UsageMetadata != nil {
+		unifiedResp.Usage = geminiResp.UsageMetadata.toUsage()
 	}
-	
+
 	return unifiedResp, nil
 }
 
+// geminiModelFromResponse 从请求URL中提取模型名称
+// URL格式: /v1beta/models/{model}:generateContent
+func geminiModelFromResponse(resp *http.Response) string {
+	if resp.Request == nil || resp.Request.URL == nil {
+		return GetDefaultModel("gemini")
+	}
+
+	path := resp.Request.URL.Path
+	idx := strings.LastIndex(path, "/models/")
+	if idx < 0 {
+		return GetDefaultModel("gemini")
+	}
+
+	model, _, _ := strings.Cut(path[idx+len("/models/"):], ":")
+	if model == "" {
+		return GetDefaultModel("gemini")
+	}
+	return model
+}
+
 // ParseStreamResponse 解析Gemini流式响应
+// streamGenerateContent?alt=sse 的每个data负载都是一个完整的GenerateContentResponse
 func (p *GeminiProvider) ParseStreamResponse(resp *http.Response) (<-chan *types.StreamResponse, error) {
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("Gemini API返回错误状态码: %d, %s", resp.StatusCode, readErrorBody(resp))
+	}
+
 	responseChan := make(chan *types.StreamResponse)
-	
+
 	go func() {
 		defer close(responseChan)
 		defer resp.Body.Close()
-		
-		// TODO: 实现Gemini流式响应解析
-		// Gemini的流式API格式与OpenAI不同，需要专门处理
+
+		id := fmt.Sprintf("gemini-%d", time.Now().Unix())
+		model := geminiModelFromResponse(resp)
+		roleSent := make(map[int]bool)  // 已输出角色的候选结果
+		toolCounts := make(map[int]int) // 每个候选结果已输出的工具调用数
+
+		var progress StreamProgress
+		decoder := NewSSEDecoder(resp.Body)
+		for {
+			event, err := decoder.Next()
+			if err != nil {
+				// Gemini没有结束标志，所有候选结果都收到finishReason才视为正常结束
+				if interrupted := progress.interruption(p.Name, err); interrupted != nil {
+					responseChan <- interrupted
+				}
+				return
+			}
+
+			var chunk geminiResponse
+			if err := json.Unmarshal([]byte(event.Data), &chunk); err != nil {
+				continue // 跳过无效的JSON
+			}
+
+			streamResp := &types.StreamResponse{
+				ID:      id,
+				Object:  "chat.completion.chunk",
+				Created: time.Now().Unix(),
+				Model:   model,
+			}
+
+			if chunk.ResponseID != "" {
+				streamResp.ID = string(chunk.ResponseID)
+			}
+
+			if chunk.ModelVersion != "" {
+				streamResp.Model = string(chunk.ModelVersion)
+			}
+
+			// 流中途返回的错误
+			if chunk.Error != nil {
+				streamResp.Error = upstreamError(p.Name, http.StatusOK, string(chunk.Error.Code), string(chunk.Error.Message), "gemini_error").Error
+				responseChan <- streamResp
+				return
+			}
+
+			// 提示词被拦截
+			if blocked := chunk.blockedError(p.Name); blocked != nil {
+				streamResp.Error = blocked
+				responseChan <- streamResp
+				return
+			}
+
+			// 指定candidateCount时每个分片可能包含多个候选结果，按index分别转换
+			candidates := chunk.Candidates
+			if len(candidates) == 0 {
+				candidates = []geminiCandidate{{}}
+			}
+			finished := false
+			for _, candidate := range candidates {
+				index := int(candidate.Index)
+				streamChoice := types.StreamChoice{Index: index}
+				if !roleSent[index] {
+					streamChoice.Delta.Role = "assistant"
+					roleSent[index] = true
+				}
+
+				streamChoice.Delta.Content, streamChoice.Delta.Reasoning = candidate.text()
+				streamChoice.FinishReason = mapGeminiFinishReason(string(candidate.FinishReason))
+
+				// Gemini的函数调用不分片，每个调用作为一个完整的增量输出
+				toolCount := toolCounts[index]
+				streamChoice.Delta.ToolCalls = completeToolCallDeltas(candidate.toolCalls(), &toolCount)
+				toolCounts[index] = toolCount
+				if streamChoice.FinishReason == "stop" && toolCount > 0 {
+					streamChoice.FinishReason = "tool_calls"
+				}
+
+				finished = finished || streamChoice.FinishReason != ""
+				streamResp.Choices = append(streamResp.Choices, streamChoice)
+			}
+
+			// usageMetadata在每个分片中都是累计值，只在带有完成原因的分片中上报
+			if finished && chunk.UsageMetadata != nil {
+				usage := chunk.UsageMetadata.toUsage()
+				streamResp.Usage = &usage
+			}
+
+			progress.Observe(streamResp)
+			responseChan <- streamResp
+		}
 	}()
-	
+
 	return responseChan, nil
-}
\ No newline at end of file
+}
+
+// mapGeminiFinishReason 将Gemini的finishReason映射为统一的finish_reason
+func mapGeminiFinishReason(reason string) string {
+	switch reason {
+	case "STOP":
+		return "stop"
+	case "MAX_TOKENS":
+		return "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return "content_filter"
+	case "", "FINISH_REASON_UNSPECIFIED":
+		return ""
+	default:
+		return strings.ToLower(reason)
+	}
+}
+
+// geminiToolConfig 将工具选择策略转换为Gemini的toolConfig，required和指定函数对应ANY模式
+func geminiToolConfig(choice *types.ToolChoice) map[string]interface{} {
+	config := map[string]interface{}{"mode": "AUTO"}
+	switch choice.Mode {
+	case types.ToolChoiceNone:
+		config["mode"] = "NONE"
+	case types.ToolChoiceRequired:
+		config["mode"] = "ANY"
+	case types.ToolChoiceFunction:
+		config["mode"] = "ANY"
+		config["allowedFunctionNames"] = []string{choice.Function}
+	}
+	return map[string]interface{}{"functionCallingConfig": config}
+}
+
+// geminiUnsupportedSchemaKeys Gemini的Schema(OpenAPI子集)不支持的JSON Schema关键字，出现时请求会被拒绝
+// $defs和definitions中的定义在移除前已被内联到引用它们的$ref处
+var geminiUnsupportedSchemaKeys = map[string]bool{
+	"$schema":              true,
+	"$id":                  true,
+	"$comment":             true,
+	"$defs":                true,
+	"definitions":          true,
+	"additionalProperties": true,
+	"examples":             true,
+}
+
+// geminiSchema 内联文档内的$ref并移除JSON Schema中Gemini不支持的关键字
+// Gemini的Schema不支持$ref，递归引用无法展开，返回错误
+func geminiSchema(schema json.RawMessage) (interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal(schema, &value); err != nil {
+		return nil, err
+	}
+
+	cleaner := &geminiSchemaCleaner{root: value, expanding: make(map[string]bool)}
+	return cleaner.clean(value, 0)
+}
+
+// geminiSchemaCleaner 展开$ref时记录正在展开的引用，用于发现递归引用
+type geminiSchemaCleaner struct {
+	root      interface{}
+	expanding map[string]bool
+}
+
+// clean 递归处理schema对象，properties中的键是属性名称，不做过滤；depth为$ref的嵌套深度
+func (g *geminiSchemaCleaner) clean(value interface{}, depth int) (interface{}, error) {
+	schema, ok := value.(map[string]interface{})
+	if !ok {
+		return value, nil
+	}
+
+	cleaned := make(map[string]interface{}, len(schema))
+	if ref, ok := schema["$ref"].(string); ok {
+		inlined, err := g.inline(ref, depth)
+		if err != nil {
+			return nil, err
+		}
+		// 与$ref并列的关键字(如description)覆盖引用目标中的同名关键字
+		if target, ok := inlined.(map[string]interface{}); ok {
+			for key, child := range target {
+				cleaned[key] = child
+			}
+		}
+	}
+
+	for key, child := range schema {
+		if key == "$ref" || geminiUnsupportedSchemaKeys[key] {
+			continue
+		}
+
+		var err error
+		switch key {
+		case "properties":
+			if properties, ok := child.(map[string]interface{}); ok {
+				cleanedProperties := make(map[string]interface{}, len(properties))
+				for name, property := range properties {
+					if cleanedProperties[name], err = g.clean(property, depth); err != nil {
+						return nil, err
+					}
+				}
+				child = cleanedProperties
+			}
+		case "items":
+			child, err = g.clean(child, depth)
+		case "anyOf", "oneOf", "allOf":
+			if list, ok := child.([]interface{}); ok {
+				cleanedList := make([]interface{}, len(list))
+				for i, item := range list {
+					if cleanedList[i], err = g.clean(item, depth); err != nil {
+						return nil, err
+					}
+				}
+				child = cleanedList
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		cleaned[key] = child
+	}
+	return cleaned, nil
+}
+
+// inline 展开$ref引用的定义
+func (g *geminiSchemaCleaner) inline(ref string, depth int) (interface{}, error) {
+	if g.expanding[ref] {
+		return nil, fmt.Errorf("$ref %s 是递归引用，Gemini不支持递归的schema", ref)
+	}
+	if depth >= maxSchemaRefDepth {
+		return nil, fmt.Errorf("$ref %s 嵌套过深", ref)
+	}
+
+	target, err := resolveSchemaRef(g.root, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	g.expanding[ref] = true
+	defer delete(g.expanding, ref)
+	return g.clean(target, depth+1)
+}
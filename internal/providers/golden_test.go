@@ -0,0 +1,220 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// 运行 go test ./internal/providers -run TestGolden -update 重新生成golden文件
+var updateGolden = flag.Bool("update", false, "重新生成golden文件")
+
+// goldenProviders 回放录制响应的适配器，path为录制时的请求路径(Gemini从中提取模型名称)
+var goldenProviders = []struct {
+	name    string
+	path    string
+	adapter func() ProviderAdapter
+}{
+	{"openai", "/v1/chat/completions", func() ProviderAdapter { return NewOpenAIProvider(&OpenAIConfig{}) }},
+	{"deepseek", "/v1/chat/completions", func() ProviderAdapter { return NewDeepSeekProvider(&DeepSeekConfig{}) }},
+	{"moonshot", "/v1/chat/completions", func() ProviderAdapter { return NewMoonshotProvider(&MoonshotConfig{}) }},
+	{"qwen", "/api/v1/services/aigc/text-generation/generation", func() ProviderAdapter { return NewQwenProvider(&QwenConfig{}) }},
+	{"claude", "/v1/messages", func() ProviderAdapter { return NewClaudeProvider(&ClaudeConfig{}) }},
+	{"gemini", "/v1beta/models/gemini-2.0-flash:generateContent", func() ProviderAdapter { return NewGeminiProvider(&GeminiConfig{}) }},
+	{"ollama", "/api/chat", func() ProviderAdapter { return NewOllamaProvider(&OllamaConfig{}) }},
+}
+
+// goldenResult 解析结果，序列化后与golden文件比较
+type goldenResult struct {
+	Error    string                  `json:"error,omitempty"`
+	Response *types.UnifiedResponse  `json:"response,omitempty"`
+	Chunks   []*types.StreamResponse `json:"chunks,omitempty"`
+}
+
+// 解析时生成的ID，比较前替换为固定值
+var (
+	generatedIDPattern   = regexp.MustCompile(`"(gemini|ollama|qwen)-\d+"`)
+	generatedCallPattern = regexp.MustCompile(`"call_[0-9a-f]{24}"`)
+)
+
+// TestGolden 回放testdata/<提供商>/*.http中录制的上游响应，比较解析结果与同名的.golden文件
+// 文件名以stream开头的按流式响应解析
+func TestGolden(t *testing.T) {
+	for _, provider := range goldenProviders {
+		files, err := filepath.Glob(filepath.Join("testdata", provider.name, "*.http"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) == 0 {
+			t.Fatalf("testdata/%s 中没有录制的响应", provider.name)
+		}
+
+		for _, file := range files {
+			name := strings.TrimSuffix(filepath.Base(file), ".http")
+			t.Run(provider.name+"/"+name, func(t *testing.T) {
+				resp := loadRecordedResponse(t, file, provider.path)
+				result := replay(t, provider.adapter(), resp, strings.HasPrefix(name, "stream"))
+				checkStreamEnding(t, name, result)
+
+				got := normalizeGolden(t, result)
+				goldenFile := strings.TrimSuffix(file, ".http") + ".golden"
+				if *updateGolden {
+					if err := os.WriteFile(goldenFile, got, 0o644); err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+
+				want, err := os.ReadFile(goldenFile)
+				if err != nil {
+					t.Fatalf("读取golden文件失败(使用 -update 生成): %v", err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("解析结果与 %s 不一致\n实际:\n%s\n期望:\n%s", goldenFile, got, want)
+				}
+			})
+		}
+	}
+}
+
+// checkStreamEnding 被截断的流必须以stream_interrupted错误结束，完整的流不能出现该错误
+// 与golden文件比较之前检查，避免用 -update 把静默截断录制为期望结果
+func checkStreamEnding(t *testing.T, name string, result goldenResult) {
+	t.Helper()
+	if !strings.HasPrefix(name, "stream") || len(result.Chunks) == 0 {
+		return
+	}
+
+	last := result.Chunks[len(result.Chunks)-1]
+	interrupted := last.Error != nil && last.Error.Code == "stream_interrupted"
+	if truncated := strings.HasSuffix(name, "_truncated"); interrupted != truncated {
+		t.Errorf("最后一个分片是否为stream_interrupted错误: %v, 期望 %v", interrupted, truncated)
+	}
+}
+
+// loadRecordedResponse 读取录制的HTTP响应(状态行、响应头和响应体)
+func loadRecordedResponse(t testing.TB, file, path string) *http.Response {
+	t.Helper()
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := recordedResponse(data, path)
+	if err != nil {
+		t.Fatalf("解析 %s 失败: %v", file, err)
+	}
+	return resp
+}
+
+// recordedResponse 将录制的数据还原为http.Response，响应体读到数据末尾为止
+func recordedResponse(data []byte, path string) (*http.Response, error) {
+	req := &http.Request{Method: http.MethodPost, URL: &url.URL{Path: path}}
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+}
+
+// replay 调用适配器解析响应，流式响应会读取到通道关闭
+func replay(t testing.TB, adapter ProviderAdapter, resp *http.Response, stream bool) goldenResult {
+	t.Helper()
+
+	if !stream {
+		unified, err := adapter.ParseResponse(resp)
+		if err != nil {
+			return goldenResult{Error: err.Error()}
+		}
+		if unified == nil {
+			t.Fatal("ParseResponse 没有返回错误，也没有返回响应")
+		}
+		return goldenResult{Response: unified}
+	}
+
+	ch, err := adapter.ParseStreamResponse(resp)
+	if err != nil {
+		return goldenResult{Error: err.Error()}
+	}
+
+	var chunks []*types.StreamResponse
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case chunk, ok := <-ch:
+			if !ok {
+				return goldenResult{Chunks: chunks}
+			}
+			chunks = append(chunks, chunk)
+		case <-timeout:
+			t.Fatal("流式响应没有结束")
+		}
+	}
+}
+
+// normalizeGolden 去掉解析时生成的时间戳和ID后格式化为JSON
+func normalizeGolden(t testing.TB, result goldenResult) []byte {
+	t.Helper()
+
+	if result.Response != nil {
+		result.Response.Created = 0
+	}
+	for _, chunk := range result.Chunks {
+		chunk.Created = 0
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = generatedIDPattern.ReplaceAll(data, []byte(`"$1-0"`))
+	data = generatedCallPattern.ReplaceAll(data, []byte(`"call_generated"`))
+	return append(data, '\n')
+}
+
+// FuzzParseResponse 以录制的响应体为种子，任意响应体都不能使解析崩溃或卡住
+func FuzzParseResponse(f *testing.F) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*", "*.http"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		resp, err := recordedResponse(data, "/")
+		if err != nil {
+			f.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		f.Add(resp.StatusCode, body)
+	}
+
+	f.Fuzz(func(t *testing.T, status int, body []byte) {
+		if status < 100 || status > 599 {
+			status = http.StatusOK
+		}
+
+		for _, provider := range goldenProviders {
+			for _, stream := range []bool{false, true} {
+				resp := &http.Response{
+					StatusCode: status,
+					Header:     http.Header{},
+					Body:       io.NopCloser(bytes.NewReader(body)),
+					Request:    &http.Request{Method: http.MethodPost, URL: &url.URL{Path: provider.path}},
+				}
+				replay(t, provider.adapter(), resp, stream)
+			}
+		}
+	})
+}
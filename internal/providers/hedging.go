@@ -0,0 +1,30 @@
+package providers
+
+import "time"
+
+// 未配置时发起对冲请求前的等待时间
+const defaultHedgeDelay = 500 * time.Millisecond
+
+// HedgingConfig 对冲请求配置
+// 首选提供商在delay_ms内没有返回(流式请求为没有返回第一个分片)时，向备选提供商发起相同的请求，
+// 使用先完成的结果并取消另一个请求，用成本换取更低的尾延迟
+type HedgingConfig struct {
+	Enabled bool `yaml:"enabled"`  // 是否默认开启，请求可通过routing.hedge单独开启或关闭
+	DelayMs int  `yaml:"delay_ms"` // 发起对冲请求前的等待时间(毫秒)
+}
+
+// IsEnabled 判断请求是否使用对冲，override为请求中的设置
+func (c *HedgingConfig) IsEnabled(override *bool) bool {
+	if override != nil {
+		return *override
+	}
+	return c != nil && c.Enabled
+}
+
+// Delay 发起对冲请求前的等待时间
+func (c *HedgingConfig) Delay() time.Duration {
+	if c == nil || c.DelayMs <= 0 {
+		return defaultHedgeDelay
+	}
+	return time.Duration(c.DelayMs) * time.Millisecond
+}
@@ -0,0 +1,328 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// $ref的最大嵌套深度，防止循环引用
+const maxSchemaRefDepth = 32
+
+// schemaValidator 最小的JSON Schema校验器，只实现结构化输出中常用的关键字:
+// type、enum、const、properties、required、additionalProperties、items、minItems、maxItems、
+// minLength、maxLength、pattern、minimum、maximum、exclusiveMinimum、exclusiveMaximum、
+// anyOf、oneOf、allOf、not 和文档内的$ref，不认识的关键字被忽略
+type schemaValidator struct {
+	root interface{}
+}
+
+// validateJSONSchema 校验JSON值是否符合schema，错误信息中带有不符合的位置
+func validateJSONSchema(schema json.RawMessage, value interface{}) error {
+	var root interface{}
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return fmt.Errorf("JSON Schema无效: %w", err)
+	}
+
+	v := &schemaValidator{root: root}
+	return v.validate(root, value, "$", 0)
+}
+
+// validate 校验value是否符合schema，path为value在输出中的位置，depth为$ref的嵌套深度
+func (v *schemaValidator) validate(schema interface{}, value interface{}, path string, depth int) error {
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			return fmt.Errorf("%s: 不允许出现", path)
+		}
+		return nil
+	case map[string]interface{}:
+		return v.validateObject(s, value, path, depth)
+	default:
+		return nil
+	}
+}
+
+// validateObject 按schema对象中的关键字依次校验
+func (v *schemaValidator) validateObject(schema map[string]interface{}, value interface{}, path string, depth int) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		if depth >= maxSchemaRefDepth {
+			return fmt.Errorf("%s: $ref嵌套过深", path)
+		}
+		target, err := v.resolveRef(ref)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := v.validate(target, value, path, depth+1); err != nil {
+			return err
+		}
+	}
+
+	if names, ok := schemaTypes(schema["type"]); ok && !matchesAnyType(names, value) {
+		return fmt.Errorf("%s: 类型应为%s，实际为%s", path, strings.Join(names, "或"), jsonType(value))
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && !containsValue(enum, value) {
+		return fmt.Errorf("%s: 取值不在枚举范围内", path)
+	}
+	if constant, ok := schema["const"]; ok && !reflect.DeepEqual(constant, value) {
+		return fmt.Errorf("%s: 取值应为 %v", path, constant)
+	}
+
+	var err error
+	switch val := value.(type) {
+	case map[string]interface{}:
+		err = v.validateProperties(schema, val, path, depth)
+	case []interface{}:
+		err = v.validateItems(schema, val, path, depth)
+	case string:
+		err = validateString(schema, val, path)
+	case float64:
+		err = validateNumber(schema, val, path)
+	}
+	if err != nil {
+		return err
+	}
+
+	return v.validateCombinators(schema, value, path, depth)
+}
+
+// validateProperties 校验对象的属性
+func (v *schemaValidator) validateProperties(schema map[string]interface{}, value map[string]interface{}, path string, depth int) error {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if key, ok := name.(string); ok {
+				if _, exists := value[key]; !exists {
+					return fmt.Errorf("%s: 缺少必需的属性 %s", path, key)
+				}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	additional, hasAdditional := schema["additionalProperties"]
+
+	// 按属性名称排序，保证错误信息稳定
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := path + "." + key
+		if property, ok := properties[key]; ok {
+			if err := v.validate(property, value[key], childPath, depth); err != nil {
+				return err
+			}
+			continue
+		}
+		if !hasAdditional {
+			continue
+		}
+		if allowed, ok := additional.(bool); ok && !allowed {
+			return fmt.Errorf("%s: 不允许的属性 %s", path, key)
+		}
+		if err := v.validate(additional, value[key], childPath, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateItems 校验数组的长度和元素
+func (v *schemaValidator) validateItems(schema map[string]interface{}, value []interface{}, path string, depth int) error {
+	if min, ok := schemaNumber(schema["minItems"]); ok && float64(len(value)) < min {
+		return fmt.Errorf("%s: 元素数不能少于 %v", path, min)
+	}
+	if max, ok := schemaNumber(schema["maxItems"]); ok && float64(len(value)) > max {
+		return fmt.Errorf("%s: 元素数不能多于 %v", path, max)
+	}
+
+	items, ok := schema["items"]
+	if !ok {
+		return nil
+	}
+	for i, item := range value {
+		if err := v.validate(items, item, fmt.Sprintf("%s[%d]", path, i), depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateCombinators 校验anyOf、oneOf、allOf和not
+func (v *schemaValidator) validateCombinators(schema map[string]interface{}, value interface{}, path string, depth int) error {
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range allOf {
+			if err := v.validate(sub, value, path, depth); err != nil {
+				return err
+			}
+		}
+	}
+
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok && v.countMatches(anyOf, value, path, depth) == 0 {
+		return fmt.Errorf("%s: 不符合anyOf中的任何一个schema", path)
+	}
+
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		if matched := v.countMatches(oneOf, value, path, depth); matched != 1 {
+			return fmt.Errorf("%s: 应恰好符合oneOf中的一个schema，实际符合 %d 个", path, matched)
+		}
+	}
+
+	if not, ok := schema["not"]; ok && v.validate(not, value, path, depth) == nil {
+		return fmt.Errorf("%s: 不应符合not中的schema", path)
+	}
+	return nil
+}
+
+// countMatches 统计value符合的schema个数
+func (v *schemaValidator) countMatches(schemas []interface{}, value interface{}, path string, depth int) int {
+	matched := 0
+	for _, sub := range schemas {
+		if v.validate(sub, value, path, depth) == nil {
+			matched++
+		}
+	}
+	return matched
+}
+
+// resolveRef 解析文档内的引用，如 #/$defs/Item 或 #/definitions/Item
+func (v *schemaValidator) resolveRef(ref string) (interface{}, error) {
+	return resolveSchemaRef(v.root, ref)
+}
+
+// resolveSchemaRef 在schema文档root中解析文档内的引用，不支持指向其他文档的引用
+func resolveSchemaRef(root interface{}, ref string) (interface{}, error) {
+	if ref == "#" {
+		return root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("不支持的$ref: %s", ref)
+	}
+
+	current := root
+	for _, token := range strings.Split(ref[2:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("无法解析$ref: %s", ref)
+		}
+		if current, ok = object[token]; !ok {
+			return nil, fmt.Errorf("无法解析$ref: %s", ref)
+		}
+	}
+	return current, nil
+}
+
+// validateString 校验字符串的长度和正则，长度按字符计算
+func validateString(schema map[string]interface{}, value string, path string) error {
+	length := float64(utf8.RuneCountInString(value))
+	if min, ok := schemaNumber(schema["minLength"]); ok && length < min {
+		return fmt.Errorf("%s: 长度不能小于 %v", path, min)
+	}
+	if max, ok := schemaNumber(schema["maxLength"]); ok && length > max {
+		return fmt.Errorf("%s: 长度不能大于 %v", path, max)
+	}
+
+	// 无法编译的正则表达式被忽略
+	if pattern, ok := schema["pattern"].(string); ok {
+		if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(value) {
+			return fmt.Errorf("%s: 不匹配正则表达式 %s", path, pattern)
+		}
+	}
+	return nil
+}
+
+// validateNumber 校验数值范围
+func validateNumber(schema map[string]interface{}, value float64, path string) error {
+	if min, ok := schemaNumber(schema["minimum"]); ok && value < min {
+		return fmt.Errorf("%s: 不能小于 %v", path, min)
+	}
+	if max, ok := schemaNumber(schema["maximum"]); ok && value > max {
+		return fmt.Errorf("%s: 不能大于 %v", path, max)
+	}
+	if min, ok := schemaNumber(schema["exclusiveMinimum"]); ok && value <= min {
+		return fmt.Errorf("%s: 必须大于 %v", path, min)
+	}
+	if max, ok := schemaNumber(schema["exclusiveMaximum"]); ok && value >= max {
+		return fmt.Errorf("%s: 必须小于 %v", path, max)
+	}
+	return nil
+}
+
+// schemaTypes 读取type关键字，支持字符串和字符串数组
+func schemaTypes(value interface{}) ([]string, bool) {
+	switch t := value.(type) {
+	case string:
+		return []string{t}, true
+	case []interface{}:
+		names := make([]string, 0, len(t))
+		for _, item := range t {
+			if name, ok := item.(string); ok {
+				names = append(names, name)
+			}
+		}
+		return names, len(names) > 0
+	}
+	return nil, false
+}
+
+// schemaNumber 读取数值关键字
+func schemaNumber(value interface{}) (float64, bool) {
+	number, ok := value.(float64)
+	return number, ok
+}
+
+// matchesAnyType 判断value是否属于names中的任意一种类型，integer要求数值没有小数部分
+func matchesAnyType(names []string, value interface{}) bool {
+	actual := jsonType(value)
+	for _, want := range names {
+		switch {
+		case want == actual:
+			return true
+		case want == "number" && actual == "integer":
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType 返回JSON值的类型名称，没有小数部分的数值视为integer
+func jsonType(value interface{}) string {
+	switch val := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if val == math.Trunc(val) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// containsValue 判断枚举中是否包含value
+func containsValue(enum []interface{}, value interface{}) bool {
+	for _, item := range enum {
+		if reflect.DeepEqual(item, value) {
+			return true
+		}
+	}
+	return false
+}
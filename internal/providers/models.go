@@ -1,5 +1,7 @@
 package providers
 
+import "sync"
+
 // ModelConfig 定义每个提供商支持的模型配置
 type ModelConfig struct {
 	Models       []string
@@ -50,24 +52,66 @@ var SupportedModels = map[string]ModelConfig{
 		},
 		DefaultModel: "moonshot-v1-8k",
 	},
+	"claude": {
+		Models: []string{
+			"claude-sonnet-4-20250514",
+			"claude-opus-4-20250514",
+			"claude-3-7-sonnet-20250219",
+			"claude-3-5-haiku-20241022",
+		},
+		DefaultModel: "claude-sonnet-4-20250514",
+	},
 }
 
+// modelsMu 保护SupportedModels，Ollama的模型列表会在后台定期刷新，与请求并发读写
+var modelsMu sync.RWMutex
+
 // GetProviderModels 获取提供商支持的模型列表
 func GetProviderModels(provider string) []string {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+
 	if config, exists := SupportedModels[provider]; exists {
-		return config.Models
+		return append([]string(nil), config.Models...)
 	}
 	return []string{}
 }
 
 // GetDefaultModel 获取提供商的默认模型
 func GetDefaultModel(provider string) string {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+
 	if config, exists := SupportedModels[provider]; exists {
 		return config.DefaultModel
 	}
 	return ""
 }
 
+// HasModelConfig 检查提供商是否有模型配置
+func HasModelConfig(provider string) bool {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+
+	_, exists := SupportedModels[provider]
+	return exists
+}
+
+// AllModelConfigs 获取所有提供商模型配置的副本
+func AllModelConfigs() map[string]ModelConfig {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+
+	configs := make(map[string]ModelConfig, len(SupportedModels))
+	for provider, config := range SupportedModels {
+		configs[provider] = ModelConfig{
+			Models:       append([]string(nil), config.Models...),
+			DefaultModel: config.DefaultModel,
+		}
+	}
+	return configs
+}
+
 // IsModelSupported 检查模型是否被提供商支持
 func IsModelSupported(provider, model string) bool {
 	models := GetProviderModels(provider)
@@ -77,4 +121,13 @@ func IsModelSupported(provider, model string) bool {
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
+
+// RegisterModels 注册或覆盖提供商的模型配置
+// 用于部署名称、模型列表在运行时才能确定的提供商(如Azure)
+func RegisterModels(provider string, config ModelConfig) {
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
+
+	SupportedModels[provider] = config
+}
@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/heyanxiao/llm-bridge/pkg/types"
 )
@@ -72,7 +71,7 @@ func (p *MoonshotProvider) ValidateRequest(req *types.UnifiedRequest) error {
 	}
 	
 	// 验证温度参数范围
-	if req.Parameters.Temperature < 0 || req.Parameters.Temperature > 1 {
+	if temperature := req.Parameters.Temperature; temperature != nil && (*temperature < 0 || *temperature > 1) {
 		return fmt.Errorf("温度参数必须在0-1之间")
 	}
 	
@@ -81,6 +80,9 @@ func (p *MoonshotProvider) ValidateRequest(req *types.UnifiedRequest) error {
 
 // Transform 将统一请求转换为月之暗面格式（与OpenAI兼容）
 func (p *MoonshotProvider) Transform(req *types.UnifiedRequest) ([]byte, error) {
+	// 不支持原生结构化输出，以提示词要求模型输出JSON
+	req = withFormatInstruction(req)
+
 	// 月之暗面API与OpenAI格式兼容
 	// 如果没有指定模型，使用默认模型
 	model := req.Model
@@ -89,14 +91,7 @@ func (p *MoonshotProvider) Transform(req *types.UnifiedRequest) ([]byte, error)
 	}
 	
 	// 模型名称映射
-	switch model {
-	case "moonshot-8k":
-		model = "moonshot-v1-8k"
-	case "moonshot-32k":
-		model = "moonshot-v1-32k"
-	case "moonshot-128k":
-		model = "moonshot-v1-128k"
-	}
+	model = CanonicalModel("moonshot", model)
 	
 	// 构建月之暗面请求结构
 	moonshotReq := map[string]interface{}{
@@ -105,16 +100,16 @@ func (p *MoonshotProvider) Transform(req *types.UnifiedRequest) ([]byte, error)
 	}
 	
 	// 添加可选参数
-	if req.Parameters.Temperature > 0 {
-		moonshotReq["temperature"] = req.Parameters.Temperature
+	if req.Parameters.Temperature != nil {
+		moonshotReq["temperature"] = *req.Parameters.Temperature
 	}
 	
 	if req.Parameters.MaxTokens > 0 {
 		moonshotReq["max_tokens"] = req.Parameters.MaxTokens
 	}
 	
-	if req.Parameters.TopP > 0 {
-		moonshotReq["top_p"] = req.Parameters.TopP
+	if req.Parameters.TopP != nil {
+		moonshotReq["top_p"] = *req.Parameters.TopP
 	}
 	
 	if req.Parameters.Stream {
@@ -133,11 +128,18 @@ func (p *MoonshotProvider) Transform(req *types.UnifiedRequest) ([]byte, error)
 		moonshotReq["stop"] = req.Parameters.Stop
 	}
 	
+	if req.Parameters.N > 1 {
+		moonshotReq["n"] = req.Parameters.N
+	}
+	
 	// 添加用户ID（如果存在）
 	if req.Metadata.UserID != "" {
 		moonshotReq["user"] = req.Metadata.UserID
 	}
 	
+	// 工具调用
+	applyOpenAITools(moonshotReq, req)
+	
 	return json.Marshal(moonshotReq)
 }
 
@@ -157,12 +159,10 @@ func (p *MoonshotProvider) CallAPI(ctx context.Context, data []byte) (*http.Resp
 	}
 	
 	// 创建HTTP客户端（月之暗面支持长文本，需要更长的超时时间）
-	client := &http.Client{
-		Timeout: time.Duration(p.Timeout) * time.Second,
-	}
+	client := p.httpClient(isStreamRequest(data))
 	
 	// 发送请求
-	resp, err := client.Do(req)
+	resp, err := p.doWithRetry(client, req)
 	if err != nil {
 		return nil, fmt.Errorf("调用月之暗面 API失败: %w", err)
 	}
@@ -172,102 +172,10 @@ func (p *MoonshotProvider) CallAPI(ctx context.Context, data []byte) (*http.Resp
 
 // ParseResponse 解析月之暗面响应（与OpenAI格式兼容）
 func (p *MoonshotProvider) ParseResponse(resp *http.Response) (*types.UnifiedResponse, error) {
-	defer resp.Body.Close()
-	
-	// 检查HTTP状态码
-	if resp.StatusCode != http.StatusOK {
-		// 读取错误响应
-		var errorResp map[string]interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&errorResp); err != nil {
-			return nil, fmt.Errorf("月之暗面 API返回错误状态码: %d", resp.StatusCode)
-		}
-		
-		// 解析错误信息
-		if errorData, exists := errorResp["error"]; exists {
-			errorMap := errorData.(map[string]interface{})
-			return &types.UnifiedResponse{
-				Error: &types.Error{
-					Code:    fmt.Sprintf("%v", errorMap["code"]),
-					Message: fmt.Sprintf("%v", errorMap["message"]),
-					Type:    "moonshot_error",
-				},
-			}, nil
-		}
-		
-		return nil, fmt.Errorf("月之暗面 API返回错误状态码: %d", resp.StatusCode)
-	}
-	
-	// 解析响应JSON
-	var moonshotResp map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&moonshotResp); err != nil {
-		return nil, fmt.Errorf("解析月之暗面响应失败: %w", err)
-	}
-	
-	// 检查响应中的错误
-	if errorData, exists := moonshotResp["error"]; exists {
-		errorMap := errorData.(map[string]interface{})
-		return &types.UnifiedResponse{
-			Error: &types.Error{
-				Code:    fmt.Sprintf("%v", errorMap["code"]),
-				Message: fmt.Sprintf("%v", errorMap["message"]),
-				Type:    "moonshot_error",
-			},
-		}, nil
-	}
-	
-	// 转换为统一响应格式
-	unifiedResp := &types.UnifiedResponse{
-		ID:      moonshotResp["id"].(string),
-		Object:  moonshotResp["object"].(string),
-		Created: int64(moonshotResp["created"].(float64)),
-		Model:   moonshotResp["model"].(string),
-	}
-	
-	// 解析choices
-	if choicesData, exists := moonshotResp["choices"]; exists {
-		choicesArray := choicesData.([]interface{})
-		choices := make([]types.Choice, len(choicesArray))
-		
-		for i, choiceData := range choicesArray {
-			choiceMap := choiceData.(map[string]interface{})
-			messageMap := choiceMap["message"].(map[string]interface{})
-			
-			choices[i] = types.Choice{
-				Index: int(choiceMap["index"].(float64)),
-				Message: types.Message{
-					Role:    messageMap["role"].(string),
-					Content: messageMap["content"].(string),
-				},
-				FinishReason: fmt.Sprintf("%v", choiceMap["finish_reason"]),
-			}
-		}
-		unifiedResp.Choices = choices
-	}
-	
-	// 解析usage
-	if usageData, exists := moonshotResp["usage"]; exists {
-		usageMap := usageData.(map[string]interface{})
-		unifiedResp.Usage = types.Usage{
-			PromptTokens:     int(usageMap["prompt_tokens"].(float64)),
-			CompletionTokens: int(usageMap["completion_tokens"].(float64)),
-			TotalTokens:      int(usageMap["total_tokens"].(float64)),
-		}
-	}
-	
-	return unifiedResp, nil
+	return parseOpenAICompatibleResponse(resp, p.Name)
 }
 
-// ParseStreamResponse 解析月之暗面流式响应
+// ParseStreamResponse 解析月之暗面流式响应（与OpenAI格式兼容）
 func (p *MoonshotProvider) ParseStreamResponse(resp *http.Response) (<-chan *types.StreamResponse, error) {
-	responseChan := make(chan *types.StreamResponse)
-	
-	go func() {
-		defer close(responseChan)
-		defer resp.Body.Close()
-		
-		// TODO: 实现SSE(Server-Sent Events)解析逻辑
-		// 月之暗面的流式响应格式与OpenAI兼容
-	}()
-	
-	return responseChan, nil
-}
\ No newline at end of file
+	return parseOpenAICompatibleStream(resp, p.Name)
+}
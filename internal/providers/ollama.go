@@ -0,0 +1,496 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// OllamaProvider Ollama本地模型适配器实现
+type OllamaProvider struct {
+	BaseProvider
+}
+
+// OllamaConfig Ollama配置
+type OllamaConfig struct {
+	BaseURL         string `yaml:"base_url"`
+	DefaultModel    string `yaml:"default_model"`
+	Timeout         int    `yaml:"timeout"`
+	Retries         int    `yaml:"retries"`
+	RefreshInterval int    `yaml:"refresh_interval"` // 模型列表刷新间隔(秒)，默认60
+}
+
+// 模型列表刷新的默认间隔，以及模型列表为空(如Ollama尚未启动)时的重试间隔
+const (
+	defaultOllamaRefreshInterval = 60 * time.Second
+	ollamaEmptyRetryInterval     = 10 * time.Second
+)
+
+// NewOllamaProvider 创建Ollama提供商实例
+func NewOllamaProvider(config *OllamaConfig) *OllamaProvider {
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+
+	timeout := config.Timeout
+	if timeout == 0 {
+		timeout = 120 // 本地模型首次加载较慢
+	}
+
+	retries := config.Retries
+	if retries == 0 {
+		retries = 3
+	}
+
+	// 模型列表在RefreshModels时从/api/tags获取，先注册配置的默认模型
+	if config.DefaultModel != "" {
+		RegisterModels("ollama", ModelConfig{
+			Models:       []string{config.DefaultModel},
+			DefaultModel: config.DefaultModel,
+		})
+	}
+
+	return &OllamaProvider{
+		BaseProvider: BaseProvider{
+			Name:    "ollama",
+			BaseURL: strings.TrimSuffix(baseURL, "/"),
+			Headers: map[string]string{
+				"Content-Type": "application/json",
+			},
+			Timeout: timeout,
+			Retries: retries,
+		},
+	}
+}
+
+// GetProviderName 获取提供商名称
+func (p *OllamaProvider) GetProviderName() string {
+	return p.Name
+}
+
+// StartModelRefresh 在后台定期刷新模型列表，直到ctx结束
+// 模型列表为空时按较短的间隔重试，Ollama启动较慢或临时不可用时也能自动恢复
+func (p *OllamaProvider) StartModelRefresh(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultOllamaRefreshInterval
+	}
+
+	go func() {
+		for {
+			delay := interval
+			if len(GetProviderModels(p.Name)) == 0 && ollamaEmptyRetryInterval < delay {
+				delay = ollamaEmptyRetryInterval
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+
+			refreshCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			if _, err := p.RefreshModels(refreshCtx); err != nil {
+				log.Printf("Ollama模型列表刷新失败: %v", err)
+			}
+			cancel()
+		}
+	}()
+}
+
+// RefreshModels 从/api/tags获取本地已安装的模型并更新模型配置
+func (p *OllamaProvider) RefreshModels(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.BaseURL+"/api/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
+	}
+
+	client := p.httpClient(false)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("获取Ollama模型列表失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Ollama API返回错误状态码: %d", resp.StatusCode)
+	}
+
+	var tagsResp struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tagsResp); err != nil {
+		return nil, fmt.Errorf("解析Ollama模型列表失败: %w", err)
+	}
+
+	models := make([]string, 0, len(tagsResp.Models))
+	for _, model := range tagsResp.Models {
+		if model.Name != "" {
+			models = append(models, model.Name)
+		}
+	}
+
+	// 保留已配置的默认模型(如果仍然存在)，否则使用第一个模型
+	defaultModel := GetDefaultModel("ollama")
+	found := false
+	for _, model := range models {
+		if model == defaultModel {
+			found = true
+			break
+		}
+	}
+	if !found {
+		defaultModel = ""
+		if len(models) > 0 {
+			defaultModel = models[0]
+		}
+	}
+
+	RegisterModels("ollama", ModelConfig{
+		Models:       models,
+		DefaultModel: defaultModel,
+	})
+
+	return models, nil
+}
+
+// ValidateRequest 验证请求参数
+func (p *OllamaProvider) ValidateRequest(req *types.UnifiedRequest) error {
+	if req.Model == "" {
+		return fmt.Errorf("模型名称不能为空")
+	}
+
+	if len(req.Messages) == 0 {
+		return fmt.Errorf("消息列表不能为空")
+	}
+
+	// 验证温度参数范围
+	if temperature := req.Parameters.Temperature; temperature != nil && (*temperature < 0 || *temperature > 2) {
+		return fmt.Errorf("温度参数必须在0-2之间")
+	}
+
+	return nil
+}
+
+// Transform 将统一请求转换为Ollama /api/chat格式
+func (p *OllamaProvider) Transform(req *types.UnifiedRequest) ([]byte, error) {
+	// 不支持原生结构化输出，以提示词要求模型输出JSON
+	req = withFormatInstruction(req)
+
+	model := req.Model
+	if model == "" || model == "ollama" {
+		model = GetDefaultModel("ollama")
+	}
+
+	toolNames := toolCallNames(req.Messages)
+	messages := make([]map[string]interface{}, 0, len(req.Messages))
+	for _, msg := range req.Messages {
+		message := map[string]interface{}{
+			"role":    msg.Role,
+			"content": msg.Content,
+		}
+
+		// Ollama的函数参数是对象，工具结果以函数名称关联调用
+		if len(msg.ToolCalls) > 0 {
+			toolCalls := make([]map[string]interface{}, len(msg.ToolCalls))
+			for i, call := range msg.ToolCalls {
+				toolCalls[i] = map[string]interface{}{
+					"function": map[string]interface{}{
+						"name":      call.Function.Name,
+						"arguments": toolArguments(call.Function.Arguments),
+					},
+				}
+			}
+			message["tool_calls"] = toolCalls
+		}
+		if msg.Role == "tool" && toolNames[msg.ToolCallID] != "" {
+			message["tool_name"] = toolNames[msg.ToolCallID]
+		}
+
+		messages = append(messages, message)
+	}
+
+	// Ollama默认开启流式输出，需要显式指定
+	ollamaReq := map[string]interface{}{
+		"model":    model,
+		"messages": messages,
+		"stream":   req.Parameters.Stream,
+	}
+
+	// 采样参数放在options中
+	options := make(map[string]interface{})
+
+	if req.Parameters.Temperature != nil {
+		options["temperature"] = *req.Parameters.Temperature
+	}
+
+	if req.Parameters.MaxTokens > 0 {
+		options["num_predict"] = req.Parameters.MaxTokens
+	}
+
+	if req.Parameters.TopP != nil {
+		options["top_p"] = *req.Parameters.TopP
+	}
+
+	if req.Parameters.FrequencyPenalty != 0 {
+		options["frequency_penalty"] = req.Parameters.FrequencyPenalty
+	}
+
+	if req.Parameters.PresencePenalty != 0 {
+		options["presence_penalty"] = req.Parameters.PresencePenalty
+	}
+
+	if len(req.Parameters.Stop) > 0 {
+		options["stop"] = req.Parameters.Stop
+	}
+
+	if len(options) > 0 {
+		ollamaReq["options"] = options
+	}
+
+	// 思考模式 (适用于qwen3、deepseek-r1等本地推理模型)
+	if req.Parameters.Reasoning {
+		ollamaReq["think"] = true
+	}
+
+	// 工具定义与OpenAI格式相同，Ollama不支持tool_choice
+	if len(req.Tools) > 0 {
+		ollamaReq["tools"] = req.Tools
+	}
+
+	return json.Marshal(ollamaReq)
+}
+
+// CallAPI 调用Ollama API
+func (p *OllamaProvider) CallAPI(ctx context.Context, data []byte) (*http.Response, error) {
+	url := p.BaseURL + "/api/chat"
+
+	// 创建HTTP请求
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("创建HTTP请求失败: %w", err)
+	}
+
+	// 设置请求头
+	for key, value := range p.Headers {
+		req.Header.Set(key, value)
+	}
+
+	// 创建HTTP客户端
+	client := p.httpClient(isStreamRequest(data))
+
+	// 发送请求
+	resp, err := p.doWithRetry(client, req)
+	if err != nil {
+		return nil, fmt.Errorf("调用Ollama API失败: %w", err)
+	}
+
+	return resp, nil
+}
+
+// ollamaChatChunk Ollama /api/chat的响应结构，非流式响应和NDJSON流的每一行格式相同
+type ollamaChatChunk struct {
+	Model     flexString `json:"model"`
+	CreatedAt flexString `json:"created_at"`
+	Message   struct {
+		Role      flexString       `json:"role"`
+		Content   flexString       `json:"content"`
+		Thinking  flexString       `json:"thinking"`
+		ToolCalls []openAIToolCall `json:"tool_calls"` // 参数为对象，没有调用ID
+	} `json:"message"`
+	Done            bool       `json:"done"`
+	DoneReason      flexString `json:"done_reason"`
+	PromptEvalCount flexInt    `json:"prompt_eval_count"`
+	EvalCount       flexInt    `json:"eval_count"`
+	Error           flexString `json:"error"`
+}
+
+// createdUnix 将created_at转换为Unix时间戳
+func (c *ollamaChatChunk) createdUnix() int64 {
+	if created, err := time.Parse(time.RFC3339Nano, string(c.CreatedAt)); err == nil {
+		return created.Unix()
+	}
+	return time.Now().Unix()
+}
+
+// toolCalls 转换工具调用并生成调用ID
+func (c *ollamaChatChunk) toolCalls() []types.ToolCall {
+	toolCalls := convertOpenAIToolCalls(c.Message.ToolCalls)
+	for i := range toolCalls {
+		if toolCalls[i].ID == "" {
+			toolCalls[i].ID = generateToolCallID()
+		}
+	}
+	return toolCalls
+}
+
+// usage 转换为统一的Usage
+func (c *ollamaChatChunk) usage() types.Usage {
+	return types.Usage{
+		PromptTokens:     int(c.PromptEvalCount),
+		CompletionTokens: int(c.EvalCount),
+		TotalTokens:      int(c.PromptEvalCount + c.EvalCount),
+	}
+}
+
+// ParseResponse 解析Ollama响应
+func (p *OllamaProvider) ParseResponse(resp *http.Response) (*types.UnifiedResponse, error) {
+	defer resp.Body.Close()
+
+	body, err := readResponseBody(resp)
+	if err != nil {
+		return nil, fmt.Errorf("读取Ollama响应失败: %w", err)
+	}
+
+	// 解析响应JSON
+	var chunk ollamaChatChunk
+	if err := json.Unmarshal(body, &chunk); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return upstreamStatusError(p.Name, resp.StatusCode, body), nil
+		}
+		return invalidResponseError(p.Name, err), nil
+	}
+
+	// 检查错误 (格式: {"error":"..."})
+	if chunk.Error != "" || resp.StatusCode != http.StatusOK {
+		return upstreamError(p.Name, resp.StatusCode, "", string(chunk.Error), "ollama_error"), nil
+	}
+
+	toolCalls := chunk.toolCalls()
+	finishReason := mapOllamaDoneReason(string(chunk.DoneReason))
+	if finishReason == "stop" && len(toolCalls) > 0 {
+		finishReason = "tool_calls"
+	}
+
+	created := chunk.createdUnix()
+	return &types.UnifiedResponse{
+		ID:      fmt.Sprintf("ollama-%d", created),
+		Object:  "chat.completion",
+		Created: created,
+		Model:   string(chunk.Model),
+		Choices: []types.Choice{
+			{
+				Index: 0,
+				Message: types.Message{
+					Role:      "assistant",
+					Content:   string(chunk.Message.Content),
+					ToolCalls: toolCalls,
+				},
+				FinishReason: finishReason,
+			},
+		},
+		Usage: chunk.usage(),
+	}, nil
+}
+
+// mapOllamaDoneReason 将Ollama的done_reason映射为统一的finish_reason
+func mapOllamaDoneReason(reason string) string {
+	switch reason {
+	case "", "stop":
+		return "stop"
+	case "length":
+		return "length"
+	default:
+		return reason
+	}
+}
+
+// ParseStreamResponse 解析Ollama流式响应
+// Ollama使用NDJSON格式(每行一个JSON对象)而不是SSE，最后一行done为true并包含token统计
+func (p *OllamaProvider) ParseStreamResponse(resp *http.Response) (<-chan *types.StreamResponse, error) {
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("Ollama API返回错误状态码: %d, %s", resp.StatusCode, readErrorBody(resp))
+	}
+
+	responseChan := make(chan *types.StreamResponse)
+
+	go func() {
+		defer close(responseChan)
+		defer resp.Body.Close()
+
+		id := fmt.Sprintf("ollama-%d", time.Now().Unix())
+		toolCount := 0 // 已输出的工具调用数
+
+		reader := bufio.NewReaderSize(resp.Body, 64*1024)
+		for {
+			line, readErr := reader.ReadBytes('\n')
+			line = bytes.TrimSpace(line)
+
+			if len(line) > 0 {
+				var chunk ollamaChatChunk
+				if err := json.Unmarshal(line, &chunk); err == nil {
+					streamResp := p.convertOllamaChunk(id, &chunk, &toolCount)
+					responseChan <- streamResp
+
+					if streamResp.Error != nil || chunk.Done {
+						return
+					}
+				}
+			}
+
+			if readErr != nil {
+				// 没有收到done为true的分片就结束，说明流被中断
+				responseChan <- streamInterrupted(p.Name, readErr)
+				return
+			}
+		}
+	}()
+
+	return responseChan, nil
+}
+
+// convertOllamaChunk 转换Ollama流式分片为统一格式，toolCount为之前的分片中已输出的工具调用数
+func (p *OllamaProvider) convertOllamaChunk(id string, chunk *ollamaChatChunk, toolCount *int) *types.StreamResponse {
+	streamResp := &types.StreamResponse{
+		ID:      id,
+		Object:  "chat.completion.chunk",
+		Created: chunk.createdUnix(),
+		Model:   string(chunk.Model),
+	}
+
+	// 流中途的错误: {"error":"..."}
+	if chunk.Error != "" {
+		streamResp.Error = &types.Error{
+			Code:    "stream_error",
+			Message: string(chunk.Error),
+			Type:    "ollama_error",
+		}
+		return streamResp
+	}
+
+	streamChoice := types.StreamChoice{
+		Index: 0,
+		Delta: types.StreamDelta{
+			Role:      string(chunk.Message.Role),
+			Content:   string(chunk.Message.Content),
+			Reasoning: string(chunk.Message.Thinking),
+		},
+	}
+
+	// Ollama的工具调用不分片，每个调用作为一个完整的增量输出
+	streamChoice.Delta.ToolCalls = completeToolCallDeltas(chunk.toolCalls(), toolCount)
+
+	if chunk.Done {
+		streamChoice.FinishReason = mapOllamaDoneReason(string(chunk.DoneReason))
+		if streamChoice.FinishReason == "stop" && *toolCount > 0 {
+			streamChoice.FinishReason = "tool_calls"
+		}
+		usage := chunk.usage()
+		streamResp.Usage = &usage
+	}
+
+	streamResp.Choices = []types.StreamChoice{streamChoice}
+	return streamResp
+}
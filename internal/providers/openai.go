@@ -1,13 +1,11 @@
 package providers
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/heyanxiao/llm-bridge/pkg/types"
@@ -66,12 +64,12 @@ func (p *OpenAIProvider) ValidateRequest(req *types.UnifiedRequest) error {
 	}
 	
 	// 验证温度参数范围
-	if req.Parameters.Temperature < 0 || req.Parameters.Temperature > 2 {
+	if temperature := req.Parameters.Temperature; temperature != nil && (*temperature < 0 || *temperature > 2) {
 		return fmt.Errorf("温度参数必须在0-2之间")
 	}
 	
 	// 验证TopP参数范围
-	if req.Parameters.TopP < 0 || req.Parameters.TopP > 1 {
+	if topP := req.Parameters.TopP; topP != nil && (*topP < 0 || *topP > 1) {
 		return fmt.Errorf("TopP参数必须在0-1之间")
 	}
 	
@@ -87,16 +85,16 @@ func (p *OpenAIProvider) Transform(req *types.UnifiedRequest) ([]byte, error) {
 	}
 	
 	// 添加可选参数
-	if req.Parameters.Temperature > 0 {
-		openaiReq["temperature"] = req.Parameters.Temperature
+	if req.Parameters.Temperature != nil {
+		openaiReq["temperature"] = *req.Parameters.Temperature
 	}
 	
 	if req.Parameters.MaxTokens > 0 {
 		openaiReq["max_tokens"] = req.Parameters.MaxTokens
 	}
 	
-	if req.Parameters.TopP > 0 {
-		openaiReq["top_p"] = req.Parameters.TopP
+	if req.Parameters.TopP != nil {
+		openaiReq["top_p"] = *req.Parameters.TopP
 	}
 	
 	if req.Parameters.Stream {
@@ -115,6 +113,19 @@ func (p *OpenAIProvider) Transform(req *types.UnifiedRequest) ([]byte, error) {
 		openaiReq["stop"] = req.Parameters.Stop
 	}
 	
+	if req.Parameters.N > 1 {
+		openaiReq["n"] = req.Parameters.N
+	}
+	
+	if req.Parameters.Seed != nil {
+		openaiReq["seed"] = *req.Parameters.Seed
+	}
+	
+	// 流式输出结束时返回token使用统计
+	if req.Parameters.Stream && req.Parameters.IncludeUsage {
+		openaiReq["stream_options"] = map[string]interface{}{"include_usage": true}
+	}
+	
 	// 添加推理相关参数 (适用于o1等推理模型)
 	if req.Parameters.Reasoning {
 		openaiReq["reasoning"] = true
@@ -129,6 +140,14 @@ func (p *OpenAIProvider) Transform(req *types.UnifiedRequest) ([]byte, error) {
 		openaiReq["user"] = req.Metadata.UserID
 	}
 	
+	// 工具调用
+	applyOpenAITools(openaiReq, req)
+	
+	// 结构化输出
+	if req.Parameters.ResponseFormat != nil {
+		openaiReq["response_format"] = openAIResponseFormat(req.Parameters.ResponseFormat)
+	}
+	
 	return json.Marshal(openaiReq)
 }
 
@@ -148,12 +167,10 @@ func (p *OpenAIProvider) CallAPI(ctx context.Context, data []byte) (*http.Respon
 	}
 	
 	// 创建HTTP客户端
-	client := &http.Client{
-		Timeout: time.Duration(p.Timeout) * time.Second,
-	}
+	client := p.httpClient(isStreamRequest(data))
 	
 	// 发送请求
-	resp, err := client.Do(req)
+	resp, err := p.doWithRetry(client, req)
 	if err != nil {
 		return nil, fmt.Errorf("调用OpenAI API失败: %w", err)
 	}
@@ -163,170 +180,151 @@ func (p *OpenAIProvider) CallAPI(ctx context.Context, data []byte) (*http.Respon
 
 // ParseResponse 解析OpenAI响应
 func (p *OpenAIProvider) ParseResponse(resp *http.Response) (*types.UnifiedResponse, error) {
+	return parseOpenAICompatibleResponse(resp, p.Name)
+}
+
+// ParseStreamResponse 解析OpenAI流式响应
+func (p *OpenAIProvider) ParseStreamResponse(resp *http.Response) (<-chan *types.StreamResponse, error) {
+	return parseOpenAICompatibleStream(resp, p.Name)
+}
+
+// openAIChatResponse OpenAI兼容格式的响应结构，同时用于非流式响应和流式分片
+type openAIChatResponse struct {
+	ID      flexString     `json:"id"`
+	Object  flexString     `json:"object"`
+	Created flexInt        `json:"created"`
+	Model   flexString     `json:"model"`
+	Choices []openAIChoice `json:"choices"`
+	Usage   *openAIUsage   `json:"usage"`
+	Error   *openAIError   `json:"error"`
+}
+
+// openAIChoice OpenAI兼容格式的选择结构
+type openAIChoice struct {
+	Index        flexInt       `json:"index"`
+	Message      openAIMessage `json:"message"`       // 非流式响应
+	Delta        openAIMessage `json:"delta"`         // 流式响应
+	FinishReason flexString    `json:"finish_reason"` // 未结束时为null
+	Usage        *openAIUsage  `json:"usage"`         // 月之暗面在choice中返回usage
+}
+
+// openAIMessage OpenAI兼容格式的消息结构
+// content在工具调用或内容被过滤时可能为null
+type openAIMessage struct {
+	Role             flexString       `json:"role"`
+	Content          flexString       `json:"content"`
+	Reasoning        flexString       `json:"reasoning"`         // o1等模型
+	ReasoningContent flexString       `json:"reasoning_content"` // deepseek-reasoner等模型
+	ToolCalls        []openAIToolCall `json:"tool_calls"`
+}
+
+// reasoning 获取推理内容
+func (m *openAIMessage) reasoning() string {
+	if m.Reasoning != "" {
+		return string(m.Reasoning)
+	}
+	return string(m.ReasoningContent)
+}
+
+// openAIUsage OpenAI兼容格式的usage结构
+type openAIUsage struct {
+	PromptTokens     flexInt `json:"prompt_tokens"`
+	CompletionTokens flexInt `json:"completion_tokens"`
+	TotalTokens      flexInt `json:"total_tokens"`
+}
+
+// toUsage 转换为统一的Usage
+func (u *openAIUsage) toUsage() types.Usage {
+	total := u.TotalTokens
+	if total == 0 {
+		total = u.PromptTokens + u.CompletionTokens
+	}
+	return types.Usage{
+		PromptTokens:     int(u.PromptTokens),
+		CompletionTokens: int(u.CompletionTokens),
+		TotalTokens:      int(total),
+	}
+}
+
+// openAIError OpenAI兼容格式的错误结构，code可能是字符串、数字或null
+type openAIError struct {
+	Message flexString `json:"message"`
+	Type    flexString `json:"type"`
+	Code    flexString `json:"code"`
+}
+
+// parseOpenAICompatibleResponse 解析OpenAI兼容格式的非流式响应
+// OpenAI、Azure、DeepSeek、月之暗面及通用兼容提供商共用此实现
+func parseOpenAICompatibleResponse(resp *http.Response, providerName string) (*types.UnifiedResponse, error) {
 	defer resp.Body.Close()
-	
-	// 检查HTTP状态码
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("OpenAI API返回错误状态码: %d", resp.StatusCode)
+
+	body, err := readResponseBody(resp)
+	if err != nil {
+		return nil, fmt.Errorf("读取%s响应失败: %w", providerName, err)
 	}
-	
+
 	// 解析响应JSON
-	var openaiResp map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&openaiResp); err != nil {
-		return nil, fmt.Errorf("解析OpenAI响应失败: %w", err)
+	var wire openAIChatResponse
+	if err := json.Unmarshal(body, &wire); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return upstreamStatusError(providerName, resp.StatusCode, body), nil
+		}
+		return invalidResponseError(providerName, err), nil
 	}
-	
+
 	// 检查错误
-	if errorData, exists := openaiResp["error"]; exists {
-		errorMap := errorData.(map[string]interface{})
-		return &types.UnifiedResponse{
-			Error: &types.Error{
-				Code:    errorMap["code"].(string),
-				Message: errorMap["message"].(string),
-				Type:    errorMap["type"].(string),
-			},
-		}, nil
+	if wire.Error != nil {
+		return upstreamError(providerName, resp.StatusCode, string(wire.Error.Code), string(wire.Error.Message), string(wire.Error.Type)), nil
 	}
-	
+
+	if resp.StatusCode != http.StatusOK {
+		return upstreamStatusError(providerName, resp.StatusCode, body), nil
+	}
+
+	if len(wire.Choices) == 0 {
+		return invalidResponseError(providerName, fmt.Errorf("响应中没有choices")), nil
+	}
+
 	// 转换为统一响应格式
 	unifiedResp := &types.UnifiedResponse{
-		ID:      openaiResp["id"].(string),
-		Object:  openaiResp["object"].(string),
-		Created: int64(openaiResp["created"].(float64)),
-		Model:   openaiResp["model"].(string),
+		ID:      string(wire.ID),
+		Object:  string(wire.Object),
+		Created: int64(wire.Created),
+		Model:   string(wire.Model),
 	}
-	
-	// 解析choices
-	if choicesData, exists := openaiResp["choices"]; exists {
-		choicesArray := choicesData.([]interface{})
-		choices := make([]types.Choice, len(choicesArray))
-		
-		for i, choiceData := range choicesArray {
-			choiceMap := choiceData.(map[string]interface{})
-			messageMap := choiceMap["message"].(map[string]interface{})
-			
-			choices[i] = types.Choice{
-				Index: int(choiceMap["index"].(float64)),
-				Message: types.Message{
-					Role:    messageMap["role"].(string),
-					Content: messageMap["content"].(string),
-				},
-				FinishReason: choiceMap["finish_reason"].(string),
-			}
-		}
-		unifiedResp.Choices = choices
+
+	if unifiedResp.Object == "" {
+		unifiedResp.Object = "chat.completion"
 	}
-	
-	// 解析usage
-	if usageData, exists := openaiResp["usage"]; exists {
-		usageMap := usageData.(map[string]interface{})
-		unifiedResp.Usage = types.Usage{
-			PromptTokens:     int(usageMap["prompt_tokens"].(float64)),
-			CompletionTokens: int(usageMap["completion_tokens"].(float64)),
-			TotalTokens:      int(usageMap["total_tokens"].(float64)),
-		}
+
+	if unifiedResp.Created == 0 {
+		unifiedResp.Created = time.Now().Unix()
 	}
-	
-	return unifiedResp, nil
-}
 
-// ParseStreamResponse 解析OpenAI流式响应
-func (p *OpenAIProvider) ParseStreamResponse(resp *http.Response) (<-chan *types.StreamResponse, error) {
-	responseChan := make(chan *types.StreamResponse)
-	
-	go func() {
-		defer close(responseChan)
-		defer resp.Body.Close()
-		
-		scanner := bufio.NewScanner(resp.Body)
-		for scanner.Scan() {
-			line := strings.TrimSpace(scanner.Text())
-			
-			// 跳过空行
-			if line == "" {
-				continue
-			}
-			
-			// 检查是否是SSE数据行
-			if strings.HasPrefix(line, "data: ") {
-				data := strings.TrimPrefix(line, "data: ")
-				
-				// 检查是否是结束标志
-				if data == "[DONE]" {
-					break
-				}
-				
-				// 解析JSON数据
-				var openaiStreamResp map[string]interface{}
-				if err := json.Unmarshal([]byte(data), &openaiStreamResp); err != nil {
-					continue // 跳过无效的JSON
-				}
-				
-				// 转换为统一格式
-				streamResp := p.convertOpenAIStreamResponse(openaiStreamResp)
-				if streamResp != nil {
-					responseChan <- streamResp
-				}
-			}
+	// 解析choices
+	choices := make([]types.Choice, len(wire.Choices))
+	for i, choice := range wire.Choices {
+		role := string(choice.Message.Role)
+		if role == "" {
+			role = "assistant"
 		}
-	}()
-	
-	return responseChan, nil
-}
 
-// convertOpenAIStreamResponse 转换OpenAI流式响应为统一格式
-func (p *OpenAIProvider) convertOpenAIStreamResponse(data map[string]interface{}) *types.StreamResponse {
-	streamResp := &types.StreamResponse{
-		Object:  "chat.completion.chunk",
-		Model:   p.Name,
-		Created: time.Now().Unix(),
-	}
-	
-	// 提取ID
-	if id, ok := data["id"].(string); ok {
-		streamResp.ID = id
-	}
-	
-	// 提取模型
-	if model, ok := data["model"].(string); ok {
-		streamResp.Model = model
-	}
-	
-	// 提取选择
-	if choices, ok := data["choices"].([]interface{}); ok && len(choices) > 0 {
-		choice := choices[0].(map[string]interface{})
-		
-		streamChoice := types.StreamChoice{
-			Index: 0,
-		}
-		
-		// 提取增量内容
-		if delta, ok := choice["delta"].(map[string]interface{}); ok {
-			streamDelta := types.StreamDelta{}
-			
-			if role, ok := delta["role"].(string); ok {
-				streamDelta.Role = role
-			}
-			
-			if content, ok := delta["content"].(string); ok {
-				streamDelta.Content = content
-			}
-			
-			// 检查是否有推理内容 (适用于o1等模型)
-			if reasoning, ok := delta["reasoning"].(string); ok {
-				streamDelta.Reasoning = reasoning
-			}
-			
-			streamChoice.Delta = streamDelta
-		}
-		
-		// 提取完成原因
-		if finishReason, ok := choice["finish_reason"].(string); ok {
-			streamChoice.FinishReason = finishReason
+		choices[i] = types.Choice{
+			Index: int(choice.Index),
+			Message: types.Message{
+				Role:      role,
+				Content:   string(choice.Message.Content),
+				ToolCalls: convertOpenAIToolCalls(choice.Message.ToolCalls),
+			},
+			FinishReason: string(choice.FinishReason),
 		}
-		
-		streamResp.Choices = []types.StreamChoice{streamChoice}
 	}
-	
-	return streamResp
-}
\ No newline at end of file
+	unifiedResp.Choices = choices
+
+	// 解析usage
+	if wire.Usage != nil {
+		unifiedResp.Usage = wire.Usage.toUsage()
+	}
+
+	return unifiedResp, nil
+}
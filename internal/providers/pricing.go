@@ -0,0 +1,248 @@
+package providers
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 路由模式
+const (
+	RoutingDefault  = ""         // 按负载均衡策略选择提供商
+	RoutingCheapest = "cheapest" // 选择满足能力要求且最便宜的健康模型
+)
+
+// IsValidRoutingMode 判断路由模式是否有效
+func IsValidRoutingMode(mode string) bool {
+	return mode == RoutingDefault || mode == RoutingCheapest
+}
+
+// 价格比较使用的基准货币
+const baseCurrency = "USD"
+
+// ModelInfo 模型的价格和能力信息
+type ModelInfo struct {
+	Provider      string  `json:"provider"`
+	Model         string  `json:"model"`
+	InputPrice    float64 `json:"inputPrice"`    // 每1K输入token价格
+	OutputPrice   float64 `json:"outputPrice"`   // 每1K输出token价格
+	Currency      string  `json:"currency"`      // 计价货币，如USD、CNY
+	ContextWindow int     `json:"contextWindow"` // 上下文窗口(token)
+	Reasoning     bool    `json:"reasoning"`     // 是否支持推理过程输出
+	Streaming     bool    `json:"streaming"`     // 是否支持流式输出
+}
+
+// ModelPriceConfig 配置文件中的模型价格，未配置的字段沿用内置价格表
+type ModelPriceConfig struct {
+	Provider      string   `yaml:"provider"`
+	Model         string   `yaml:"model"`
+	InputPrice    *float64 `yaml:"input_price"`
+	OutputPrice   *float64 `yaml:"output_price"`
+	Currency      string   `yaml:"currency"`
+	ContextWindow int      `yaml:"context_window"`
+	Reasoning     *bool    `yaml:"reasoning"`
+	Streaming     *bool    `yaml:"streaming"`
+}
+
+// PricingConfig 价格表和路由配置
+type PricingConfig struct {
+	Routing       string             `yaml:"routing"`        // 默认路由模式，请求未指定时使用
+	ExchangeRates map[string]float64 `yaml:"exchange_rates"` // 1单位货币折合多少USD
+	Models        []ModelPriceConfig `yaml:"models"`
+}
+
+// ModelRequirements 路由时对模型能力的要求
+type ModelRequirements struct {
+	MinContextWindow int  // 最小上下文窗口，0表示不限制
+	Reasoning        bool // 需要推理过程输出
+	Streaming        bool // 需要流式输出
+}
+
+// Satisfies 判断模型是否满足能力要求
+func (info ModelInfo) Satisfies(req ModelRequirements) bool {
+	if req.MinContextWindow > 0 && info.ContextWindow > 0 && info.ContextWindow < req.MinContextWindow {
+		return false
+	}
+	if req.Reasoning && !info.Reasoning {
+		return false
+	}
+	if req.Streaming && !info.Streaming {
+		return false
+	}
+	return true
+}
+
+// modelKey 价格表的key
+func modelKey(provider, model string) string {
+	return provider + "/" + model
+}
+
+var (
+	pricingMu sync.RWMutex
+
+	// exchangeRates 1单位货币折合多少USD，可通过配置覆盖
+	exchangeRates = map[string]float64{
+		"USD": 1,
+		"CNY": 0.14,
+	}
+
+	// modelCatalog 内置价格表 (每1K token，参考各平台公开价格)
+	modelCatalog = buildCatalog([]ModelInfo{
+		{Provider: "openai", Model: "gpt-3.5-turbo", InputPrice: 0.0005, OutputPrice: 0.0015, Currency: "USD", ContextWindow: 16385},
+		{Provider: "openai", Model: "gpt-4o-2024-08-06", InputPrice: 0.0025, OutputPrice: 0.01, Currency: "USD", ContextWindow: 128000},
+		{Provider: "openai", Model: "gpt-4.1-2025-04-14", InputPrice: 0.002, OutputPrice: 0.008, Currency: "USD", ContextWindow: 1047576},
+
+		{Provider: "gemini", Model: "gemini-2.5-pro", InputPrice: 0.00125, OutputPrice: 0.01, Currency: "USD", ContextWindow: 1048576, Reasoning: true},
+		{Provider: "gemini", Model: "gemini-2.5-flash", InputPrice: 0.0003, OutputPrice: 0.0025, Currency: "USD", ContextWindow: 1048576, Reasoning: true},
+		{Provider: "gemini", Model: "gemini-2.0-flash", InputPrice: 0.0001, OutputPrice: 0.0004, Currency: "USD", ContextWindow: 1048576},
+		{Provider: "gemini", Model: "gemini-1.5-flash", InputPrice: 0.000075, OutputPrice: 0.0003, Currency: "USD", ContextWindow: 1048576},
+		{Provider: "gemini", Model: "gemini-1.5-pro", InputPrice: 0.00125, OutputPrice: 0.005, Currency: "USD", ContextWindow: 2097152},
+
+		{Provider: "deepseek", Model: "deepseek-chat", InputPrice: 0.002, OutputPrice: 0.008, Currency: "CNY", ContextWindow: 65536},
+		{Provider: "deepseek", Model: "deepseek-reasoner", InputPrice: 0.004, OutputPrice: 0.016, Currency: "CNY", ContextWindow: 65536, Reasoning: true},
+
+		{Provider: "qwen", Model: "qwen-max", InputPrice: 0.0024, OutputPrice: 0.0096, Currency: "CNY", ContextWindow: 32768},
+		{Provider: "qwen", Model: "qwen-plus", InputPrice: 0.0008, OutputPrice: 0.002, Currency: "CNY", ContextWindow: 131072},
+		{Provider: "qwen", Model: "qwq-plus", InputPrice: 0.0016, OutputPrice: 0.004, Currency: "CNY", ContextWindow: 131072, Reasoning: true},
+
+		{Provider: "moonshot", Model: "moonshot-v1-8k", InputPrice: 0.012, OutputPrice: 0.012, Currency: "CNY", ContextWindow: 8192},
+		{Provider: "moonshot", Model: "moonshot-v1-32k", InputPrice: 0.024, OutputPrice: 0.024, Currency: "CNY", ContextWindow: 32768},
+		{Provider: "moonshot", Model: "moonshot-v1-128k", InputPrice: 0.06, OutputPrice: 0.06, Currency: "CNY", ContextWindow: 131072},
+		{Provider: "moonshot", Model: "kimi-k2-0711-preview", InputPrice: 0.004, OutputPrice: 0.016, Currency: "CNY", ContextWindow: 131072},
+
+		{Provider: "claude", Model: "claude-sonnet-4-20250514", InputPrice: 0.003, OutputPrice: 0.015, Currency: "USD", ContextWindow: 200000, Reasoning: true},
+		{Provider: "claude", Model: "claude-opus-4-20250514", InputPrice: 0.015, OutputPrice: 0.075, Currency: "USD", ContextWindow: 200000, Reasoning: true},
+		{Provider: "claude", Model: "claude-3-7-sonnet-20250219", InputPrice: 0.003, OutputPrice: 0.015, Currency: "USD", ContextWindow: 200000, Reasoning: true},
+		{Provider: "claude", Model: "claude-3-5-haiku-20241022", InputPrice: 0.0008, OutputPrice: 0.004, Currency: "USD", ContextWindow: 200000},
+	})
+)
+
+// buildCatalog 构建价格表，内置模型都支持流式输出
+func buildCatalog(models []ModelInfo) map[string]ModelInfo {
+	catalog := make(map[string]ModelInfo, len(models))
+	for _, info := range models {
+		info.Streaming = true
+		catalog[modelKey(info.Provider, info.Model)] = info
+	}
+	return catalog
+}
+
+// ApplyPricingConfig 将配置文件中的价格和汇率合并到价格表
+func ApplyPricingConfig(config *PricingConfig) {
+	pricingMu.Lock()
+	defer pricingMu.Unlock()
+
+	for currency, rate := range config.ExchangeRates {
+		if rate > 0 {
+			exchangeRates[strings.ToUpper(currency)] = rate
+		}
+	}
+
+	for _, entry := range config.Models {
+		if entry.Provider == "" || entry.Model == "" {
+			continue
+		}
+
+		key := modelKey(entry.Provider, entry.Model)
+		info, exists := modelCatalog[key]
+		if !exists {
+			info = ModelInfo{Provider: entry.Provider, Model: entry.Model, Currency: baseCurrency, Streaming: true}
+		}
+
+		if entry.InputPrice != nil {
+			info.InputPrice = *entry.InputPrice
+		}
+		if entry.OutputPrice != nil {
+			info.OutputPrice = *entry.OutputPrice
+		}
+		if entry.Currency != "" {
+			info.Currency = strings.ToUpper(entry.Currency)
+		}
+		if entry.ContextWindow > 0 {
+			info.ContextWindow = entry.ContextWindow
+		}
+		if entry.Reasoning != nil {
+			info.Reasoning = *entry.Reasoning
+		}
+		if entry.Streaming != nil {
+			info.Streaming = *entry.Streaming
+		}
+
+		modelCatalog[key] = info
+	}
+}
+
+// GetModelInfo 获取模型的价格和能力信息
+func GetModelInfo(provider, model string) (ModelInfo, bool) {
+	pricingMu.RLock()
+	defer pricingMu.RUnlock()
+
+	info, exists := modelCatalog[modelKey(provider, model)]
+	return info, exists
+}
+
+// ListModelInfo 获取价格表中的所有模型，按提供商和模型名称排序
+func ListModelInfo() []ModelInfo {
+	pricingMu.RLock()
+	defer pricingMu.RUnlock()
+
+	list := make([]ModelInfo, 0, len(modelCatalog))
+	for _, info := range modelCatalog {
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return modelKey(list[i].Provider, list[i].Model) < modelKey(list[j].Provider, list[j].Model)
+	})
+	return list
+}
+
+// EstimateCost 按基准货币(USD)估算请求费用，汇率未知时返回false
+func EstimateCost(info ModelInfo, promptTokens, completionTokens int) (float64, bool) {
+	pricingMu.RLock()
+	rate, exists := exchangeRates[strings.ToUpper(info.Currency)]
+	pricingMu.RUnlock()
+	if !exists {
+		return 0, false
+	}
+
+	cost := float64(promptTokens)/1000*info.InputPrice + float64(completionTokens)/1000*info.OutputPrice
+	return cost * rate, true
+}
+
+// CheapestModels 在指定提供商已注册的模型中，按估算费用从低到高返回满足能力要求的模型
+// 没有价格信息或汇率未知的模型不参与比较
+func CheapestModels(providerNames []string, req ModelRequirements, promptTokens, completionTokens int) []ModelInfo {
+	type candidate struct {
+		info ModelInfo
+		cost float64
+	}
+
+	candidates := make([]candidate, 0)
+	for _, provider := range providerNames {
+		for _, model := range GetProviderModels(provider) {
+			info, exists := GetModelInfo(provider, model)
+			if !exists || !info.Satisfies(req) {
+				continue
+			}
+
+			cost, ok := EstimateCost(info, promptTokens, completionTokens)
+			if !ok {
+				continue
+			}
+			candidates = append(candidates, candidate{info: info, cost: cost})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].cost != candidates[j].cost {
+			return candidates[i].cost < candidates[j].cost
+		}
+		return modelKey(candidates[i].info.Provider, candidates[i].info.Model) < modelKey(candidates[j].info.Provider, candidates[j].info.Model)
+	})
+
+	result := make([]ModelInfo, len(candidates))
+	for i, c := range candidates {
+		result[i] = c.info
+	}
+	return result
+}
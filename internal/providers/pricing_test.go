@@ -0,0 +1,88 @@
+package providers
+
+import "testing"
+
+// registerTestPrices 向价格表添加测试用的模型并注册到对应提供商，测试结束后删除
+func registerTestPrices(t *testing.T, models []ModelInfo) {
+	t.Helper()
+
+	providerModels := make(map[string]ModelConfig)
+	pricingMu.Lock()
+	for _, info := range models {
+		modelCatalog[modelKey(info.Provider, info.Model)] = info
+		config := providerModels[info.Provider]
+		config.Models = append(config.Models, info.Model)
+		providerModels[info.Provider] = config
+	}
+	pricingMu.Unlock()
+	registerTestModels(t, providerModels)
+
+	t.Cleanup(func() {
+		pricingMu.Lock()
+		defer pricingMu.Unlock()
+		for _, info := range models {
+			delete(modelCatalog, modelKey(info.Provider, info.Model))
+		}
+	})
+}
+
+// TestCheapestModels 按估算费用排序，过滤不满足上下文、推理和流式要求的模型
+func TestCheapestModels(t *testing.T) {
+	registerTestPrices(t, []ModelInfo{
+		// 0.01 CNY 折合 0.0014 USD，比 0.002 USD 便宜
+		{Provider: "price-a", Model: "small", InputPrice: 0.01, OutputPrice: 0.01, Currency: "CNY", ContextWindow: 8192, Streaming: true},
+		{Provider: "price-a", Model: "reasoner", InputPrice: 0.004, OutputPrice: 0.004, Currency: "USD", ContextWindow: 131072, Reasoning: true, Streaming: true},
+		{Provider: "price-b", Model: "large", InputPrice: 0.002, OutputPrice: 0.002, Currency: "USD", ContextWindow: 131072, Streaming: true},
+		{Provider: "price-b", Model: "batch", InputPrice: 0.0001, OutputPrice: 0.0001, Currency: "USD", ContextWindow: 131072},
+		{Provider: "price-b", Model: "unknown-currency", InputPrice: 0.00001, OutputPrice: 0.00001, Currency: "XYZ", Streaming: true},
+	})
+	providers := []string{"price-a", "price-b"}
+
+	tests := []struct {
+		name      string
+		req       ModelRequirements
+		providers []string
+		want      []string
+	}{
+		{name: "不限制能力", want: []string{"batch", "small", "large", "reasoner"}},
+		{name: "需要流式输出", req: ModelRequirements{Streaming: true}, want: []string{"small", "large", "reasoner"}},
+		{name: "需要更大的上下文", req: ModelRequirements{MinContextWindow: 32768, Streaming: true}, want: []string{"large", "reasoner"}},
+		{name: "需要推理", req: ModelRequirements{Reasoning: true}, want: []string{"reasoner"}},
+		{name: "只在指定提供商中选择", req: ModelRequirements{Streaming: true}, providers: []string{"price-b"}, want: []string{"large"}},
+		{name: "没有满足要求的模型", req: ModelRequirements{Reasoning: true}, providers: []string{"price-b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := tt.providers
+			if names == nil {
+				names = providers
+			}
+
+			var got []string
+			for _, info := range CheapestModels(names, tt.req, 1000, 1000) {
+				got = append(got, info.Model)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("CheapestModels() = %v, 期望 %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("CheapestModels() = %v, 期望 %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// TestModelInfoSatisfies 上下文窗口未知的模型不按上下文长度过滤
+func TestModelInfoSatisfies(t *testing.T) {
+	unknown := ModelInfo{Streaming: true}
+	if !unknown.Satisfies(ModelRequirements{MinContextWindow: 1 << 20, Streaming: true}) {
+		t.Error("上下文窗口未知的模型不应被过滤")
+	}
+	small := ModelInfo{ContextWindow: 8192}
+	if small.Satisfies(ModelRequirements{MinContextWindow: 8193}) {
+		t.Error("上下文窗口不足的模型应被过滤")
+	}
+}
@@ -76,6 +76,9 @@ func (p *QwenProvider) ValidateRequest(req *types.UnifiedRequest) error {
 
 // Transform 将统一请求转换为通义千问格式
 func (p *QwenProvider) Transform(req *types.UnifiedRequest) ([]byte, error) {
+	// 不支持原生结构化输出，以提示词要求模型输出JSON
+	req = withFormatInstruction(req)
+
 	// 通义千问使用不同的API格式
 	// 默认模型
 	model := req.Model
@@ -95,25 +98,32 @@ func (p *QwenProvider) Transform(req *types.UnifiedRequest) ([]byte, error) {
 	// 添加参数
 	params := qwenReq["parameters"].(map[string]interface{})
 	
-	if req.Parameters.Temperature > 0 {
-		params["temperature"] = req.Parameters.Temperature
+	if req.Parameters.Temperature != nil {
+		params["temperature"] = *req.Parameters.Temperature
 	}
 	
 	if req.Parameters.MaxTokens > 0 {
 		params["max_tokens"] = req.Parameters.MaxTokens
 	}
 	
-	if req.Parameters.TopP > 0 {
-		params["top_p"] = req.Parameters.TopP
+	if req.Parameters.TopP != nil {
+		params["top_p"] = *req.Parameters.TopP
 	}
 	
 	if len(req.Parameters.Stop) > 0 {
 		params["stop"] = req.Parameters.Stop
 	}
 	
-	// 通义千问支持增量输出
+	// 通义千问支持增量输出，流式请求使用message格式返回choices
 	if req.Parameters.Stream {
 		params["incremental_output"] = true
+		params["result_format"] = "message"
+	}
+	
+	// 工具定义放在parameters中，工具调用只在message格式中返回
+	if len(req.Tools) > 0 {
+		applyOpenAITools(params, req)
+		params["result_format"] = "message"
 	}
 	
 	return json.Marshal(qwenReq)
@@ -134,13 +144,17 @@ func (p *QwenProvider) CallAPI(ctx context.Context, data []byte) (*http.Response
 		req.Header.Set(key, value)
 	}
 	
-	// 创建HTTP客户端
-	client := &http.Client{
-		Timeout: time.Duration(p.Timeout) * time.Second,
+	// 增量输出需要开启DashScope的SSE模式
+	if isQwenStreamRequest(data) {
+		req.Header.Set("X-DashScope-SSE", "enable")
+		req.Header.Set("Accept", "text/event-stream")
 	}
 	
+	// 创建HTTP客户端
+	client := p.httpClient(isQwenStreamRequest(data))
+	
 	// 发送请求
-	resp, err := client.Do(req)
+	resp, err := p.doWithRetry(client, req)
 	if err != nil {
 		return nil, fmt.Errorf("调用通义千问 API失败: %w", err)
 	}
@@ -148,131 +162,231 @@ func (p *QwenProvider) CallAPI(ctx context.Context, data []byte) (*http.Response
 	return resp, nil
 }
 
+// qwenResponse DashScope响应结构，同时用于非流式响应和流式事件
+type qwenResponse struct {
+	RequestID flexString  `json:"request_id"`
+	Code      flexString  `json:"code"`
+	Message   flexString  `json:"message"`
+	Output    *qwenOutput `json:"output"`
+	Usage     *qwenUsage  `json:"usage"`
+}
+
+// qwenOutput DashScope的output结构，result_format为text时返回text，为message时返回choices
+type qwenOutput struct {
+	Text         flexString `json:"text"`
+	FinishReason flexString `json:"finish_reason"`
+	Choices      []struct {
+		Message      openAIMessage `json:"message"`
+		FinishReason flexString    `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// qwenUsage DashScope的usage结构
+type qwenUsage struct {
+	InputTokens  flexInt `json:"input_tokens"`
+	OutputTokens flexInt `json:"output_tokens"`
+	TotalTokens  flexInt `json:"total_tokens"`
+}
+
+// toUsage 转换为统一的Usage
+func (u *qwenUsage) toUsage() types.Usage {
+	totalTokens := u.TotalTokens
+	if totalTokens == 0 {
+		totalTokens = u.InputTokens + u.OutputTokens
+	}
+
+	return types.Usage{
+		PromptTokens:     int(u.InputTokens),
+		CompletionTokens: int(u.OutputTokens),
+		TotalTokens:      int(totalTokens),
+	}
+}
+
+// message 提取输出中的消息和结束原因，未结束时DashScope返回字符串"null"
+func (o *qwenOutput) message() (message openAIMessage, finishReason string) {
+	finishReason = string(o.FinishReason)
+	if len(o.Choices) > 0 {
+		message = o.Choices[0].Message
+		if o.Choices[0].FinishReason != "" {
+			finishReason = string(o.Choices[0].FinishReason)
+		}
+	} else {
+		message.Content = o.Text
+	}
+
+	if finishReason == "null" {
+		finishReason = ""
+	}
+	return message, finishReason
+}
+
 // ParseResponse 解析通义千问响应
 func (p *QwenProvider) ParseResponse(resp *http.Response) (*types.UnifiedResponse, error) {
 	defer resp.Body.Close()
-	
-	// 检查HTTP状态码
-	if resp.StatusCode != http.StatusOK {
-		// 读取错误响应
-		var errorResp map[string]interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&errorResp); err != nil {
-			return nil, fmt.Errorf("通义千问 API返回错误状态码: %d", resp.StatusCode)
-		}
-		
-		// 提取错误信息
-		var errorMsg string
-		if msg, exists := errorResp["message"]; exists {
-			errorMsg = fmt.Sprintf("%v", msg)
-		} else {
-			errorMsg = fmt.Sprintf("API错误: %d", resp.StatusCode)
-		}
-		
-		return &types.UnifiedResponse{
-			Error: &types.Error{
-				Code:    fmt.Sprintf("%d", resp.StatusCode),
-				Message: errorMsg,
-				Type:    "qwen_error",
-			},
-		}, nil
+
+	body, err := readResponseBody(resp)
+	if err != nil {
+		return nil, fmt.Errorf("读取通义千问响应失败: %w", err)
 	}
-	
+
 	// 解析响应JSON
-	var qwenResp map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&qwenResp); err != nil {
-		return nil, fmt.Errorf("解析通义千问响应失败: %w", err)
+	var qwenResp qwenResponse
+	if err := json.Unmarshal(body, &qwenResp); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return upstreamStatusError(p.Name, resp.StatusCode, body), nil
+		}
+		return invalidResponseError(p.Name, err), nil
 	}
-	
+
+	// 检查错误 (格式: {"code":"...","message":"...","request_id":"..."})
+	if resp.StatusCode != http.StatusOK || (qwenResp.Code != "" && qwenResp.Output == nil) {
+		return upstreamError(p.Name, resp.StatusCode, string(qwenResp.Code), string(qwenResp.Message), "qwen_error"), nil
+	}
+
+	if qwenResp.Output == nil {
+		return invalidResponseError(p.Name, fmt.Errorf("缺少output字段")), nil
+	}
+
 	// 转换为统一响应格式
 	unifiedResp := &types.UnifiedResponse{
 		ID:      fmt.Sprintf("qwen-%d", time.Now().Unix()),
 		Object:  "chat.completion",
 		Created: time.Now().Unix(),
-		Model:   "", // 从响应中获取
+		Model:   p.Name,
 	}
-	
+
+	// 获取请求ID（如果有）
+	if qwenResp.RequestID != "" {
+		unifiedResp.ID = string(qwenResp.RequestID)
+	}
+
 	// 解析输出
-	if outputData, exists := qwenResp["output"]; exists {
-		outputMap := outputData.(map[string]interface{})
-		
-		// 获取文本内容
-		var content string
-		if textData, exists := outputMap["text"]; exists {
-			content = textData.(string)
-		} else if choicesData, exists := outputMap["choices"]; exists {
-			// 有些情况下返回choices格式
-			choicesArray := choicesData.([]interface{})
-			if len(choicesArray) > 0 {
-				choiceMap := choicesArray[0].(map[string]interface{})
-				if messageData, exists := choiceMap["message"]; exists {
-					messageMap := messageData.(map[string]interface{})
-					content = messageMap["content"].(string)
-				}
-			}
-		}
-		
-		// 获取结束原因
-		finishReason := "stop"
-		if reasonData, exists := outputMap["finish_reason"]; exists {
-			finishReason = reasonData.(string)
-		}
-		
-		unifiedResp.Choices = []types.Choice{
-			{
-				Index: 0,
-				Message: types.Message{
-					Role:    "assistant",
-					Content: content,
-				},
-				FinishReason: finishReason,
+	message, finishReason := qwenResp.Output.message()
+	if finishReason == "" {
+		finishReason = "stop"
+	}
+
+	unifiedResp.Choices = []types.Choice{
+		{
+			Index: 0,
+			Message: types.Message{
+				Role:      "assistant",
+				Content:   string(message.Content),
+				ToolCalls: convertOpenAIToolCalls(message.ToolCalls),
 			},
-		}
+			FinishReason: finishReason,
+		},
 	}
-	
+
 	// 解析使用情况
-	if usageData, exists := qwenResp["usage"]; exists {
-		usageMap := usageData.(map[string]interface{})
-		
-		var promptTokens, completionTokens, totalTokens int
-		
-		if val, exists := usageMap["input_tokens"]; exists {
-			promptTokens = int(val.(float64))
-		}
-		if val, exists := usageMap["output_tokens"]; exists {
-			completionTokens = int(val.(float64))
-		}
-		if val, exists := usageMap["total_tokens"]; exists {
-			totalTokens = int(val.(float64))
-		} else {
-			totalTokens = promptTokens + completionTokens
-		}
-		
-		unifiedResp.Usage = types.Usage{
-			PromptTokens:     promptTokens,
-			CompletionTokens: completionTokens,
-			TotalTokens:      totalTokens,
-		}
-	}
-	
-	// 获取请求ID（如果有）
-	if requestID, exists := qwenResp["request_id"]; exists {
-		unifiedResp.ID = requestID.(string)
+	if qwenResp.Usage != nil {
+		unifiedResp.Usage = qwenResp.Usage.toUsage()
 	}
-	
+
 	return unifiedResp, nil
 }
 
+// isQwenStreamRequest 判断请求体是否开启了增量输出
+func isQwenStreamRequest(data []byte) bool {
+	var reqData struct {
+		Parameters struct {
+			IncrementalOutput bool `json:"incremental_output"`
+		} `json:"parameters"`
+	}
+	if err := json.Unmarshal(data, &reqData); err != nil {
+		return false
+	}
+	return reqData.Parameters.IncrementalOutput
+}
+
 // ParseStreamResponse 解析通义千问流式响应
+// DashScope的SSE帧格式为 id:/event:/data:，event为result或error
 func (p *QwenProvider) ParseStreamResponse(resp *http.Response) (<-chan *types.StreamResponse, error) {
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("通义千问 API返回错误状态码: %d, %s", resp.StatusCode, readErrorBody(resp))
+	}
+
 	responseChan := make(chan *types.StreamResponse)
 	
 	go func() {
 		defer close(responseChan)
 		defer resp.Body.Close()
 		
-		// TODO: 实现通义千问的流式响应解析
-		// 通义千问使用SSE格式，但数据格式与OpenAI略有不同
+		var progress StreamProgress
+		decoder := NewSSEDecoder(resp.Body)
+		for {
+			event, err := decoder.Next()
+			if err != nil {
+				// DashScope没有结束标志，收到finish_reason才视为正常结束
+				if interrupted := progress.interruption(p.Name, err); interrupted != nil {
+					responseChan <- interrupted
+				}
+				return
+			}
+			
+			streamResp := p.convertQwenStreamEvent(event.Event, event.Data)
+			if streamResp == nil {
+				continue
+			}
+			progress.Observe(streamResp)
+			responseChan <- streamResp
+			
+			// 错误事件后结束
+			if streamResp.Error != nil {
+				return
+			}
+		}
 	}()
 	
 	return responseChan, nil
-}
\ No newline at end of file
+}
+
+// convertQwenStreamEvent 转换DashScope流式事件为统一格式
+func (p *QwenProvider) convertQwenStreamEvent(eventType, data string) *types.StreamResponse {
+	var event qwenResponse
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return nil // 跳过无效的JSON
+	}
+
+	streamResp := &types.StreamResponse{
+		ID:      string(event.RequestID),
+		Object:  "chat.completion.chunk",
+		Model:   p.Name,
+		Created: time.Now().Unix(),
+	}
+
+	// 错误事件: {"code":"...","message":"...","request_id":"..."}
+	if eventType == "error" {
+		streamResp.Error = upstreamError(p.Name, http.StatusOK, string(event.Code), string(event.Message), "qwen_error").Error
+		return streamResp
+	}
+
+	if event.Output == nil {
+		return nil
+	}
+
+	message, finishReason := event.Output.message()
+	streamChoice := types.StreamChoice{
+		Index: 0,
+		Delta: types.StreamDelta{
+			Role:    string(message.Role),
+			Content: string(message.Content),
+			// 推理模型(如qwq-plus)的思考过程
+			Reasoning: message.reasoning(),
+			ToolCalls: convertOpenAIToolCallDeltas(message.ToolCalls),
+		},
+	}
+
+	if finishReason != "" {
+		streamChoice.FinishReason = finishReason
+
+		if event.Usage != nil {
+			usage := event.Usage.toUsage()
+			streamResp.Usage = &usage
+		}
+	}
+
+	streamResp.Choices = []types.StreamChoice{streamChoice}
+	return streamResp
+}
@@ -0,0 +1,135 @@
+package providers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ModelAliasConfig 模型别名配置，修改别名指向即可切换模型而无需修改客户端代码
+//
+//	models:
+//	  aliases:
+//	    fast: "gemini/gemini-2.0-flash"
+//	    smart: "openai/gpt-4o-2024-08-06"
+//	    reasoning: "deepseek/deepseek-reasoner"
+type ModelAliasConfig struct {
+	Aliases map[string]string `yaml:"aliases"` // 别名 -> provider/model、provider或模型名称
+}
+
+var (
+	aliasesMu sync.RWMutex
+
+	// userAliases 配置文件中定义的模型别名
+	userAliases = map[string]string{}
+)
+
+// SetModelAliases 设置模型别名，空的别名或目标会被忽略
+func SetModelAliases(aliases map[string]string) {
+	cleaned := make(map[string]string, len(aliases))
+	for alias, target := range aliases {
+		alias, target = strings.TrimSpace(alias), strings.TrimSpace(target)
+		if alias == "" || target == "" {
+			continue
+		}
+		cleaned[alias] = target
+	}
+
+	aliasesMu.Lock()
+	defer aliasesMu.Unlock()
+	userAliases = cleaned
+}
+
+// ModelAliases 获取所有模型别名的副本
+func ModelAliases() map[string]string {
+	aliasesMu.RLock()
+	defer aliasesMu.RUnlock()
+
+	aliases := make(map[string]string, len(userAliases))
+	for alias, target := range userAliases {
+		aliases[alias] = target
+	}
+	return aliases
+}
+
+// lookupModelAlias 查找模型别名指向的目标
+func lookupModelAlias(name string) (string, bool) {
+	aliasesMu.RLock()
+	defer aliasesMu.RUnlock()
+
+	target, exists := userAliases[name]
+	return target, exists
+}
+
+// ModelResolveError 无法根据模型名称确定提供商
+type ModelResolveError struct {
+	Model      string
+	Candidates []string // 模型名称有歧义时，支持该模型的提供商
+}
+
+// Error 实现error接口
+func (e *ModelResolveError) Error() string {
+	if len(e.Candidates) > 0 {
+		return fmt.Sprintf("模型 %s 同时由多个提供商支持(%s)，请使用 provider/model 形式指定", e.Model, strings.Join(e.Candidates, ", "))
+	}
+	return fmt.Sprintf("找不到模型 %s 对应的提供商", e.Model)
+}
+
+// Ambiguous 模型名称是否有歧义
+func (e *ModelResolveError) Ambiguous() bool {
+	return len(e.Candidates) > 0
+}
+
+// ResolveModel 将只有模型名称的请求解析为提供商和具体模型
+// 依次尝试: 配置的别名、provider/model 前缀、提供商名称(使用默认模型)、唯一的模型名称
+// available不为nil时只在其返回true的提供商中查找模型名称，避免匹配到未配置密钥的提供商
+func ResolveModel(name string, available func(provider string) bool) (FailoverTarget, error) {
+	requested := strings.TrimSpace(name)
+	name = requested
+	if target, exists := lookupModelAlias(name); exists {
+		name = target
+	}
+
+	// 模型名称本身可能包含斜杠(如openrouter的openai/gpt-4o-mini)，只在前缀是已知提供商时拆分
+	if provider, model, found := strings.Cut(name, "/"); found && model != "" && HasModelConfig(provider) {
+		return FailoverTarget{Provider: provider, Model: model}, nil
+	}
+
+	if HasModelConfig(name) {
+		if model := GetDefaultModel(name); model != "" {
+			return FailoverTarget{Provider: name, Model: model}, nil
+		}
+	}
+
+	candidates := findProvidersForModel(name, available)
+	switch len(candidates) {
+	case 0:
+		return FailoverTarget{}, &ModelResolveError{Model: requested}
+	case 1:
+		return FailoverTarget{Provider: candidates[0], Model: name}, nil
+	default:
+		return FailoverTarget{}, &ModelResolveError{Model: requested, Candidates: candidates}
+	}
+}
+
+// findProvidersForModel 查找支持指定模型(包括提供商内置的模型别名)的提供商，按名称排序
+func findProvidersForModel(model string, available func(provider string) bool) []string {
+	var found []string
+	for provider, config := range AllModelConfigs() {
+		if available != nil && !available(provider) {
+			continue
+		}
+
+		canonical := CanonicalModel(provider, model)
+		for _, m := range config.Models {
+			if m == canonical {
+				found = append(found, provider)
+				break
+			}
+		}
+	}
+
+	sort.Strings(found)
+	return found
+}
@@ -0,0 +1,161 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	retryBaseDelay     = 500 * time.Millisecond // 首次重试的基础等待时间
+	retryMaxDelay      = 10 * time.Second       // 指数退避的最大等待时间
+	retryMaxRetryAfter = 30 * time.Second       // 上游Retry-After的最大等待时间
+)
+
+// doWithRetry 发送HTTP请求，对连接错误、429和5xx按指数退避加抖动重试
+// p.Retries为首次请求失败后的重试次数，Retries=3时最多发送4次请求
+// 非幂等请求(POST)的请求头已经发出后连接出错时，上游可能已经开始处理，不再重试以免重复计费
+// 重试只发生在响应返回给调用方之前，流式响应一旦开始转发就不会再重试
+// 最后一次尝试的响应(即使是429/5xx)会原样返回，由ParseResponse生成错误信息
+func (p *BaseProvider) doWithRetry(client *http.Client, req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 {
+			var err error
+			if attemptReq, err = cloneRequest(req); err != nil {
+				return nil, err
+			}
+		}
+
+		// 记录请求是否已经发出，判断连接错误能否安全重试
+		var written atomic.Bool
+		attemptReq = attemptReq.WithContext(httptrace.WithClientTrace(attemptReq.Context(), &httptrace.ClientTrace{
+			WroteHeaders: func() { written.Store(true) },
+		}))
+
+		resp, err := client.Do(attemptReq)
+		if err != nil {
+			// 请求上下文已取消或超时，或者上游可能已经收到请求时，不再重试
+			if ctx.Err() != nil || attempt >= p.Retries || (written.Load() && !isIdempotent(req.Method)) {
+				return nil, err
+			}
+
+			if !waitForRetry(ctx, backoffDelay(attempt)) {
+				return nil, err
+			}
+			continue
+		}
+
+		if !isRetryableStatus(resp.StatusCode) || attempt >= p.Retries {
+			return resp, nil
+		}
+
+		delay := backoffDelay(attempt)
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			// 上游要求等待的时间过长时直接返回，由调用方决定是否切换提供商
+			if retryAfter > retryMaxRetryAfter {
+				return resp, nil
+			}
+			delay = retryAfter
+		}
+
+		// 等待时间超出请求截止时间时直接返回本次响应
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return resp, nil
+		}
+
+		// 丢弃本次响应，复用连接
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+		resp.Body.Close()
+
+		if !waitForRetry(ctx, delay) {
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// cloneRequest 复制请求用于重试，请求体通过GetBody重新获取
+func cloneRequest(req *http.Request) (*http.Request, error) {
+	cloned := req.Clone(req.Context())
+	if req.Body == nil || req.GetBody == nil {
+		return cloned, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("重试时复制请求体失败: %w", err)
+	}
+	cloned.Body = body
+	return cloned, nil
+}
+
+// isIdempotent 判断请求方法是否幂等，幂等请求在连接出错后总是可以重试
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// isRetryableStatus 判断状态码是否可重试
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// backoffDelay 计算第attempt次失败后的等待时间，在[delay/2, delay)之间随机抖动
+func backoffDelay(attempt int) time.Duration {
+	delay := retryBaseDelay << uint(attempt)
+	if delay <= 0 || delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
+
+// parseRetryAfter 解析Retry-After响应头，支持秒数和HTTP日期两种格式
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
+
+// waitForRetry 等待重试，上下文结束时返回false
+func waitForRetry(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,256 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// retryResponse 测试服务器依次返回的响应
+type retryResponse struct {
+	status     int
+	retryAfter string
+}
+
+// retryServer 按顺序返回responses中的响应，超出后重复最后一个，记录收到的请求体
+type retryServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	bodies []string
+}
+
+func newRetryServer(t *testing.T, responses ...retryResponse) *retryServer {
+	s := &retryServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		s.mu.Lock()
+		s.bodies = append(s.bodies, string(body))
+		response := responses[len(responses)-1]
+		if len(s.bodies) <= len(responses) {
+			response = responses[len(s.bodies)-1]
+		}
+		s.mu.Unlock()
+
+		if response.retryAfter != "" {
+			w.Header().Set("Retry-After", response.retryAfter)
+		}
+		w.WriteHeader(response.status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// attempts 返回服务器收到的请求数
+func (s *retryServer) attempts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.bodies)
+}
+
+// TestDoWithRetry 重试次数、Retry-After、请求截止时间和请求体重放
+func TestDoWithRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		retries      int
+		timeout      time.Duration
+		responses    []retryResponse
+		wantStatus   int
+		wantAttempts int
+		minElapsed   time.Duration
+		maxElapsed   time.Duration
+	}{
+		{
+			name:         "503后重试成功",
+			retries:      3,
+			responses:    []retryResponse{{status: 503}, {status: 200}},
+			wantStatus:   200,
+			wantAttempts: 2,
+		},
+		{
+			name:         "按Retry-After等待",
+			retries:      3,
+			responses:    []retryResponse{{status: 429, retryAfter: "1"}, {status: 200}},
+			wantStatus:   200,
+			wantAttempts: 2,
+			minElapsed:   time.Second,
+		},
+		{
+			name:         "Retry-After超过30秒时不等待",
+			retries:      3,
+			responses:    []retryResponse{{status: 429, retryAfter: "60"}},
+			wantStatus:   429,
+			wantAttempts: 1,
+			maxElapsed:   time.Second,
+		},
+		{
+			name:         "等待时间超出请求截止时间时返回",
+			retries:      3,
+			timeout:      500 * time.Millisecond,
+			responses:    []retryResponse{{status: 503, retryAfter: "1"}},
+			wantStatus:   503,
+			wantAttempts: 1,
+			maxElapsed:   500 * time.Millisecond,
+		},
+		{
+			name:         "每次重试重放请求体",
+			retries:      3,
+			responses:    []retryResponse{{status: 502, retryAfter: "0"}, {status: 500, retryAfter: "0"}, {status: 200}},
+			wantStatus:   200,
+			wantAttempts: 3,
+		},
+		{
+			name:         "重试次数用完后返回最后的响应",
+			retries:      3,
+			responses:    []retryResponse{{status: 503, retryAfter: "0"}},
+			wantStatus:   503,
+			wantAttempts: 4,
+		},
+		{
+			name:         "Retries为0时只请求一次",
+			responses:    []retryResponse{{status: 503, retryAfter: "0"}},
+			wantStatus:   503,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newRetryServer(t, tt.responses...)
+			p := &BaseProvider{Retries: tt.retries}
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			const body = `{"model":"gpt-4o"}`
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, bytes.NewReader([]byte(body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			start := time.Now()
+			resp, err := p.doWithRetry(http.DefaultClient, req)
+			elapsed := time.Since(start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus || server.attempts() != tt.wantAttempts {
+				t.Errorf("状态码 %d, 请求 %d 次; 期望 %d, %d 次", resp.StatusCode, server.attempts(), tt.wantStatus, tt.wantAttempts)
+			}
+			if elapsed < tt.minElapsed || (tt.maxElapsed > 0 && elapsed > tt.maxElapsed) {
+				t.Errorf("耗时 %s, 期望在 [%s, %s] 之间", elapsed, tt.minElapsed, tt.maxElapsed)
+			}
+			server.mu.Lock()
+			defer server.mu.Unlock()
+			for i, got := range server.bodies {
+				if got != body {
+					t.Errorf("第%d次请求的请求体 %q, 期望 %q", i+1, got, body)
+				}
+			}
+		})
+	}
+}
+
+// TestDoWithRetryStopsAtContextDeadline 等待重试期间请求截止时间到达时返回上下文错误
+func TestDoWithRetryStopsAtContextDeadline(t *testing.T) {
+	attempts := 0
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		return nil, errors.New("connection refused")
+	})}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "http://upstream.invalid", nil)
+
+	start := time.Now()
+	if _, err := (&BaseProvider{Retries: 3}).doWithRetry(client, req); err == nil {
+		t.Fatal("应返回错误")
+	}
+	// 首次重试的退避时间至少为retryBaseDelay/2，超过截止时间
+	if elapsed := time.Since(start); attempts != 1 || elapsed > retryBaseDelay/2 {
+		t.Errorf("请求 %d 次, 耗时 %s; 期望请求1次并在截止时间返回", attempts, elapsed)
+	}
+}
+
+// roundTripFunc 用函数实现http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// TestDoWithRetryConnectionErrors 请求发出前的连接错误重试，POST请求发出后连接断开时不重试
+func TestDoWithRetryConnectionErrors(t *testing.T) {
+	t.Run("请求发出前连接失败", func(t *testing.T) {
+		attempts := 0
+		client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			attempts++
+			if attempts == 1 {
+				return nil, errors.New("connection refused")
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		})}
+
+		req, _ := http.NewRequest(http.MethodPost, "http://upstream.invalid", bytes.NewReader([]byte("{}")))
+		resp, err := (&BaseProvider{Retries: 1}).doWithRetry(client, req)
+		if err != nil || resp.StatusCode != http.StatusOK || attempts != 2 {
+			t.Errorf("请求 %d 次, 错误 %v; 期望重试后成功", attempts, err)
+		}
+	})
+
+	t.Run("POST请求发出后连接断开", func(t *testing.T) {
+		var mu sync.Mutex
+		attempts := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			attempts++
+			mu.Unlock()
+			io.ReadAll(r.Body)
+
+			// 读取请求后不返回响应直接断开连接
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+		}))
+		defer server.Close()
+
+		req, _ := http.NewRequest(http.MethodPost, server.URL, bytes.NewReader([]byte("{}")))
+		if _, err := (&BaseProvider{Retries: 3}).doWithRetry(http.DefaultClient, req); err == nil {
+			t.Fatal("应返回错误")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if attempts != 1 {
+			t.Errorf("上游可能已处理的POST请求不应重试，实际请求 %d 次", attempts)
+		}
+	})
+}
+
+// TestParseRetryAfter Retry-After支持秒数和HTTP日期
+func TestParseRetryAfter(t *testing.T) {
+	if delay, ok := parseRetryAfter("5"); !ok || delay != 5*time.Second {
+		t.Errorf("parseRetryAfter(5) = %s, %v", delay, ok)
+	}
+	date := time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)
+	if delay, ok := parseRetryAfter(date); !ok || delay <= 8*time.Second || delay > 10*time.Second {
+		t.Errorf("parseRetryAfter(%s) = %s, %v", date, delay, ok)
+	}
+	for _, invalid := range []string{"", "-1", "soon"} {
+		if _, ok := parseRetryAfter(invalid); ok {
+			t.Errorf("parseRetryAfter(%q) 应无效", invalid)
+		}
+	}
+}
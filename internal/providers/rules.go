@@ -0,0 +1,305 @@
+package providers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// RoutingRuleConfig 路由规则配置
+// 规则按顺序匹配，使用第一条所有条件都满足的规则
+//
+//	routing_rules:
+//	  - name: search-team
+//	    match:
+//	      headers: {X-Team: search}
+//	      min_prompt_tokens: 8000
+//	    action:
+//	      target: "qwen/qwen-plus"
+//	      parameters: {temperature: 0.2}
+//	  - name: block-night-reasoning
+//	    match:
+//	      reasoning: true
+//	      time_range: "00:00-07:00"
+//	      timezone: Asia/Shanghai
+//	    action:
+//	      deny: true
+//	      message: "夜间不提供推理模型服务"
+type RoutingRuleConfig struct {
+	Name   string     `yaml:"name"`
+	Match  RuleMatch  `yaml:"match"`
+	Action RuleAction `yaml:"action"`
+}
+
+// RuleMatch 规则的匹配条件，未配置的条件不参与匹配
+type RuleMatch struct {
+	UserIDs         []string          `yaml:"user_ids"`          // 用户ID
+	Headers         map[string]string `yaml:"headers"`           // 请求头，值为*时只要求请求头存在
+	Providers       []string          `yaml:"providers"`         // 请求中指定的提供商
+	Models          []string          `yaml:"models"`            // 请求中指定的模型名称或别名
+	MinPromptTokens int               `yaml:"min_prompt_tokens"` // 估算的输入token数下限
+	MaxPromptTokens int               `yaml:"max_prompt_tokens"` // 估算的输入token数上限
+	Reasoning       *bool             `yaml:"reasoning"`         // 是否要求输出推理过程
+	TimeRange       string            `yaml:"time_range"`        // 生效时间段，如 09:00-18:00，支持跨零点
+	Timezone        string            `yaml:"timezone"`          // 时间段使用的时区，默认为服务器时区
+}
+
+// RuleAction 规则匹配后的动作
+type RuleAction struct {
+	Target     string         `yaml:"target"`     // 目标，支持 provider/model、provider 和模型别名
+	Parameters RuleParameters `yaml:"parameters"` // 覆盖请求参数
+	Deny       bool           `yaml:"deny"`       // 拒绝请求
+	Message    string         `yaml:"message"`    // 拒绝时返回的错误信息
+}
+
+// RuleParameters 规则覆盖的请求参数，未配置的参数保持请求中的值
+type RuleParameters struct {
+	Temperature     *float64 `yaml:"temperature"`
+	MaxTokens       *int     `yaml:"max_tokens"`
+	TopP            *float64 `yaml:"top_p"`
+	Reasoning       *bool    `yaml:"reasoning"`
+	ReasoningEffort string   `yaml:"reasoning_effort"`
+}
+
+// RuleInput 规则匹配的输入
+type RuleInput struct {
+	Request *types.UnifiedRequest
+	Header  func(name string) string // 读取请求头
+	Now     time.Time
+}
+
+// RoutingRule 解析后的路由规则
+type RoutingRule struct {
+	config   RoutingRuleConfig
+	headers  map[string]string
+	timed    bool
+	start    int // 时间段开始(当天第几分钟)
+	end      int // 时间段结束(当天第几分钟)
+	location *time.Location
+}
+
+// RuleEngine 路由规则引擎
+type RuleEngine struct {
+	rules []*RoutingRule
+}
+
+// NewRuleEngine 解析路由规则配置，配置有误时返回错误
+func NewRuleEngine(configs []RoutingRuleConfig) (*RuleEngine, error) {
+	engine := &RuleEngine{}
+	seen := make(map[string]bool, len(configs))
+
+	for i, config := range configs {
+		if config.Name == "" {
+			return nil, fmt.Errorf("第 %d 条路由规则缺少name", i+1)
+		}
+		if seen[config.Name] {
+			return nil, fmt.Errorf("路由规则名称重复: %s", config.Name)
+		}
+		seen[config.Name] = true
+
+		rule, err := newRoutingRule(config)
+		if err != nil {
+			return nil, fmt.Errorf("路由规则 %s 配置有误: %w", config.Name, err)
+		}
+		engine.rules = append(engine.rules, rule)
+	}
+
+	return engine, nil
+}
+
+// newRoutingRule 解析单条路由规则
+func newRoutingRule(config RoutingRuleConfig) (*RoutingRule, error) {
+	action := config.Action
+	if action.Deny && action.Target != "" {
+		return nil, fmt.Errorf("deny和target不能同时配置")
+	}
+	if !action.Deny && action.Target == "" && action.Parameters == (RuleParameters{}) {
+		return nil, fmt.Errorf("action至少需要配置target、parameters或deny之一")
+	}
+
+	match := config.Match
+	if match.MaxPromptTokens > 0 && match.MinPromptTokens > match.MaxPromptTokens {
+		return nil, fmt.Errorf("min_prompt_tokens不能大于max_prompt_tokens")
+	}
+
+	rule := &RoutingRule{
+		config:   config,
+		headers:  make(map[string]string, len(match.Headers)),
+		location: time.Local,
+	}
+	for name, value := range match.Headers {
+		rule.headers[http.CanonicalHeaderKey(name)] = value
+	}
+
+	if match.Timezone != "" {
+		location, err := time.LoadLocation(match.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("无效的时区 %s: %w", match.Timezone, err)
+		}
+		rule.location = location
+	}
+
+	if match.TimeRange != "" {
+		startText, endText, found := strings.Cut(match.TimeRange, "-")
+		if !found {
+			return nil, fmt.Errorf("无效的时间段 %s，格式应为 HH:MM-HH:MM", match.TimeRange)
+		}
+
+		start, err := parseClock(startText)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(endText)
+		if err != nil {
+			return nil, err
+		}
+		rule.timed, rule.start, rule.end = true, start, end
+	}
+
+	return rule, nil
+}
+
+// parseClock 解析 HH:MM 形式的时间，返回当天第几分钟
+func parseClock(text string) (int, error) {
+	clock, err := time.Parse("15:04", strings.TrimSpace(text))
+	if err != nil {
+		return 0, fmt.Errorf("无效的时间 %s，格式应为 HH:MM", text)
+	}
+	return clock.Hour()*60 + clock.Minute(), nil
+}
+
+// Match 返回第一条匹配请求的规则，没有匹配时返回nil
+func (e *RuleEngine) Match(input RuleInput) *RoutingRule {
+	if e == nil {
+		return nil
+	}
+
+	promptTokens := -1 // 按需估算
+	for _, rule := range e.rules {
+		if rule.matches(input, &promptTokens) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// matches 判断请求是否满足规则的所有条件
+func (r *RoutingRule) matches(input RuleInput, promptTokens *int) bool {
+	match := r.config.Match
+	req := input.Request
+
+	if len(match.UserIDs) > 0 && !containsString(match.UserIDs, req.Metadata.UserID) {
+		return false
+	}
+	if len(match.Providers) > 0 && !containsString(match.Providers, req.Provider) {
+		return false
+	}
+	if len(match.Models) > 0 && !containsString(match.Models, req.Model) {
+		return false
+	}
+	if match.Reasoning != nil && *match.Reasoning != req.Parameters.Reasoning {
+		return false
+	}
+
+	for name, expected := range r.headers {
+		value := ""
+		if input.Header != nil {
+			value = input.Header(name)
+		}
+		if value == "" || (expected != "*" && value != expected) {
+			return false
+		}
+	}
+
+	if match.MinPromptTokens > 0 || match.MaxPromptTokens > 0 {
+		if *promptTokens < 0 {
+			*promptTokens = EstimateMessagesTokens(req.Messages)
+		}
+		if match.MinPromptTokens > 0 && *promptTokens < match.MinPromptTokens {
+			return false
+		}
+		if match.MaxPromptTokens > 0 && *promptTokens > match.MaxPromptTokens {
+			return false
+		}
+	}
+
+	if r.timed && !r.inTimeRange(input.Now) {
+		return false
+	}
+
+	return true
+}
+
+// inTimeRange 判断时间是否在规则的时间段内，开始时间大于结束时间表示跨零点
+func (r *RoutingRule) inTimeRange(now time.Time) bool {
+	local := now.In(r.location)
+	minute := local.Hour()*60 + local.Minute()
+
+	if r.start <= r.end {
+		return minute >= r.start && minute < r.end
+	}
+	return minute >= r.start || minute < r.end
+}
+
+// Name 规则名称
+func (r *RoutingRule) Name() string {
+	return r.config.Name
+}
+
+// Denied 规则是否拒绝请求，返回拒绝时的错误信息
+func (r *RoutingRule) Denied() (bool, string) {
+	if !r.config.Action.Deny {
+		return false, ""
+	}
+	if r.config.Action.Message != "" {
+		return true, r.config.Action.Message
+	}
+	return true, "请求被路由规则 " + r.config.Name + " 拒绝"
+}
+
+// Apply 将规则的目标和参数覆盖应用到请求
+// 目标按请求中只指定model时的规则解析，available用于过滤未注册的提供商
+func (r *RoutingRule) Apply(req *types.UnifiedRequest, available func(provider string) bool) error {
+	action := r.config.Action
+
+	if action.Target != "" {
+		target, err := ResolveModel(action.Target, available)
+		if err != nil {
+			return fmt.Errorf("路由规则 %s 的目标无效: %w", r.config.Name, err)
+		}
+		req.Provider = target.Provider
+		req.Model = target.Model
+	}
+
+	params := action.Parameters
+	if params.Temperature != nil {
+		req.Parameters.Temperature = params.Temperature
+	}
+	if params.MaxTokens != nil {
+		req.Parameters.MaxTokens = *params.MaxTokens
+	}
+	if params.TopP != nil {
+		req.Parameters.TopP = params.TopP
+	}
+	if params.Reasoning != nil {
+		req.Parameters.Reasoning = *params.Reasoning
+	}
+	if params.ReasoningEffort != "" {
+		req.Parameters.ReasoningEffort = params.ReasoningEffort
+	}
+
+	return nil
+}
+
+// containsString 判断列表中是否包含指定字符串
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
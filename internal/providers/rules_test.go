@@ -0,0 +1,245 @@
+package providers
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// TestNewRuleEngineValidation 配置有误的规则在加载时返回错误
+func TestNewRuleEngineValidation(t *testing.T) {
+	target := RuleAction{Target: "qwen/qwen-plus"}
+
+	tests := []struct {
+		name    string
+		configs []RoutingRuleConfig
+		wantErr string
+	}{
+		{name: "缺少name", configs: []RoutingRuleConfig{{Action: target}}, wantErr: "缺少name"},
+		{name: "名称重复", configs: []RoutingRuleConfig{{Name: "a", Action: target}, {Name: "a", Action: target}}, wantErr: "名称重复"},
+		{name: "deny与target同时配置", configs: []RoutingRuleConfig{{Name: "a", Action: RuleAction{Deny: true, Target: "qwen"}}}, wantErr: "deny和target"},
+		{name: "action为空", configs: []RoutingRuleConfig{{Name: "a"}}, wantErr: "action至少需要"},
+		{
+			name:    "token下限大于上限",
+			configs: []RoutingRuleConfig{{Name: "a", Match: RuleMatch{MinPromptTokens: 100, MaxPromptTokens: 10}, Action: target}},
+			wantErr: "min_prompt_tokens",
+		},
+		{name: "无效的时区", configs: []RoutingRuleConfig{{Name: "a", Match: RuleMatch{Timezone: "Mars/Base"}, Action: target}}, wantErr: "无效的时区"},
+		{name: "时间段缺少结束时间", configs: []RoutingRuleConfig{{Name: "a", Match: RuleMatch{TimeRange: "09:00"}, Action: target}}, wantErr: "无效的时间段"},
+		{name: "无效的时间", configs: []RoutingRuleConfig{{Name: "a", Match: RuleMatch{TimeRange: "09:00-25:00"}, Action: target}}, wantErr: "无效的时间"},
+		{
+			name: "有效配置",
+			configs: []RoutingRuleConfig{
+				{Name: "a", Match: RuleMatch{TimeRange: "22:00-06:00", Timezone: "Asia/Shanghai"}, Action: RuleAction{Deny: true}},
+				{Name: "b", Action: target},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRuleEngine(tt.configs)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("不应返回错误: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("错误 %v, 期望包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// ruleInput 创建规则匹配的输入，content为唯一一条用户消息
+func ruleInput(content string, headers map[string]string, now time.Time) RuleInput {
+	header := http.Header{}
+	for name, value := range headers {
+		header.Set(name, value)
+	}
+	return RuleInput{
+		Request: &types.UnifiedRequest{
+			Model:    "deepseek-chat",
+			Messages: []types.Message{{Role: "user", Content: content}},
+		},
+		Header: header.Get,
+		Now:    now,
+	}
+}
+
+// TestRoutingRuleMatches 各匹配条件单独生效
+func TestRoutingRuleMatches(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skip("缺少时区数据:", err)
+	}
+	noon := time.Date(2024, 6, 1, 12, 0, 0, 0, shanghai)
+	// 单条用户消息的token数为内容长度(中文每字1个)加8
+	prompt := func(tokens int) string { return strings.Repeat("字", tokens-8) }
+
+	tests := []struct {
+		name    string
+		match   RuleMatch
+		content string
+		headers map[string]string
+		now     time.Time
+		want    bool
+	}{
+		{name: "请求头名称不区分大小写", match: RuleMatch{Headers: map[string]string{"x-team": "search"}}, headers: map[string]string{"X-Team": "search"}, want: true},
+		{name: "请求头的值不同", match: RuleMatch{Headers: map[string]string{"X-Team": "search"}}, headers: map[string]string{"X-Team": "ads"}},
+		{name: "通配符匹配任意值", match: RuleMatch{Headers: map[string]string{"X-Team": "*"}}, headers: map[string]string{"X-Team": "ads"}, want: true},
+		{name: "通配符要求请求头存在", match: RuleMatch{Headers: map[string]string{"X-Team": "*"}}},
+
+		{name: "达到token下限", match: RuleMatch{MinPromptTokens: 100}, content: prompt(100), want: true},
+		{name: "低于token下限", match: RuleMatch{MinPromptTokens: 100}, content: prompt(99)},
+		{name: "达到token上限", match: RuleMatch{MaxPromptTokens: 100}, content: prompt(100), want: true},
+		{name: "超过token上限", match: RuleMatch{MaxPromptTokens: 100}, content: prompt(101)},
+		{name: "在token区间内", match: RuleMatch{MinPromptTokens: 50, MaxPromptTokens: 100}, content: prompt(80), want: true},
+
+		{name: "时间段内", match: RuleMatch{TimeRange: "09:00-18:00", Timezone: "Asia/Shanghai"}, now: noon, want: true},
+		{name: "时间段结束时间不包含在内", match: RuleMatch{TimeRange: "09:00-12:00", Timezone: "Asia/Shanghai"}, now: noon},
+		{
+			name:  "跨零点时间段的前半段",
+			match: RuleMatch{TimeRange: "22:00-06:00", Timezone: "Asia/Shanghai"},
+			now:   time.Date(2024, 6, 1, 23, 30, 0, 0, shanghai),
+			want:  true,
+		},
+		{
+			name:  "跨零点时间段的后半段",
+			match: RuleMatch{TimeRange: "22:00-06:00", Timezone: "Asia/Shanghai"},
+			now:   time.Date(2024, 6, 1, 5, 59, 0, 0, shanghai),
+			want:  true,
+		},
+		{name: "跨零点时间段之外", match: RuleMatch{TimeRange: "22:00-06:00", Timezone: "Asia/Shanghai"}, now: noon},
+		{
+			// UTC 15:00 为上海时间 23:00
+			name:  "按规则的时区判断",
+			match: RuleMatch{TimeRange: "22:00-06:00", Timezone: "Asia/Shanghai"},
+			now:   time.Date(2024, 6, 1, 15, 0, 0, 0, time.UTC),
+			want:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := newRoutingRule(RoutingRuleConfig{Name: tt.name, Match: tt.match, Action: RuleAction{Deny: true}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			promptTokens := -1
+			input := ruleInput(tt.content, tt.headers, tt.now)
+			if got := rule.matches(input, &promptTokens); got != tt.want {
+				t.Errorf("matches() = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRuleEngineFirstMatch 使用第一条所有条件都满足的规则
+func TestRuleEngineFirstMatch(t *testing.T) {
+	engine, err := NewRuleEngine([]RoutingRuleConfig{
+		{Name: "search", Match: RuleMatch{Headers: map[string]string{"X-Team": "search"}}, Action: RuleAction{Target: "qwen/qwen-plus"}},
+		{Name: "any-team", Match: RuleMatch{Headers: map[string]string{"X-Team": "*"}}, Action: RuleAction{Target: "deepseek"}},
+		{Name: "fallback", Action: RuleAction{Deny: true}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		team string
+		want string
+	}{
+		{team: "search", want: "search"},
+		{team: "ads", want: "any-team"},
+		{want: "fallback"},
+	}
+	for _, tt := range tests {
+		headers := map[string]string{}
+		if tt.team != "" {
+			headers["X-Team"] = tt.team
+		}
+		rule := engine.Match(ruleInput("你好", headers, time.Now()))
+		if rule == nil || rule.Name() != tt.want {
+			t.Errorf("X-Team=%q 匹配规则 %v, 期望 %s", tt.team, rule, tt.want)
+		}
+	}
+
+	var empty *RuleEngine
+	if empty.Match(ruleInput("你好", nil, time.Now())) != nil {
+		t.Error("未配置规则时不应匹配")
+	}
+}
+
+// TestRoutingRuleApply 目标和参数覆盖应用到请求，未配置的参数保持不变
+func TestRoutingRuleApply(t *testing.T) {
+	temperature, maxTokens, reasoning := 0.2, 512, true
+	rule, err := newRoutingRule(RoutingRuleConfig{
+		Name: "override",
+		Action: RuleAction{
+			Target: "qwen/qwen-plus",
+			Parameters: RuleParameters{
+				Temperature:     &temperature,
+				MaxTokens:       &maxTokens,
+				Reasoning:       &reasoning,
+				ReasoningEffort: "high",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topP := 0.9
+	req := &types.UnifiedRequest{
+		Provider:   "deepseek",
+		Model:      "deepseek-chat",
+		Parameters: types.Parameters{MaxTokens: 100, TopP: &topP},
+	}
+	if err := rule.Apply(req, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if req.Provider != "qwen" || req.Model != "qwen-plus" {
+		t.Errorf("目标 %s/%s, 期望 qwen/qwen-plus", req.Provider, req.Model)
+	}
+	params := req.Parameters
+	if params.Temperature == nil || *params.Temperature != 0.2 || params.MaxTokens != 512 ||
+		!params.Reasoning || params.ReasoningEffort != "high" {
+		t.Errorf("参数覆盖不正确: %+v", params)
+	}
+	if params.TopP == nil || *params.TopP != 0.9 {
+		t.Errorf("未配置的top_p应保持不变: %v", params.TopP)
+	}
+
+	invalid, err := newRoutingRule(RoutingRuleConfig{Name: "invalid", Action: RuleAction{Target: "no-such-model"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := invalid.Apply(&types.UnifiedRequest{}, nil); err == nil || !strings.Contains(err.Error(), "目标无效") {
+		t.Errorf("无法解析的目标应返回错误，实际 %v", err)
+	}
+}
+
+// TestRoutingRuleDenied 拒绝规则返回配置的错误信息，未配置时使用默认信息
+func TestRoutingRuleDenied(t *testing.T) {
+	custom, _ := newRoutingRule(RoutingRuleConfig{Name: "night", Action: RuleAction{Deny: true, Message: "夜间不提供服务"}})
+	if denied, message := custom.Denied(); !denied || message != "夜间不提供服务" {
+		t.Errorf("Denied() = %v, %q", denied, message)
+	}
+
+	defaultMessage, _ := newRoutingRule(RoutingRuleConfig{Name: "night", Action: RuleAction{Deny: true}})
+	if denied, message := defaultMessage.Denied(); !denied || !strings.Contains(message, "night") {
+		t.Errorf("Denied() = %v, %q", denied, message)
+	}
+
+	target, _ := newRoutingRule(RoutingRuleConfig{Name: "route", Action: RuleAction{Target: "qwen"}})
+	if denied, _ := target.Denied(); denied {
+		t.Error("非拒绝规则不应拒绝请求")
+	}
+}
@@ -0,0 +1,268 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// SSEEvent 一个完整的Server-Sent Events事件
+type SSEEvent struct {
+	ID    string // id字段
+	Event string // event字段，未指定时为空
+	Data  string // data字段，多行data以换行符拼接
+}
+
+// SSEDecoder SSE流解码器
+// 按照SSE规范解析事件：支持多行data、注释/心跳行(以冒号开头)，且不限制单行长度
+type SSEDecoder struct {
+	reader *bufio.Reader
+}
+
+// NewSSEDecoder 创建SSE解码器
+func NewSSEDecoder(r io.Reader) *SSEDecoder {
+	return &SSEDecoder{
+		reader: bufio.NewReaderSize(r, 64*1024),
+	}
+}
+
+// Next 读取下一个事件，流结束时返回io.EOF
+func (d *SSEDecoder) Next() (*SSEEvent, error) {
+	event := &SSEEvent{}
+	var dataLines []string
+	hasData := false
+
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			// 流结束前的最后一个事件可能没有以空行结尾
+			if err == io.EOF && hasData {
+				event.Data = strings.Join(dataLines, "\n")
+				return event, nil
+			}
+			return nil, err
+		}
+
+		// 空行表示事件结束，没有data的事件(如纯心跳)直接忽略
+		if line == "" {
+			if hasData {
+				event.Data = strings.Join(dataLines, "\n")
+				return event, nil
+			}
+			event = &SSEEvent{}
+			continue
+		}
+
+		// 以冒号开头的是注释行，通常用作keepalive
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "data":
+			dataLines = append(dataLines, value)
+			hasData = true
+		case "event":
+			event.Event = value
+		case "id":
+			event.ID = value
+		}
+	}
+}
+
+// readLine 读取一整行(不含行尾的\r\n)，超长行会被完整拼接
+func (d *SSEDecoder) readLine() (string, error) {
+	var buf bytes.Buffer
+	for {
+		fragment, isPrefix, err := d.reader.ReadLine()
+		if err != nil {
+			if err == io.EOF && buf.Len() > 0 {
+				return buf.String(), nil
+			}
+			return "", err
+		}
+		buf.Write(fragment)
+		if !isPrefix {
+			return strings.TrimSuffix(buf.String(), "\r"), nil
+		}
+	}
+}
+
+// readErrorBody 读取非200响应的部分内容，用于生成错误信息
+func readErrorBody(resp *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return strings.TrimSpace(string(body))
+}
+
+// StreamProgress 记录流中每个候选结果是否已收到完成原因，用于判断流是否完整结束
+// n>1时各候选结果分别完成，只有全部完成才算结束
+type StreamProgress struct {
+	pending  map[int]bool // 已开始输出但尚未收到完成原因的候选结果
+	finished bool         // 至少一个候选结果已收到完成原因
+}
+
+// Observe 记录一个分片中的候选结果
+func (s *StreamProgress) Observe(resp *types.StreamResponse) {
+	if s.pending == nil {
+		s.pending = make(map[int]bool)
+	}
+	for _, choice := range resp.Choices {
+		if choice.FinishReason != "" {
+			delete(s.pending, choice.Index)
+			s.finished = true
+		} else {
+			s.pending[choice.Index] = true
+		}
+	}
+}
+
+// Complete 所有已开始输出的候选结果都已收到完成原因
+func (s *StreamProgress) Complete() bool {
+	return s.finished && len(s.pending) == 0
+}
+
+// interruption 读取上游的流出错或结束时调用
+// 读取出错(连接重置、超时等)或流在所有候选结果完成前结束时返回stream_interrupted错误分片，正常结束时返回nil
+func (s *StreamProgress) interruption(providerName string, err error) *types.StreamResponse {
+	if err == io.EOF && s.Complete() {
+		return nil
+	}
+	return streamInterrupted(providerName, err)
+}
+
+// streamInterrupted 上游的流被中断时的错误分片，err为读取流时的错误
+func streamInterrupted(providerName string, err error) *types.StreamResponse {
+	message := fmt.Sprintf("%s 流式响应在结束前中断", providerName)
+	if err != nil && err != io.EOF {
+		message += ": " + err.Error()
+	}
+	return &types.StreamResponse{
+		Object:  "chat.completion.chunk",
+		Created: time.Now().Unix(),
+		Model:   providerName,
+		Error: &types.Error{
+			Code:    "stream_interrupted",
+			Message: message,
+			Type:    providerName + "_error",
+		},
+	}
+}
+
+// parseOpenAICompatibleStream 解析OpenAI兼容格式的流式响应
+// OpenAI、DeepSeek、月之暗面等兼容厂商共用此实现
+func parseOpenAICompatibleStream(resp *http.Response, providerName string) (<-chan *types.StreamResponse, error) {
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("%s API返回错误状态码: %d, %s", providerName, resp.StatusCode, readErrorBody(resp))
+	}
+
+	responseChan := make(chan *types.StreamResponse)
+
+	go func() {
+		defer close(responseChan)
+		defer resp.Body.Close()
+
+		var progress StreamProgress
+		decoder := NewSSEDecoder(resp.Body)
+		for {
+			event, err := decoder.Next()
+			if err != nil {
+				// 没有收到[DONE]时，只有所有候选结果都已完成才视为正常结束
+				if interrupted := progress.interruption(providerName, err); interrupted != nil {
+					responseChan <- interrupted
+				}
+				return
+			}
+
+			data := strings.TrimSpace(event.Data)
+
+			// 检查是否是结束标志
+			if data == "[DONE]" {
+				return
+			}
+
+			// 解析JSON数据
+			var chunk openAIChatResponse
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				continue // 跳过无效的JSON
+			}
+
+			// 转换为统一格式
+			streamResp := convertOpenAICompatibleChunk(&chunk, providerName)
+			progress.Observe(streamResp)
+			responseChan <- streamResp
+
+			// 流中途返回错误后结束
+			if streamResp.Error != nil {
+				return
+			}
+		}
+	}()
+
+	return responseChan, nil
+}
+
+// convertOpenAICompatibleChunk 转换OpenAI兼容格式的流式分片为统一格式
+func convertOpenAICompatibleChunk(chunk *openAIChatResponse, providerName string) *types.StreamResponse {
+	streamResp := &types.StreamResponse{
+		ID:      string(chunk.ID),
+		Object:  "chat.completion.chunk",
+		Model:   string(chunk.Model),
+		Created: int64(chunk.Created),
+	}
+
+	if streamResp.Model == "" {
+		streamResp.Model = providerName
+	}
+
+	if streamResp.Created == 0 {
+		streamResp.Created = time.Now().Unix()
+	}
+
+	// 流中途的错误对象: {"error":{"message":"...","type":"...","code":"..."}}
+	if chunk.Error != nil {
+		streamResp.Error = upstreamError(providerName, http.StatusOK, string(chunk.Error.Code), string(chunk.Error.Message), string(chunk.Error.Type)).Error
+		if chunk.Error.Code == "" {
+			streamResp.Error.Code = "stream_error"
+		}
+		return streamResp
+	}
+
+	// 提取usage (OpenAI的stream_options.include_usage会在最后一个分片中返回)
+	if chunk.Usage != nil {
+		usage := chunk.Usage.toUsage()
+		streamResp.Usage = &usage
+	}
+
+	// 提取选择，n>1时一个分片可能包含多个候选结果
+	for _, choice := range chunk.Choices {
+		streamResp.Choices = append(streamResp.Choices, types.StreamChoice{
+			Index: int(choice.Index),
+			Delta: types.StreamDelta{
+				Role:      string(choice.Delta.Role),
+				Content:   string(choice.Delta.Content),
+				Reasoning: choice.Delta.reasoning(),
+				ToolCalls: convertOpenAIToolCallDeltas(choice.Delta.ToolCalls),
+			},
+			FinishReason: string(choice.FinishReason),
+		})
+
+		// 月之暗面在choice中返回usage
+		if choice.Usage != nil && streamResp.Usage == nil {
+			usage := choice.Usage.toUsage()
+			streamResp.Usage = &usage
+		}
+	}
+
+	return streamResp
+}
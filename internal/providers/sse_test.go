@@ -0,0 +1,171 @@
+package providers
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// TestSSEDecoder 多行data、注释、CRLF、缺少结尾空行和超长行
+func TestSSEDecoder(t *testing.T) {
+	long := strings.Repeat("x", 200*1024)
+	input := ": keep-alive\n\n" +
+		"event: result\r\nid: 1\r\ndata: a\r\ndata: b\r\n\r\n" +
+		"event: ping\n\n" +
+		"data:" + long + "\n\n" +
+		"data: {\"last\":true}"
+
+	want := []SSEEvent{
+		{ID: "1", Event: "result", Data: "a\nb"},
+		{Data: long},
+		{Data: `{"last":true}`},
+	}
+
+	decoder := NewSSEDecoder(strings.NewReader(input))
+	var got []SSEEvent
+	for {
+		event, err := decoder.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, *event)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("解析结果不一致: 实际 %d 个事件, 期望 %d 个", len(got), len(want))
+	}
+}
+
+// FuzzSSEDecoder 任意输入都必须在有限个事件后以io.EOF结束
+func FuzzSSEDecoder(f *testing.F) {
+	f.Add([]byte("data: hello\n\n"))
+	f.Add([]byte("event: result\nid: 1\ndata: a\ndata: b\n\n"))
+	f.Add([]byte(": keep-alive\r\n\r\ndata: [DONE]\r\n\r\n"))
+	f.Add([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"截"))
+	f.Add([]byte("id:1\nevent:error\n:HTTP_STATUS/400\ndata:{}\n\n"))
+	f.Add([]byte("\n\n\r\r::\ndata\ndata:\n"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decoder := NewSSEDecoder(bytes.NewReader(data))
+		limit := bytes.Count(data, []byte("\n")) + bytes.Count(data, []byte("\r")) + 1
+		for i := 0; ; i++ {
+			if i > limit {
+				t.Fatalf("事件数超过行数: %q", data)
+			}
+
+			event, err := decoder.Next()
+			if err != nil {
+				if err != io.EOF {
+					t.Fatalf("非预期的错误: %v", err)
+				}
+				return
+			}
+			if event == nil {
+				t.Fatal("没有错误时返回了空事件")
+			}
+		}
+	})
+}
+
+// errorReader 读完内容后返回指定错误，模拟连接被重置
+type errorReader struct {
+	data io.Reader
+	err  error
+}
+
+func (r *errorReader) Read(p []byte) (int, error) {
+	n, err := r.data.Read(p)
+	if err == io.EOF {
+		return n, r.err
+	}
+	return n, err
+}
+
+// TestOpenAICompatibleStreamInterrupted 读取出错或流在完成前结束时以stream_interrupted错误结束，正常结束时不输出错误
+func TestOpenAICompatibleStreamInterrupted(t *testing.T) {
+	const (
+		content  = "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"前半\"}}]}\n\n"
+		finished = "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n"
+	)
+
+	tests := []struct {
+		name        string
+		body        io.Reader
+		interrupted bool
+	}{
+		{name: "连接重置", body: &errorReader{data: strings.NewReader(content), err: io.ErrUnexpectedEOF}, interrupted: true},
+		{name: "没有完成原因和[DONE]", body: strings.NewReader(content), interrupted: true},
+		{name: "收到完成原因后结束", body: strings.NewReader(content + finished)},
+		{name: "收到[DONE]", body: strings.NewReader(content + "data: [DONE]\n\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(tt.body)}
+			streamChan, err := parseOpenAICompatibleStream(resp, "openai")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var last *types.StreamResponse
+			for chunk := range streamChan {
+				last = chunk
+			}
+			interrupted := last != nil && last.Error != nil && last.Error.Code == "stream_interrupted"
+			if interrupted != tt.interrupted {
+				t.Errorf("最后一个分片 %+v, 期望中断: %v", last, tt.interrupted)
+			}
+		})
+	}
+}
+
+// TestOpenAICompatibleStreamMultipleChoices n>1时分片中的每个候选结果都被转换，全部完成前结束视为中断
+func TestOpenAICompatibleStreamMultipleChoices(t *testing.T) {
+	const (
+		content = "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"甲\"}},{\"index\":1,\"delta\":{\"content\":\"乙\"}}]}\n\n"
+		first   = "data: {\"id\":\"1\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n"
+		second  = "data: {\"id\":\"1\",\"choices\":[{\"index\":1,\"delta\":{},\"finish_reason\":\"length\"}]}\n\n"
+	)
+
+	tests := []struct {
+		name        string
+		body        string
+		interrupted bool
+	}{
+		{name: "所有候选结果都完成", body: content + first + second},
+		{name: "只有一个候选结果完成", body: content + first, interrupted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(tt.body))}
+			streamChan, err := parseOpenAICompatibleStream(resp, "openai")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var chunks []*types.StreamResponse
+			for chunk := range streamChan {
+				chunks = append(chunks, chunk)
+			}
+			if len(chunks) == 0 || len(chunks[0].Choices) != 2 ||
+				chunks[0].Choices[0].Delta.Content != "甲" || chunks[0].Choices[1].Delta.Content != "乙" {
+				t.Fatalf("第一个分片应包含两个候选结果，实际 %+v", chunks)
+			}
+
+			last := chunks[len(chunks)-1]
+			interrupted := last.Error != nil && last.Error.Code == "stream_interrupted"
+			if interrupted != tt.interrupted {
+				t.Errorf("最后一个分片 %+v, 期望中断: %v", last, tt.interrupted)
+			}
+		})
+	}
+}
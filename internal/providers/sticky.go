@@ -0,0 +1,69 @@
+package providers
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+)
+
+// StickyConfig 会话粘性路由配置
+// 自动选择提供商时，带有会话ID(或用户ID)的请求通过一致性哈希固定到同一个提供商和模型，
+// 该提供商熔断时只有固定到它的会话会迁移到其他提供商
+type StickyConfig struct {
+	Enabled *bool `yaml:"enabled"` // 未配置时默认开启
+}
+
+// IsEnabled 是否开启会话粘性路由
+func (c *StickyConfig) IsEnabled() bool {
+	return c == nil || c.Enabled == nil || *c.Enabled
+}
+
+// RankSticky 按会话key对熔断器未打开的提供商排序，第一个为会话固定的提供商
+func (t *HealthTracker) RankSticky(key string, providers []ProviderAdapter) []ProviderAdapter {
+	return rankRendezvous(key, t.availableProviders(providers), func(string) int { return 1 })
+}
+
+// RankSticky 按会话key和权重对熔断器未打开的提供商排序，权重为0的提供商不参与
+func (wb *WeightedBalancer) RankSticky(key string, providers []ProviderAdapter) []ProviderAdapter {
+	return rankRendezvous(key, wb.availableProviders(providers), wb.weight)
+}
+
+// rankRendezvous 加权最高随机权重(rendezvous)哈希
+// 每个提供商的得分只取决于key和提供商名称，增减提供商时其余会话的固定目标不变
+func rankRendezvous(key string, providers []ProviderAdapter, weight func(providerName string) int) []ProviderAdapter {
+	type scored struct {
+		provider ProviderAdapter
+		score    float64
+	}
+
+	candidates := make([]scored, 0, len(providers))
+	for _, provider := range providers {
+		name := provider.GetProviderName()
+		w := weight(name)
+		if w <= 0 {
+			continue
+		}
+
+		hasher := fnv.New64a()
+		hasher.Write([]byte(key))
+		hasher.Write([]byte{0})
+		hasher.Write([]byte(name))
+
+		// 将哈希映射到(0,1)区间，得分 = 权重 / -ln(u)
+		u := (float64(hasher.Sum64()) + 1) / (math.MaxUint64 + 2.0)
+		candidates = append(candidates, scored{provider: provider, score: float64(w) / -math.Log(u)})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return candidates[i].provider.GetProviderName() < candidates[j].provider.GetProviderName()
+	})
+
+	ranked := make([]ProviderAdapter, len(candidates))
+	for i, c := range candidates {
+		ranked[i] = c.provider
+	}
+	return ranked
+}
@@ -0,0 +1,87 @@
+package providers
+
+import (
+	"fmt"
+	"testing"
+)
+
+// stickyFirst 返回会话固定的提供商名称，没有可用提供商时返回空
+func stickyFirst(lb LoadBalancer, key string, providers []ProviderAdapter) string {
+	ranked := lb.RankSticky(key, providers)
+	if len(ranked) == 0 {
+		return ""
+	}
+	return ranked[0].GetProviderName()
+}
+
+// TestRankStickyStable 同一会话始终固定到同一个提供商，与提供商列表的顺序无关
+func TestRankStickyStable(t *testing.T) {
+	lb := NewRoundRobinBalancer(BreakerConfig{})
+	providers := stubProviders("openai", "deepseek", "qwen", "claude")
+	reversed := stubProviders("claude", "qwen", "deepseek", "openai")
+
+	pinned := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("session-%d", i)
+		first := stickyFirst(lb, key, providers)
+		for j := 0; j < 5; j++ {
+			if got := stickyFirst(lb, key, providers); got != first {
+				t.Fatalf("会话 %s 第%d次固定到 %s, 之前为 %s", key, j+2, got, first)
+			}
+		}
+		if got := stickyFirst(lb, key, reversed); got != first {
+			t.Errorf("会话 %s 在提供商顺序变化后固定到 %s, 之前为 %s", key, got, first)
+		}
+		pinned[first] = true
+	}
+	if len(pinned) < 2 {
+		t.Errorf("不同会话应分散到多个提供商，实际只有 %v", pinned)
+	}
+}
+
+// TestRankStickyRepinOnBreakerOpen 固定的提供商熔断时迁移到排序中的下一个，其余会话不受影响，恢复后回到原提供商
+func TestRankStickyRepinOnBreakerOpen(t *testing.T) {
+	lb := NewRoundRobinBalancer(BreakerConfig{})
+	providers := stubProviders("openai", "deepseek", "qwen", "claude")
+
+	const key = "session-repin"
+	ranked := lb.RankSticky(key, providers)
+	pinned, next := ranked[0].GetProviderName(), ranked[1].GetProviderName()
+
+	before := make(map[string]string)
+	for i := 0; i < 50; i++ {
+		session := fmt.Sprintf("session-%d", i)
+		before[session] = stickyFirst(lb, session, providers)
+	}
+
+	breaker := lb.breaker(breakerKey(pinned, ""))
+	tripNow(breaker)
+	if got := stickyFirst(lb, key, providers); got != next {
+		t.Errorf("%s 熔断后会话固定到 %s, 期望 %s", pinned, got, next)
+	}
+	for session, first := range before {
+		if got := stickyFirst(lb, session, providers); first != pinned && got != first {
+			t.Errorf("会话 %s 没有固定到熔断的提供商，不应迁移: %s -> %s", session, first, got)
+		}
+	}
+
+	halfOpen(breaker)
+	breaker.Record(true)
+	if got := stickyFirst(lb, key, providers); got != pinned {
+		t.Errorf("%s 恢复后会话固定到 %s, 期望回到 %s", pinned, got, pinned)
+	}
+}
+
+// TestRankStickyWeighted 权重为0的提供商不参与会话固定，权重高的提供商固定的会话更多
+func TestRankStickyWeighted(t *testing.T) {
+	lb := NewWeightedBalancer(BreakerConfig{}, map[string]int{"a": 9, "b": 1, "c": 0})
+	providers := stubProviders("a", "b", "c")
+
+	counts := make(map[string]int)
+	for i := 0; i < 200; i++ {
+		counts[stickyFirst(lb, fmt.Sprintf("session-%d", i), providers)]++
+	}
+	if counts["c"] != 0 || counts["a"] <= counts["b"] {
+		t.Errorf("各提供商固定的会话数 %v", counts)
+	}
+}
@@ -0,0 +1,113 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// StructuredOutputConfig 结构化输出配置
+// 请求指定response_format为json_object或json_schema时，网关校验最终输出是否为符合要求的JSON
+type StructuredOutputConfig struct {
+	Retry bool `yaml:"retry"` // 输出不符合要求时是否带上错误原因重试一次(仅非流式请求)
+}
+
+// RetryEnabled 判断输出不符合要求时是否重试
+func (c *StructuredOutputConfig) RetryEnabled() bool {
+	return c != nil && c.Retry
+}
+
+// StructuredOutputError 模型输出不符合结构化输出要求
+type StructuredOutputError struct {
+	Reason string // 不符合要求的原因
+}
+
+// Error 实现error接口
+func (e *StructuredOutputError) Error() string {
+	return "模型输出不符合结构化输出要求: " + e.Reason
+}
+
+// ValidateStructuredOutput 校验模型输出是否为符合response_format要求的JSON
+// 整个输出被Markdown代码块包裹时先去掉代码块，校验通过时返回去掉代码块后的JSON
+func ValidateStructuredOutput(format *types.ResponseFormat, content string) (string, error) {
+	if !format.IsJSON() {
+		return content, nil
+	}
+
+	text := stripCodeFence(content)
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return content, &StructuredOutputError{Reason: "输出不是有效的JSON: " + err.Error()}
+	}
+
+	if schema := format.Schema(); schema != nil {
+		if err := validateJSONSchema(schema, value); err != nil {
+			return content, &StructuredOutputError{Reason: err.Error()}
+		}
+	} else if _, ok := value.(map[string]interface{}); !ok {
+		return content, &StructuredOutputError{Reason: "输出不是JSON对象"}
+	}
+
+	return text, nil
+}
+
+// stripCodeFence 去掉包裹整个输出的Markdown代码块，如 ```json ... ```
+func stripCodeFence(content string) string {
+	text := strings.TrimSpace(content)
+	if !strings.HasPrefix(text, "```") || !strings.HasSuffix(text, "```") || len(text) < 6 {
+		return text
+	}
+
+	text = strings.TrimSuffix(text[3:], "```")
+	// 去掉代码块的语言标识
+	if idx := strings.IndexByte(text, '\n'); idx >= 0 {
+		text = text[idx+1:]
+	}
+	return strings.TrimSpace(text)
+}
+
+// StructuredOutputInstruction 要求模型按response_format输出的提示词
+// 用于没有原生结构化输出的提供商，以及输出不符合要求后的重试
+func StructuredOutputInstruction(format *types.ResponseFormat) string {
+	instruction := "请只输出一个有效的JSON对象，不要输出JSON以外的任何内容，也不要使用Markdown代码块。"
+	if schema := format.Schema(); schema != nil {
+		instruction = fmt.Sprintf("请只输出符合以下JSON Schema的JSON，不要输出JSON以外的任何内容，也不要使用Markdown代码块。\nJSON Schema:\n%s", schema)
+	}
+	return instruction
+}
+
+// withFormatInstruction 将结构化输出要求以提示词的形式追加到system消息中
+// 用于不支持原生结构化输出的提供商(Claude、通义千问、月之暗面、Ollama)和只支持json_object的DeepSeek
+func withFormatInstruction(req *types.UnifiedRequest) *types.UnifiedRequest {
+	format := req.Parameters.ResponseFormat
+	if !format.IsJSON() {
+		return req
+	}
+
+	instruction := StructuredOutputInstruction(format)
+	converted := *req
+	converted.Messages = make([]types.Message, 0, len(req.Messages)+1)
+	if len(req.Messages) > 0 && req.Messages[0].Role == "system" {
+		system := req.Messages[0]
+		system.Content += "\n\n" + instruction
+		converted.Messages = append(converted.Messages, system)
+		converted.Messages = append(converted.Messages, req.Messages[1:]...)
+	} else {
+		converted.Messages = append(converted.Messages, types.Message{Role: "system", Content: instruction})
+		converted.Messages = append(converted.Messages, req.Messages...)
+	}
+	return &converted
+}
+
+// openAIResponseFormat 生成OpenAI格式的response_format，json_schema未指定名称时使用response
+func openAIResponseFormat(format *types.ResponseFormat) *types.ResponseFormat {
+	if format.Type != types.ResponseFormatJSONSchema || format.JSONSchema == nil || format.JSONSchema.Name != "" {
+		return format
+	}
+
+	schema := *format.JSONSchema
+	schema.Name = "response"
+	return &types.ResponseFormat{Type: format.Type, JSONSchema: &schema}
+}
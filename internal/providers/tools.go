@@ -0,0 +1,148 @@
+package providers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// openAIToolCall OpenAI兼容格式的工具调用，同时用于非流式响应和流式增量
+type openAIToolCall struct {
+	Index    flexInt    `json:"index"` // 流式增量中的序号
+	ID       flexString `json:"id"`
+	Type     flexString `json:"type"`
+	Function struct {
+		Name      flexString `json:"name"`
+		Arguments flexJSON   `json:"arguments"`
+	} `json:"function"`
+}
+
+// applyOpenAITools 将工具定义和工具选择策略添加到OpenAI兼容格式的请求中
+// OpenAI、DeepSeek、月之暗面、通义千问共用此实现
+func applyOpenAITools(body map[string]interface{}, req *types.UnifiedRequest) {
+	if len(req.Tools) == 0 {
+		return
+	}
+
+	body["tools"] = req.Tools
+	if req.ToolChoice != nil {
+		body["tool_choice"] = req.ToolChoice
+	}
+}
+
+// convertOpenAIToolCalls 转换OpenAI兼容格式的工具调用
+func convertOpenAIToolCalls(calls []openAIToolCall) []types.ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	toolCalls := make([]types.ToolCall, len(calls))
+	for i, call := range calls {
+		toolCalls[i] = types.ToolCall{
+			ID:   string(call.ID),
+			Type: "function",
+			Function: types.FunctionCall{
+				Name:      string(call.Function.Name),
+				Arguments: string(call.Function.Arguments),
+			},
+		}
+	}
+	return toolCalls
+}
+
+// convertOpenAIToolCallDeltas 转换OpenAI兼容格式的工具调用增量
+func convertOpenAIToolCallDeltas(calls []openAIToolCall) []types.ToolCallDelta {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	deltas := make([]types.ToolCallDelta, len(calls))
+	for i, call := range calls {
+		deltas[i] = types.ToolCallDelta{
+			Index: int(call.Index),
+			ID:    string(call.ID),
+			Type:  string(call.Type),
+			Function: types.FunctionCallDelta{
+				Name:      string(call.Function.Name),
+				Arguments: string(call.Function.Arguments),
+			},
+		}
+	}
+	return deltas
+}
+
+// completeToolCallDeltas 将完整的工具调用转换为流式增量，count为之前已输出的工具调用数
+// 用于不分片返回工具调用的上游(Gemini、Ollama)
+func completeToolCallDeltas(calls []types.ToolCall, count *int) []types.ToolCallDelta {
+	if len(calls) == 0 {
+		return nil
+	}
+
+	deltas := make([]types.ToolCallDelta, len(calls))
+	for i, call := range calls {
+		deltas[i] = types.ToolCallDelta{
+			Index: *count,
+			ID:    call.ID,
+			Type:  call.Type,
+			Function: types.FunctionCallDelta{
+				Name:      call.Function.Name,
+				Arguments: call.Function.Arguments,
+			},
+		}
+		*count++
+	}
+	return deltas
+}
+
+// toolCallNames 建立工具调用ID到函数名称的映射
+// Gemini和Ollama的工具结果以函数名称关联调用，需要从之前的assistant消息中查找
+func toolCallNames(messages []types.Message) map[string]string {
+	names := make(map[string]string)
+	for _, msg := range messages {
+		for _, call := range msg.ToolCalls {
+			names[call.ID] = call.Function.Name
+		}
+	}
+	return names
+}
+
+// toolArguments 将JSON格式的函数参数解析为对象，参数为空或无效时返回空对象
+// Claude、Gemini和Ollama的函数参数是对象而不是字符串
+func toolArguments(arguments string) json.RawMessage {
+	var args map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil || args == nil {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(arguments)
+}
+
+// toolArgumentsText 将对象形式的函数参数转换为JSON字符串，参数为空时返回空对象
+func toolArgumentsText(args json.RawMessage) string {
+	if len(args) == 0 || string(args) == "null" {
+		return "{}"
+	}
+	return string(args)
+}
+
+// toolResult 将tool消息的内容转换为对象，内容不是JSON对象时放在content字段中
+// Gemini的functionResponse.response必须是对象
+func toolResult(content string) interface{} {
+	var result map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(content), &result); err == nil && result != nil {
+		return json.RawMessage(content)
+	}
+	return map[string]string{"content": content}
+}
+
+// generateToolCallID 为不返回调用ID的上游(Gemini、Ollama)生成随机的工具调用ID
+func generateToolCallID() string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("call_%d", time.Now().UnixNano())
+	}
+	return "call_" + hex.EncodeToString(buf)
+}
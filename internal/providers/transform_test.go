@@ -0,0 +1,187 @@
+package providers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/heyanxiao/llm-bridge/pkg/types"
+)
+
+// samplingPaths 各提供商请求体中温度和top_p参数的位置
+var samplingPaths = []struct {
+	provider    string
+	temperature []string
+	topP        []string
+}{
+	{"openai", []string{"temperature"}, []string{"top_p"}},
+	{"deepseek", []string{"temperature"}, []string{"top_p"}},
+	{"moonshot", []string{"temperature"}, []string{"top_p"}},
+	{"qwen", []string{"parameters", "temperature"}, []string{"parameters", "top_p"}},
+	{"claude", []string{"temperature"}, []string{"top_p"}},
+	{"gemini", []string{"generationConfig", "temperature"}, []string{"generationConfig", "topP"}},
+	{"ollama", []string{"options", "temperature"}, []string{"options", "top_p"}},
+}
+
+// newTransformProvider 创建用于测试请求转换的提供商
+func newTransformProvider(name string) ProviderAdapter {
+	for _, provider := range goldenProviders {
+		if provider.name == name {
+			return provider.adapter()
+		}
+	}
+	return nil
+}
+
+// lookupPath 按路径读取JSON对象中的值
+func lookupPath(body map[string]interface{}, path []string) (interface{}, bool) {
+	var current interface{} = body
+	for _, key := range path {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// TestTransformExplicitZeroSampling 显式指定的temperature和top_p为0时也要转发给上游，未指定时不转发
+func TestTransformExplicitZeroSampling(t *testing.T) {
+	zero := 0.0
+	for _, tt := range samplingPaths {
+		t.Run(tt.provider, func(t *testing.T) {
+			provider := newTransformProvider(tt.provider)
+			model := GetDefaultModel(tt.provider)
+			if model == "" {
+				// Ollama的模型列表在运行时获取，没有默认模型
+				model = "llama3"
+			}
+			req := &types.UnifiedRequest{
+				Provider: tt.provider,
+				Model:    model,
+				Messages: []types.Message{{Role: "user", Content: "你好"}},
+			}
+
+			for _, explicit := range []bool{true, false} {
+				if explicit {
+					req.Parameters.Temperature, req.Parameters.TopP = &zero, &zero
+				} else {
+					req.Parameters.Temperature, req.Parameters.TopP = nil, nil
+				}
+
+				if err := provider.ValidateRequest(req); err != nil {
+					t.Fatalf("ValidateRequest: %v", err)
+				}
+				data, err := provider.Transform(req)
+				if err != nil {
+					t.Fatalf("Transform: %v", err)
+				}
+				var body map[string]interface{}
+				if err := json.Unmarshal(data, &body); err != nil {
+					t.Fatal(err)
+				}
+
+				for _, path := range [][]string{tt.temperature, tt.topP} {
+					value, exists := lookupPath(body, path)
+					if explicit && (!exists || value != 0.0) {
+						t.Errorf("%v 应为0，实际 %v (存在: %v)", path, value, exists)
+					}
+					if !explicit && exists {
+						t.Errorf("未指定时不应转发 %v", path)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestGeminiSchemaInlinesRefs $ref在移除$defs前被内联，递归引用返回错误
+func TestGeminiSchemaInlinesRefs(t *testing.T) {
+	schema := json.RawMessage(`{
+		"type": "object",
+		"$defs": {
+			"Address": {"type": "object", "properties": {"city": {"$ref": "#/definitions/City"}}, "additionalProperties": false},
+			"Tag": {"type": "string"}
+		},
+		"definitions": {"City": {"type": "string", "description": "城市"}},
+		"properties": {
+			"home": {"$ref": "#/$defs/Address", "description": "住址"},
+			"tags": {"type": "array", "items": {"$ref": "#/$defs/Tag"}}
+		}
+	}`)
+
+	got, err := geminiSchema(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(got)
+	want := `{"properties":{"home":{"description":"住址","properties":{"city":{"description":"城市","type":"string"}},"type":"object"},` +
+		`"tags":{"items":{"type":"string"},"type":"array"}},"type":"object"}`
+	if string(data) != want {
+		t.Errorf("geminiSchema() = %s\n期望 %s", data, want)
+	}
+
+	for _, invalid := range []string{
+		`{"$defs": {"Node": {"type": "object", "properties": {"children": {"type": "array", "items": {"$ref": "#/$defs/Node"}}}}}, "$ref": "#/$defs/Node"}`,
+		`{"type": "object", "properties": {"a": {"$ref": "#/$defs/Missing"}}}`,
+		`{"type": "object", "properties": {"a": {"$ref": "https://example.com/schema.json"}}}`,
+	} {
+		if _, err := geminiSchema(json.RawMessage(invalid)); err == nil {
+			t.Errorf("无法内联的schema应返回错误: %s", invalid)
+		}
+	}
+}
+
+// TestGeminiValidateRecursiveSchema 无法转换的schema在校验请求时拒绝
+func TestGeminiValidateRecursiveSchema(t *testing.T) {
+	provider := NewGeminiProvider(&GeminiConfig{})
+	req := &types.UnifiedRequest{
+		Model:    "gemini-2.5-flash",
+		Messages: []types.Message{{Role: "user", Content: "你好"}},
+		Parameters: types.Parameters{ResponseFormat: &types.ResponseFormat{
+			Type: types.ResponseFormatJSONSchema,
+			JSONSchema: &types.JSONSchemaFormat{
+				Name:   "tree",
+				Schema: json.RawMessage(`{"type": "object", "properties": {"child": {"$ref": "#"}}}`),
+			},
+		}},
+	}
+	if err := provider.ValidateRequest(req); err == nil {
+		t.Error("递归的schema应校验失败")
+	}
+}
+
+// TestGeminiStreamMultipleCandidates 指定candidateCount时每个候选结果都被转换
+func TestGeminiStreamMultipleCandidates(t *testing.T) {
+	body := `data: {"candidates":[{"content":{"role":"model","parts":[{"text":"甲"}]},"index":0},{"content":{"role":"model","parts":[{"text":"乙"}]},"index":1}]}` + "\n\n" +
+		`data: {"candidates":[{"content":{"role":"model","parts":[{"text":""}]},"finishReason":"STOP","index":0},{"finishReason":"MAX_TOKENS","index":1}],"usageMetadata":{"promptTokenCount":1,"candidatesTokenCount":2,"totalTokenCount":3}}` + "\n\n"
+
+	provider := NewGeminiProvider(&GeminiConfig{})
+	resp := &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}
+	streamChan, err := provider.ParseStreamResponse(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := make(map[int]string)
+	finish := make(map[int]string)
+	for chunk := range streamChan {
+		if chunk.Error != nil {
+			t.Fatalf("不应返回错误: %+v", chunk.Error)
+		}
+		for _, choice := range chunk.Choices {
+			content[choice.Index] += choice.Delta.Content
+			if choice.FinishReason != "" {
+				finish[choice.Index] = choice.FinishReason
+			}
+		}
+	}
+	if content[0] != "甲" || content[1] != "乙" || finish[0] != "stop" || finish[1] != "length" {
+		t.Errorf("内容 %v, 完成原因 %v", content, finish)
+	}
+}
@@ -116,6 +116,43 @@ func (m *RedisMetrics) IncrementRequest(provider string, responseTime time.Durat
 	pipe.Exec(ctx)
 }
 
+// RecordHedgeAttempt 记录对冲请求中的一次调用，won表示该调用的结果被采用
+func (m *RedisMetrics) RecordHedgeAttempt(provider string, responseTime time.Duration, won bool) {
+	if m.client == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	pipe := m.client.Pipeline()
+
+	pipe.Incr(ctx, "stats:hedge:attempts")
+	pipe.Incr(ctx, fmt.Sprintf("stats:provider:%s:hedge_attempts", provider))
+	pipe.IncrBy(ctx, fmt.Sprintf("stats:provider:%s:hedge_response_time", provider), responseTime.Milliseconds())
+	if won {
+		pipe.Incr(ctx, "stats:hedge:wins")
+		pipe.Incr(ctx, fmt.Sprintf("stats:provider:%s:hedge_wins", provider))
+	}
+
+	pipe.Exec(ctx)
+}
+
+// GetProviderHedgeStats 获取提供商的对冲请求统计
+func (m *RedisMetrics) GetProviderHedgeStats(provider string) (attempts int64, wins int64) {
+	if m.client == nil {
+		return 0, 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	attempts, _ = m.client.Get(ctx, fmt.Sprintf("stats:provider:%s:hedge_attempts", provider)).Int64()
+	wins, _ = m.client.Get(ctx, fmt.Sprintf("stats:provider:%s:hedge_wins", provider)).Int64()
+
+	return
+}
+
 // GetStats 获取统计数据
 func (m *RedisMetrics) GetStats() (totalRequests int64, avgResponseTime int64, totalTokens int64, uptime time.Duration) {
 	if m.client == nil {
@@ -0,0 +1,197 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// AnthropicRequest Anthropic Messages API格式的请求
+type AnthropicRequest struct {
+	Model         string             `json:"model"`
+	System        json.RawMessage    `json:"system"` // 字符串或文本内容块数组
+	Messages      []AnthropicMessage `json:"messages"`
+	MaxTokens     int                `json:"max_tokens"`
+	StopSequences []string           `json:"stop_sequences"`
+	Temperature   *float64           `json:"temperature"`
+	TopP          *float64           `json:"top_p"`
+	Stream        bool               `json:"stream"`
+	Metadata      struct {
+		UserID string `json:"user_id"`
+	} `json:"metadata"`
+	Thinking *struct {
+		Type         string `json:"type"` // enabled 或 disabled
+		BudgetTokens int    `json:"budget_tokens"`
+	} `json:"thinking"`
+	Tools      []AnthropicTool `json:"tools"`
+	ToolChoice *struct {
+		Type string `json:"type"` // auto、any、tool、none
+		Name string `json:"name"`
+	} `json:"tool_choice"`
+}
+
+// AnthropicMessage Anthropic格式的消息，content为字符串或内容块数组
+type AnthropicMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// AnthropicContentBlock Anthropic格式的内容块
+type AnthropicContentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	ID        string          `json:"id"`          // tool_use
+	Name      string          `json:"name"`        // tool_use
+	Input     json.RawMessage `json:"input"`       // tool_use
+	ToolUseID string          `json:"tool_use_id"` // tool_result
+	Content   json.RawMessage `json:"content"`     // tool_result，字符串或文本内容块数组
+}
+
+// AnthropicTool Anthropic格式的工具定义，服务端工具(type不为custom)会被忽略
+type AnthropicTool struct {
+	Type        string          `json:"type"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// ToUnified 转换为统一请求格式，system作为第一条system消息
+func (r *AnthropicRequest) ToUnified() (UnifiedRequest, error) {
+	req := UnifiedRequest{
+		Model:  r.Model,
+		Format: FormatAnthropic,
+		Parameters: Parameters{
+			MaxTokens: r.MaxTokens,
+			Stop:      r.StopSequences,
+			Stream:    r.Stream,
+		},
+		Metadata: Metadata{UserID: r.Metadata.UserID},
+	}
+
+	system, err := contentText(r.System)
+	if err != nil {
+		return req, fmt.Errorf("system格式错误: %w", err)
+	}
+
+	req.Messages = make([]Message, 0, len(r.Messages)+1)
+	if system != "" {
+		req.Messages = append(req.Messages, Message{Role: "system", Content: system})
+	}
+	for i, msg := range r.Messages {
+		messages, err := msg.toMessages()
+		if err != nil {
+			return req, fmt.Errorf("第 %d 条消息格式错误: %w", i+1, err)
+		}
+		req.Messages = append(req.Messages, messages...)
+	}
+
+	for _, tool := range r.Tools {
+		if tool.Type != "" && tool.Type != "custom" {
+			continue
+		}
+		req.Tools = append(req.Tools, Tool{
+			Type:     "function",
+			Function: ToolFunction{Name: tool.Name, Description: tool.Description, Parameters: tool.InputSchema},
+		})
+	}
+
+	if r.ToolChoice != nil {
+		switch r.ToolChoice.Type {
+		case "auto":
+			req.ToolChoice = &ToolChoice{Mode: ToolChoiceAuto}
+		case "any":
+			req.ToolChoice = &ToolChoice{Mode: ToolChoiceRequired}
+		case "none":
+			req.ToolChoice = &ToolChoice{Mode: ToolChoiceNone}
+		case "tool":
+			req.ToolChoice = &ToolChoice{Mode: ToolChoiceFunction, Function: r.ToolChoice.Name}
+		}
+	}
+
+	if r.Temperature != nil {
+		req.Parameters.Temperature = r.Temperature
+	}
+	if r.TopP != nil {
+		req.Parameters.TopP = r.TopP
+	}
+
+	if r.Thinking != nil && r.Thinking.Type == "enabled" {
+		req.Parameters.Reasoning = true
+		req.Parameters.ReasoningEffort = reasoningEffortForBudget(r.Thinking.BudgetTokens)
+	}
+
+	// 流式响应的message_delta事件需要输出token数
+	if r.Stream {
+		req.Parameters.IncludeUsage = true
+	}
+
+	return req, nil
+}
+
+// toMessages 转换为统一格式的消息
+// tool_use内容块转换为assistant消息的工具调用，tool_result内容块转换为tool消息并排在同一条消息的文本之前，
+// 思考过程被忽略，图片、文档等其他内容块返回错误
+func (m *AnthropicMessage) toMessages() ([]Message, error) {
+	var text string
+	if err := json.Unmarshal(m.Content, &text); err == nil {
+		return []Message{{Role: m.Role, Content: text}}, nil
+	}
+
+	var blocks []AnthropicContentBlock
+	if err := json.Unmarshal(m.Content, &blocks); err != nil {
+		return nil, err
+	}
+
+	var messages []Message
+	var texts []string
+	var toolCalls []ToolCall
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			texts = append(texts, block.Text)
+		case "tool_use":
+			arguments := "{}"
+			if len(block.Input) > 0 {
+				arguments = string(block.Input)
+			}
+			toolCalls = append(toolCalls, ToolCall{
+				ID:       block.ID,
+				Type:     "function",
+				Function: FunctionCall{Name: block.Name, Arguments: arguments},
+			})
+		case "tool_result":
+			result, err := contentText(block.Content)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, Message{Role: "tool", Content: result, ToolCallID: block.ToolUseID})
+		case "thinking", "redacted_thinking":
+			// 历史消息中的思考过程不需要再发给模型
+		default:
+			return nil, unsupportedPartError(block.Type)
+		}
+	}
+
+	if len(texts) > 0 || len(toolCalls) > 0 || len(messages) == 0 {
+		messages = append(messages, Message{
+			Role:      m.Role,
+			Content:   strings.Join(texts, "\n"),
+			ToolCalls: toolCalls,
+		})
+	}
+	return messages, nil
+}
+
+// reasoningEffortForBudget 按Claude提供商的换算规则将思考token预算对应到推理强度，未指定预算时返回空
+func reasoningEffortForBudget(budget int) string {
+	switch {
+	case budget <= 0:
+		return ""
+	case budget <= 1024:
+		return "low"
+	case budget >= 16384:
+		return "high"
+	default:
+		return "medium"
+	}
+}
@@ -0,0 +1,171 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 请求格式
+const (
+	FormatUnified   = "unified"   // 网关原有格式，响应中带有provider字段，由 X-LLM-Bridge-Format: unified 请求头选择
+	FormatOpenAI    = "openai"    // OpenAI Chat Completions格式，/v1/chat/completions 的默认格式
+	FormatAnthropic = "anthropic" // Anthropic Messages格式，由 /v1/messages 接入
+	FormatGemini    = "gemini"    // Gemini generateContent格式，由 /v1beta/models/{model}:generateContent 接入
+)
+
+// openAIRequestFields OpenAI Chat Completions格式中位于顶层的参数
+type openAIRequestFields struct {
+	Temperature         *float64        `json:"temperature"`
+	MaxTokens           *int            `json:"max_tokens"`
+	MaxCompletionTokens *int            `json:"max_completion_tokens"`
+	TopP                *float64        `json:"top_p"`
+	Stream              *bool           `json:"stream"`
+	Stop                json.RawMessage `json:"stop"` // 字符串或字符串数组
+	N                   *int            `json:"n"`
+	Seed                *int            `json:"seed"`
+	User                string          `json:"user"`
+	FrequencyPenalty    *float64        `json:"frequency_penalty"`
+	PresencePenalty     *float64        `json:"presence_penalty"`
+	ReasoningEffort     string          `json:"reasoning_effort"`
+	StreamOptions       *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+	ResponseFormat *ResponseFormat `json:"response_format"`
+}
+
+// UnmarshalJSON 同时读取网关原有格式(parameters)和OpenAI Chat Completions格式(顶层)的参数，顶层参数优先
+// 响应格式不由请求体决定，由接入的接口和请求头设置Format
+func (r *UnifiedRequest) UnmarshalJSON(data []byte) error {
+	type unifiedRequest UnifiedRequest
+	var base unifiedRequest
+	if err := json.Unmarshal(data, &base); err != nil {
+		return err
+	}
+
+	var top openAIRequestFields
+	if err := json.Unmarshal(data, &top); err != nil {
+		return err
+	}
+
+	*r = UnifiedRequest(base)
+
+	params := &r.Parameters
+	if top.Temperature != nil {
+		params.Temperature = top.Temperature
+	}
+	if top.MaxCompletionTokens != nil {
+		params.MaxTokens = *top.MaxCompletionTokens
+	} else if top.MaxTokens != nil {
+		params.MaxTokens = *top.MaxTokens
+	}
+	if top.TopP != nil {
+		params.TopP = top.TopP
+	}
+	if top.Stream != nil {
+		params.Stream = *top.Stream
+	}
+	if top.N != nil {
+		params.N = *top.N
+	}
+	if top.Seed != nil {
+		params.Seed = top.Seed
+	}
+	if top.FrequencyPenalty != nil {
+		params.FrequencyPenalty = *top.FrequencyPenalty
+	}
+	if top.PresencePenalty != nil {
+		params.PresencePenalty = *top.PresencePenalty
+	}
+	if top.ReasoningEffort != "" {
+		params.ReasoningEffort = top.ReasoningEffort
+	}
+	if top.StreamOptions != nil {
+		params.IncludeUsage = top.StreamOptions.IncludeUsage
+	}
+	if top.ResponseFormat != nil {
+		params.ResponseFormat = top.ResponseFormat
+	}
+	if len(top.Stop) > 0 && string(top.Stop) != "null" {
+		var stop string
+		if err := json.Unmarshal(top.Stop, &stop); err == nil {
+			params.Stop = []string{stop}
+		} else if err := json.Unmarshal(top.Stop, &params.Stop); err != nil {
+			return err
+		}
+	}
+
+	if r.Metadata.UserID == "" {
+		r.Metadata.UserID = top.User
+	}
+
+	return nil
+}
+
+// UnmarshalJSON 消息内容支持字符串和内容片段数组(OpenAI和Anthropic格式的文本片段相同)，片段数组中的文本按换行拼接
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type message Message
+	var raw struct {
+		message
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*m = Message(raw.message)
+	content, err := contentText(raw.Content)
+	if err != nil {
+		return err
+	}
+	m.Content = content
+	return nil
+}
+
+// MarshalJSON 只有工具调用的assistant消息content输出为null，与OpenAI格式一致
+func (m Message) MarshalJSON() ([]byte, error) {
+	type message Message
+	if m.Content != "" || len(m.ToolCalls) == 0 {
+		return json.Marshal(message(m))
+	}
+
+	return json.Marshal(struct {
+		message
+		Content *string `json:"content"`
+	}{message: message(m)})
+}
+
+// contentText 读取字符串或内容片段数组形式的内容，片段数组中的文本按换行拼接
+// 网关只转发文本，图片、音频、文件等其他类型的片段返回错误，避免静默丢弃后模型按缺失的内容回答
+func contentText(data json.RawMessage) (string, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return "", nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		return text, nil
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return "", err
+	}
+
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Type != "text" {
+			return "", unsupportedPartError(part.Type)
+		}
+		texts = append(texts, part.Text)
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// unsupportedPartError 内容片段类型不受支持时的错误
+func unsupportedPartError(partType string) error {
+	return fmt.Errorf("不支持的内容片段类型 %q，目前只支持文本", partType)
+}
@@ -0,0 +1,81 @@
+package types
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestUnsupportedContentParts 图片等非文本片段返回错误，不能被静默丢弃
+func TestUnsupportedContentParts(t *testing.T) {
+	tests := []struct {
+		name   string
+		decode func() error
+	}{
+		{
+			name: "OpenAI image_url",
+			decode: func() error {
+				var req UnifiedRequest
+				return json.Unmarshal([]byte(`{"model":"gpt-4o","messages":[{"role":"user","content":[
+					{"type":"text","text":"这是什么"},
+					{"type":"image_url","image_url":{"url":"https://example.com/a.png"}}]}]}`), &req)
+			},
+		},
+		{
+			name: "Anthropic image",
+			decode: func() error {
+				var req AnthropicRequest
+				if err := json.Unmarshal([]byte(`{"model":"claude","max_tokens":10,"messages":[{"role":"user","content":[
+					{"type":"image","source":{"type":"base64","media_type":"image/png","data":"AAAA"}},
+					{"type":"text","text":"这是什么"}]}]}`), &req); err != nil {
+					return err
+				}
+				_, err := req.ToUnified()
+				return err
+			},
+		},
+		{
+			name: "Gemini inlineData",
+			decode: func() error {
+				var req GeminiRequest
+				return json.Unmarshal([]byte(`{"contents":[{"role":"user","parts":[
+					{"text":"这是什么"},
+					{"inlineData":{"mimeType":"image/png","data":"AAAA"}}]}]}`), &req)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.decode()
+			if err == nil || !strings.Contains(err.Error(), "不支持的内容片段类型") {
+				t.Errorf("应返回不支持的内容片段错误，实际 %v", err)
+			}
+		})
+	}
+}
+
+// TestTextContentParts 文本片段按换行拼接，Anthropic历史消息中的思考过程被忽略
+func TestTextContentParts(t *testing.T) {
+	var req UnifiedRequest
+	if err := json.Unmarshal([]byte(`{"model":"gpt-4o","messages":[{"role":"user","content":[
+		{"type":"text","text":"第一行"},{"type":"text","text":"第二行"}]}]}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Messages[0].Content; got != "第一行\n第二行" {
+		t.Errorf("content = %q", got)
+	}
+
+	var anthropicReq AnthropicRequest
+	if err := json.Unmarshal([]byte(`{"model":"claude","max_tokens":10,"messages":[{"role":"assistant","content":[
+		{"type":"thinking","thinking":"...","signature":"sig"},{"type":"text","text":"回答"}]}]}`), &anthropicReq); err != nil {
+		t.Fatal(err)
+	}
+	unified, err := anthropicReq.ToUnified()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := unified.Messages[0].Content; got != "回答" {
+		t.Errorf("content = %q", got)
+	}
+}
@@ -0,0 +1,63 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// 输出格式类型
+const (
+	ResponseFormatText       = "text"        // 普通文本
+	ResponseFormatJSONObject = "json_object" // 任意JSON对象
+	ResponseFormatJSONSchema = "json_schema" // 符合指定JSON Schema的JSON
+)
+
+// ResponseFormat 结构化输出格式，JSON格式与OpenAI的response_format一致
+type ResponseFormat struct {
+	Type       string            `json:"type"`                  // text、json_object、json_schema
+	JSONSchema *JSONSchemaFormat `json:"json_schema,omitempty"` // type为json_schema时的schema定义
+}
+
+// JSONSchemaFormat 结构化输出的JSON Schema定义
+type JSONSchemaFormat struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Schema      json.RawMessage `json:"schema"`
+	Strict      *bool           `json:"strict,omitempty"`
+}
+
+// IsJSON 判断是否要求输出JSON
+func (f *ResponseFormat) IsJSON() bool {
+	return f != nil && (f.Type == ResponseFormatJSONObject || f.Type == ResponseFormatJSONSchema)
+}
+
+// Schema 返回要求输出符合的JSON Schema，未指定时返回nil
+func (f *ResponseFormat) Schema() json.RawMessage {
+	if f == nil || f.Type != ResponseFormatJSONSchema || f.JSONSchema == nil {
+		return nil
+	}
+	return f.JSONSchema.Schema
+}
+
+// Validate 校验输出格式，未指定时视为普通文本
+func (f *ResponseFormat) Validate() error {
+	if f == nil {
+		return nil
+	}
+
+	switch f.Type {
+	case ResponseFormatText, ResponseFormatJSONObject:
+		return nil
+	case ResponseFormatJSONSchema:
+		if f.JSONSchema == nil || len(f.JSONSchema.Schema) == 0 {
+			return fmt.Errorf("json_schema格式缺少schema定义")
+		}
+		var schema map[string]interface{}
+		if err := json.Unmarshal(f.JSONSchema.Schema, &schema); err != nil || schema == nil {
+			return fmt.Errorf("json_schema.schema必须是JSON对象")
+		}
+		return nil
+	default:
+		return fmt.Errorf("不支持的response_format类型: %s", f.Type)
+	}
+}
@@ -0,0 +1,345 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// GeminiRequest Gemini generateContent格式的请求，模型名称和是否流式由URL决定
+type GeminiRequest struct {
+	Contents          []GeminiContent        `json:"contents"`
+	SystemInstruction *GeminiContent         `json:"systemInstruction"`
+	GenerationConfig  GeminiGenerationConfig `json:"generationConfig"`
+	Tools             []struct {
+		FunctionDeclarations []GeminiFunctionDeclaration `json:"functionDeclarations"`
+	} `json:"tools"`
+	ToolConfig *struct {
+		FunctionCallingConfig struct {
+			Mode                 string   `json:"mode"` // AUTO、ANY、NONE
+			AllowedFunctionNames []string `json:"allowedFunctionNames"`
+		} `json:"functionCallingConfig"`
+	} `json:"toolConfig"`
+}
+
+// GeminiFunctionDeclaration Gemini格式的函数定义，参数使用OpenAPI Schema或JSON Schema描述
+type GeminiFunctionDeclaration struct {
+	Name                 string          `json:"name"`
+	Description          string          `json:"description"`
+	Parameters           json.RawMessage `json:"parameters"`
+	ParametersJSONSchema json.RawMessage `json:"parametersJsonSchema"`
+}
+
+// GeminiContent Gemini格式的一条消息，角色为user或model
+type GeminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []GeminiPart `json:"parts"`
+}
+
+// GeminiPart Gemini格式的内容片段，thought为true时是思考过程
+type GeminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// UnmarshalJSON 网关只转发文本和函数调用，图片、文件等其他类型的片段返回错误
+func (p *GeminiPart) UnmarshalJSON(data []byte) error {
+	type geminiPart GeminiPart
+	var raw struct {
+		geminiPart
+		InlineData          json.RawMessage `json:"inlineData"`
+		FileData            json.RawMessage `json:"fileData"`
+		ExecutableCode      json.RawMessage `json:"executableCode"`
+		CodeExecutionResult json.RawMessage `json:"codeExecutionResult"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	unsupported := []struct {
+		partType string
+		value    json.RawMessage
+	}{
+		{"inlineData", raw.InlineData},
+		{"fileData", raw.FileData},
+		{"executableCode", raw.ExecutableCode},
+		{"codeExecutionResult", raw.CodeExecutionResult},
+	}
+	for _, part := range unsupported {
+		if len(part.value) > 0 && string(part.value) != "null" {
+			return unsupportedPartError(part.partType)
+		}
+	}
+
+	*p = GeminiPart(raw.geminiPart)
+	return nil
+}
+
+// GeminiFunctionCall Gemini格式的函数调用
+type GeminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+// GeminiFunctionResponse Gemini格式的函数调用结果
+type GeminiFunctionResponse struct {
+	ID       string          `json:"id,omitempty"`
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response"`
+}
+
+// GeminiGenerationConfig Gemini格式的生成配置
+type GeminiGenerationConfig struct {
+	Temperature      *float64 `json:"temperature"`
+	MaxOutputTokens  int      `json:"maxOutputTokens"`
+	TopP             *float64 `json:"topP"`
+	StopSequences    []string `json:"stopSequences"`
+	CandidateCount   int      `json:"candidateCount"`
+	Seed             *int     `json:"seed"`
+	PresencePenalty  *float64 `json:"presencePenalty"`
+	FrequencyPenalty *float64 `json:"frequencyPenalty"`
+	ThinkingConfig   *struct {
+		IncludeThoughts bool `json:"includeThoughts"`
+		ThinkingBudget  int  `json:"thinkingBudget"`
+	} `json:"thinkingConfig"`
+	ResponseMimeType   string          `json:"responseMimeType"`
+	ResponseSchema     json.RawMessage `json:"responseSchema"`     // OpenAPI Schema
+	ResponseJSONSchema json.RawMessage `json:"responseJsonSchema"` // JSON Schema
+}
+
+// ToUnified 转换为统一请求格式，systemInstruction作为第一条system消息，model角色转换为assistant
+func (r *GeminiRequest) ToUnified(model string, stream bool) UnifiedRequest {
+	config := r.GenerationConfig
+	req := UnifiedRequest{
+		Model:  model,
+		Format: FormatGemini,
+		Parameters: Parameters{
+			MaxTokens: config.MaxOutputTokens,
+			Stop:      config.StopSequences,
+			Stream:    stream,
+			N:         config.CandidateCount,
+			Seed:      config.Seed,
+		},
+	}
+
+	req.Messages = make([]Message, 0, len(r.Contents)+1)
+	if r.SystemInstruction != nil {
+		if system := r.SystemInstruction.text(); system != "" {
+			req.Messages = append(req.Messages, Message{Role: "system", Content: system})
+		}
+	}
+	calls := &geminiCallIDs{pending: make(map[string][]string)}
+	for _, content := range r.Contents {
+		req.Messages = append(req.Messages, content.toMessages(calls)...)
+	}
+
+	for _, tool := range r.Tools {
+		for _, declaration := range tool.FunctionDeclarations {
+			parameters := declaration.ParametersJSONSchema
+			if len(parameters) == 0 {
+				parameters = lowerSchemaTypes(declaration.Parameters)
+			}
+			req.Tools = append(req.Tools, Tool{
+				Type:     "function",
+				Function: ToolFunction{Name: declaration.Name, Description: declaration.Description, Parameters: parameters},
+			})
+		}
+	}
+
+	if r.ToolConfig != nil {
+		callingConfig := r.ToolConfig.FunctionCallingConfig
+		switch strings.ToUpper(callingConfig.Mode) {
+		case "AUTO":
+			req.ToolChoice = &ToolChoice{Mode: ToolChoiceAuto}
+		case "NONE":
+			req.ToolChoice = &ToolChoice{Mode: ToolChoiceNone}
+		case "ANY":
+			req.ToolChoice = &ToolChoice{Mode: ToolChoiceRequired}
+			if len(callingConfig.AllowedFunctionNames) == 1 {
+				req.ToolChoice = &ToolChoice{Mode: ToolChoiceFunction, Function: callingConfig.AllowedFunctionNames[0]}
+			}
+		}
+	}
+
+	if config.Temperature != nil {
+		req.Parameters.Temperature = config.Temperature
+	}
+	if config.TopP != nil {
+		req.Parameters.TopP = config.TopP
+	}
+	if config.PresencePenalty != nil {
+		req.Parameters.PresencePenalty = *config.PresencePenalty
+	}
+	if config.FrequencyPenalty != nil {
+		req.Parameters.FrequencyPenalty = *config.FrequencyPenalty
+	}
+
+	// 要求输出JSON时转换为response_format，指定schema时为json_schema
+	if config.ResponseMimeType == "application/json" {
+		schema := config.ResponseJSONSchema
+		if len(schema) == 0 {
+			schema = lowerSchemaTypes(config.ResponseSchema)
+		}
+		req.Parameters.ResponseFormat = &ResponseFormat{Type: ResponseFormatJSONObject}
+		if len(schema) > 0 {
+			req.Parameters.ResponseFormat = &ResponseFormat{
+				Type:       ResponseFormatJSONSchema,
+				JSONSchema: &JSONSchemaFormat{Name: "response", Schema: schema},
+			}
+		}
+	}
+
+	if config.ThinkingConfig != nil && config.ThinkingConfig.IncludeThoughts {
+		req.Parameters.Reasoning = true
+		req.Parameters.ReasoningEffort = reasoningEffortForBudget(config.ThinkingConfig.ThinkingBudget)
+	}
+
+	// 流式响应的最后一个分片需要输出usageMetadata
+	if stream {
+		req.Parameters.IncludeUsage = true
+	}
+
+	return req
+}
+
+// geminiCallIDs 为请求中的函数调用分配ID
+// Gemini的函数调用可以没有ID，functionResponse按函数名称依次对应之前未返回结果的调用
+type geminiCallIDs struct {
+	count   int
+	pending map[string][]string // 函数名称到未返回结果的调用ID
+}
+
+// call 返回函数调用的ID
+func (g *geminiCallIDs) call(call *GeminiFunctionCall) string {
+	id := call.ID
+	if id == "" {
+		g.count++
+		id = fmt.Sprintf("call_%d", g.count)
+	}
+	g.pending[call.Name] = append(g.pending[call.Name], id)
+	return id
+}
+
+// response 返回函数调用结果对应的调用ID
+func (g *geminiCallIDs) response(response *GeminiFunctionResponse) string {
+	pending := g.pending[response.Name]
+	if response.ID != "" {
+		for i, id := range pending {
+			if id == response.ID {
+				g.pending[response.Name] = append(pending[:i:i], pending[i+1:]...)
+				break
+			}
+		}
+		return response.ID
+	}
+
+	if len(pending) == 0 {
+		return response.Name
+	}
+	g.pending[response.Name] = pending[1:]
+	return pending[0]
+}
+
+// toMessages 转换为统一格式的消息
+// functionCall片段转换为assistant消息的工具调用，functionResponse片段转换为tool消息并排在同一条消息的文本之前
+func (c *GeminiContent) toMessages(calls *geminiCallIDs) []Message {
+	role := "user"
+	if c.Role == "model" {
+		role = "assistant"
+	}
+
+	var messages []Message
+	var texts []string
+	var toolCalls []ToolCall
+	for _, part := range c.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			arguments := "{}"
+			if len(part.FunctionCall.Args) > 0 {
+				arguments = string(part.FunctionCall.Args)
+			}
+			toolCalls = append(toolCalls, ToolCall{
+				ID:       calls.call(part.FunctionCall),
+				Type:     "function",
+				Function: FunctionCall{Name: part.FunctionCall.Name, Arguments: arguments},
+			})
+		case part.FunctionResponse != nil:
+			messages = append(messages, Message{
+				Role:       "tool",
+				Content:    string(part.FunctionResponse.Response),
+				ToolCallID: calls.response(part.FunctionResponse),
+			})
+		case !part.Thought && part.Text != "":
+			texts = append(texts, part.Text)
+		}
+	}
+
+	if len(texts) > 0 || len(toolCalls) > 0 || len(messages) == 0 {
+		messages = append(messages, Message{Role: role, Content: strings.Join(texts, "\n"), ToolCalls: toolCalls})
+	}
+	return messages
+}
+
+// text 拼接消息中的文本片段，思考过程和函数调用片段被忽略
+func (c *GeminiContent) text() string {
+	texts := make([]string, 0, len(c.Parts))
+	for _, part := range c.Parts {
+		if !part.Thought && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// lowerSchemaTypes 将Gemini OpenAPI Schema中大写的type(如OBJECT、STRING)转换为JSON Schema的小写形式
+func lowerSchemaTypes(schema json.RawMessage) json.RawMessage {
+	if len(schema) == 0 {
+		return schema
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(schema, &value); err != nil {
+		return schema
+	}
+
+	lowered, err := json.Marshal(lowerSchemaValue(value))
+	if err != nil {
+		return schema
+	}
+	return lowered
+}
+
+// lowerSchemaValue 递归处理schema对象，properties中的键是属性名称，不做转换
+func lowerSchemaValue(value interface{}) interface{} {
+	schema, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+
+	for key, child := range schema {
+		switch key {
+		case "type":
+			if text, ok := child.(string); ok {
+				schema[key] = strings.ToLower(text)
+			}
+		case "properties":
+			if properties, ok := child.(map[string]interface{}); ok {
+				for name, property := range properties {
+					properties[name] = lowerSchemaValue(property)
+				}
+			}
+		case "items":
+			schema[key] = lowerSchemaValue(child)
+		case "anyOf":
+			if list, ok := child.([]interface{}); ok {
+				for i, item := range list {
+					list[i] = lowerSchemaValue(item)
+				}
+			}
+		}
+	}
+	return schema
+}
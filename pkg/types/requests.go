@@ -4,30 +4,47 @@ import "time"
 
 // 统一请求结构 - 屏蔽各LLM平台差异
 type UnifiedRequest struct {
-	Model      string     `json:"model" validate:"required"`       // 模型名称
-	Messages   []Message  `json:"messages" validate:"required"`    // 对话消息列表
-	Parameters Parameters `json:"parameters"`                      // 请求参数
-	Provider   string     `json:"provider" validate:"required"`    // 指定的LLM提供商
-	Metadata   Metadata   `json:"metadata"`                        // 请求元数据
+	Model      string      `json:"model" validate:"required"`    // 模型名称
+	Messages   []Message   `json:"messages" validate:"required"` // 对话消息列表
+	Parameters Parameters  `json:"parameters"`                   // 请求参数
+	Provider   string      `json:"provider" validate:"required"` // 指定的LLM提供商
+	Routing    *Routing    `json:"routing,omitempty"`            // 路由选项 (未指定provider时生效)
+	Metadata   Metadata    `json:"metadata"`                     // 请求元数据
+	Format     string      `json:"-"`                            // 请求格式，决定响应格式
+	Tools      []Tool      `json:"tools,omitempty"`              // 可供模型调用的工具
+	ToolChoice *ToolChoice `json:"tool_choice,omitempty"`        // 工具选择策略，未指定时由模型决定
+}
+
+// 路由选项
+type Routing struct {
+	Mode             string `json:"mode,omitempty"`               // 路由模式: cheapest(最便宜的可用模型)
+	MinContextWindow int    `json:"min_context_window,omitempty"` // 要求的最小上下文窗口(token)
+	Hedge            *bool  `json:"hedge,omitempty"`              // 是否使用对冲请求，未指定时使用配置
 }
 
 // 消息结构
 type Message struct {
-	Role    string `json:"role" validate:"required"`    // 角色: system, user, assistant
-	Content string `json:"content" validate:"required"` // 消息内容
+	Role       string     `json:"role" validate:"required"`    // 角色: system, user, assistant, tool
+	Content    string     `json:"content" validate:"required"` // 消息内容
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`        // assistant消息中的工具调用
+	ToolCallID string     `json:"tool_call_id,omitempty"`      // tool消息对应的工具调用ID
 }
 
 // 请求参数
 type Parameters struct {
-	Temperature      float64 `json:"temperature,omitempty"`       // 温度参数 (0.0-2.0)
-	MaxTokens        int     `json:"max_tokens,omitempty"`        // 最大输出token数
-	TopP             float64 `json:"top_p,omitempty"`             // 核采样参数
-	Stream           bool    `json:"stream,omitempty"`            // 是否流式输出
-	FrequencyPenalty float64 `json:"frequency_penalty,omitempty"` // 频率惩罚
-	PresencePenalty  float64 `json:"presence_penalty,omitempty"`  // 存在惩罚
-	Stop             []string `json:"stop,omitempty"`             // 停止序列
-	Reasoning        bool    `json:"reasoning,omitempty"`         // 是否输出推理过程 (适用于o1、deepseek-reasoner等模型)
-	ReasoningEffort  string  `json:"reasoning_effort,omitempty"`  // 推理强度: low, medium, high (适用于部分模型)
+	Temperature      *float64        `json:"temperature,omitempty"`       // 温度参数 (0.0-2.0)，未指定时为nil，0为有效值
+	MaxTokens        int             `json:"max_tokens,omitempty"`        // 最大输出token数
+	TopP             *float64        `json:"top_p,omitempty"`             // 核采样参数，未指定时为nil
+	Stream           bool            `json:"stream,omitempty"`            // 是否流式输出
+	FrequencyPenalty float64         `json:"frequency_penalty,omitempty"` // 频率惩罚
+	PresencePenalty  float64         `json:"presence_penalty,omitempty"`  // 存在惩罚
+	Stop             []string        `json:"stop,omitempty"`              // 停止序列
+	Reasoning        bool            `json:"reasoning,omitempty"`         // 是否输出推理过程 (适用于o1、deepseek-reasoner等模型)
+	ReasoningEffort  string          `json:"reasoning_effort,omitempty"`  // 推理强度: low, medium, high (适用于部分模型)
+	N                int             `json:"n,omitempty"`                 // 生成的候选回复数
+	Seed             *int            `json:"seed,omitempty"`              // 随机种子
+	IncludeUsage     bool            `json:"include_usage,omitempty"`     // 流式输出结束时返回token使用统计
+	ResponseFormat   *ResponseFormat `json:"response_format,omitempty"`   // 结构化输出格式，要求输出JSON时由网关校验
 }
 
 // 请求元数据
@@ -42,13 +59,14 @@ type Metadata struct {
 
 // 统一响应结构
 type UnifiedResponse struct {
-	ID      string   `json:"id"`                // 响应ID
-	Object  string   `json:"object"`            // 对象类型
-	Created int64    `json:"created"`           // 创建时间戳
-	Model   string   `json:"model"`             // 使用的模型
-	Choices []Choice `json:"choices"`           // 生成的选择列表
-	Usage   Usage    `json:"usage"`             // token使用统计
-	Error   *Error   `json:"error,omitempty"`   // 错误信息
+	ID       string   `json:"id"`                 // 响应ID
+	Object   string   `json:"object"`             // 对象类型
+	Created  int64    `json:"created"`            // 创建时间戳
+	Model    string   `json:"model"`              // 使用的模型
+	Choices  []Choice `json:"choices"`            // 生成的选择列表
+	Usage    Usage    `json:"usage"`              // token使用统计
+	Provider string   `json:"provider,omitempty"` // 实际处理请求的提供商 (发生故障转移时与请求中的不同)
+	Error    *Error   `json:"error,omitempty"`    // 错误信息
 }
 
 // 选择结构
@@ -70,27 +88,32 @@ type Error struct {
 	Code    string `json:"code"`    // 错误代码
 	Message string `json:"message"` // 错误消息
 	Type    string `json:"type"`    // 错误类型
+	Status  int    `json:"-"`       // 上游返回的HTTP状态码，流中的错误为200
 }
 
 // 流式响应结构
 type StreamResponse struct {
-	ID      string        `json:"id"`      // 响应ID
-	Object  string        `json:"object"`  // 对象类型
-	Created int64         `json:"created"` // 创建时间戳
-	Model   string        `json:"model"`   // 使用的模型
-	Choices []StreamChoice `json:"choices"` // 流式选择
+	ID       string         `json:"id"`                 // 响应ID
+	Object   string         `json:"object"`             // 对象类型
+	Created  int64          `json:"created"`            // 创建时间戳
+	Model    string         `json:"model"`              // 使用的模型
+	Choices  []StreamChoice `json:"choices"`            // 流式选择
+	Usage    *Usage         `json:"usage,omitempty"`    // token使用统计 (通常只在最后一个分片中出现)
+	Provider string         `json:"provider,omitempty"` // 实际处理请求的提供商
+	Error    *Error         `json:"error,omitempty"`    // 流中途返回的错误
 }
 
 // 流式选择结构
 type StreamChoice struct {
-	Index int          `json:"index"` // 选择索引
-	Delta StreamDelta  `json:"delta"` // 增量内容
-	FinishReason string `json:"finish_reason,omitempty"` // 完成原因
+	Index        int         `json:"index"`                   // 选择索引
+	Delta        StreamDelta `json:"delta"`                   // 增量内容
+	FinishReason string      `json:"finish_reason,omitempty"` // 完成原因
 }
 
 // 流式增量内容
 type StreamDelta struct {
-	Role      string `json:"role,omitempty"`      // 角色
-	Content   string `json:"content,omitempty"`   // 内容片段
-	Reasoning string `json:"reasoning,omitempty"` // 推理过程片段 (适用于推理模型)
-}
\ No newline at end of file
+	Role      string          `json:"role,omitempty"`       // 角色
+	Content   string          `json:"content,omitempty"`    // 内容片段
+	Reasoning string          `json:"reasoning,omitempty"`  // 推理过程片段 (适用于推理模型)
+	ToolCalls []ToolCallDelta `json:"tool_calls,omitempty"` // 工具调用增量
+}
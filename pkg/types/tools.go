@@ -0,0 +1,103 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// 工具选择模式
+const (
+	ToolChoiceAuto     = "auto"     // 由模型决定是否调用工具
+	ToolChoiceNone     = "none"     // 不调用工具
+	ToolChoiceRequired = "required" // 必须调用至少一个工具
+	ToolChoiceFunction = "function" // 必须调用指定的函数
+)
+
+// Tool 可供模型调用的工具，目前只支持函数
+type Tool struct {
+	Type     string       `json:"type"` // 固定为function
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction 函数定义
+type ToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"` // 参数的JSON Schema
+}
+
+// ToolChoice 工具选择策略
+// JSON格式与OpenAI一致: "auto"、"none"、"required" 或 {"type":"function","function":{"name":"..."}}
+type ToolChoice struct {
+	Mode     string // auto、none、required、function
+	Function string // Mode为function时调用的函数名称
+}
+
+// UnmarshalJSON 解析字符串或指定函数的对象形式
+func (t *ToolChoice) UnmarshalJSON(data []byte) error {
+	var mode string
+	if err := json.Unmarshal(data, &mode); err == nil {
+		switch mode {
+		case ToolChoiceAuto, ToolChoiceNone, ToolChoiceRequired:
+			*t = ToolChoice{Mode: mode}
+			return nil
+		}
+		return fmt.Errorf("无效的tool_choice: %s", mode)
+	}
+
+	var named struct {
+		Type     string `json:"type"`
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(data, &named); err != nil {
+		return err
+	}
+	if named.Function.Name == "" {
+		return fmt.Errorf("tool_choice缺少函数名称")
+	}
+
+	*t = ToolChoice{Mode: ToolChoiceFunction, Function: named.Function.Name}
+	return nil
+}
+
+// MarshalJSON 输出OpenAI格式
+func (t ToolChoice) MarshalJSON() ([]byte, error) {
+	if t.Mode != ToolChoiceFunction {
+		return json.Marshal(t.Mode)
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"type":     "function",
+		"function": map[string]string{"name": t.Function},
+	})
+}
+
+// ToolCall assistant消息中的工具调用
+type ToolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"` // 固定为function
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall 函数调用
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON格式的参数
+}
+
+// ToolCallDelta 流式输出中的工具调用增量
+// 同一个调用的多个增量index相同，id、type和函数名称只在第一个增量中出现，参数按片段拼接
+type ToolCallDelta struct {
+	Index    int               `json:"index"`
+	ID       string            `json:"id,omitempty"`
+	Type     string            `json:"type,omitempty"`
+	Function FunctionCallDelta `json:"function"`
+}
+
+// FunctionCallDelta 函数调用增量
+type FunctionCallDelta struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}